@@ -0,0 +1,74 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * cmd/hprose-gen/main.go                                 *
+ *                                                        *
+ * hprose code generator for Go.                          *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+// hprose-gen is the code generator of hprose.
+//
+// Usage:
+//
+//	hprose-gen stub -type Greeter [-namespace ns] [-output file] [dir | files...]
+//
+// The stub command generates a client type which implements the Go interface
+// on top of rpc.Client, and a function which publishes an implementation of
+// the interface to rpc.Service. See the usage of the stub command for more.
+//
+// It is usually run by go generate:
+//
+//	//go:generate hprose-gen stub -type Greeter
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+const usage = `hprose-gen is the code generator of hprose.
+
+Usage:
+
+	hprose-gen <command> [arguments]
+
+The commands are:
+
+	stub    generate the client and the service registration of interfaces
+
+Use "hprose-gen <command> -h" for more information about a command.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	var err error
+	switch os.Args[1] {
+	case "stub":
+		err = stubCommand(os.Args[2:])
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(os.Stdout, usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "hprose-gen: unknown command %q\n\n", os.Args[1])
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "hprose-gen:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,805 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * cmd/hprose-gen/stub.go                                 *
+ *                                                        *
+ * hprose stub generator for Go.                          *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const rpcPath = "github.com/hprose/hprose-golang/rpc"
+
+const stubUsage = `Usage:
+
+	hprose-gen stub -type T[,T...] [-namespace ns] [-output file] [dir | files...]
+
+The stub command parses the Go package in dir (the current directory by
+default) or the files, and generates the code in the package for every
+interface T:
+
+	TClient       the client type which implements T on top of rpc.Client
+	NewTClient    the constructor of TClient
+	RegisterT     publishes the methods of an implementation of T to
+	              rpc.Service
+
+The invoke settings of a method are set by the directives in its comment,
+they mirror the tags of the stub fields used by rpc.Client.UseService:
+
+	type Greeter interface {
+		// Hello says hello
+		//hprose:idempotent retry=3 timeout=5s
+		Hello(name string) (string, error)
+	}
+
+The directives are:
+
+	name=remote    the remote method name (the method name by default)
+	byref          the arguments are passed by reference
+	simple         the arguments are serialized in simple mode
+	idempotent     the method is idempotent, so it can be retried
+	failswitch     switch the service address when the invoking fails
+	oneway         do not wait for the result
+	timeout=5s     the timeout of the invoking (a duration or nanoseconds)
+	retry=3        the max retry times of the invoking
+	result=raw     the result mode: normal, serialized, raw or rawwithendtag
+
+The boolean directives can be written as key=false. The simple and oneway
+directives also apply to the service registration.
+
+The first parameter of a method may be a context.Context, it is used by the
+invoking, and the implementation of the method is called with
+context.Background() on the service. A method may return *rpc.Future to be
+invoked asynchronously. A method which does not return an error panics when
+the invoking fails.
+
+Flags:
+`
+
+type stubSettings struct {
+	byRef      bool
+	simple     bool
+	idempotent bool
+	failswitch bool
+	oneway     bool
+	timeout    time.Duration
+	retry      int
+	mode       string
+}
+
+type stubParam struct {
+	name string
+	typ  string
+}
+
+type stubMethod struct {
+	name     string
+	remote   string
+	ctx      string
+	params   []stubParam
+	variadic bool
+	results  []string
+	hasError bool
+	future   bool
+	settings stubSettings
+}
+
+type interfaceDecl struct {
+	iface *ast.InterfaceType
+	file  *ast.File
+}
+
+type stubGenerator struct {
+	fset       *token.FileSet
+	pkg        string
+	interfaces map[string]interfaceDecl
+	imports    map[string]string
+	namespace  string
+	buf        bytes.Buffer
+}
+
+func stubCommand(args []string) error {
+	flags := flag.NewFlagSet("stub", flag.ExitOnError)
+	types := flags.String("type", "", "comma-separated list of interface names; required")
+	namespace := flags.String("namespace", "", "namespace of the remote methods")
+	output := flags.String("output", "", "output file name; default <dir>/<type>_hprose.go")
+	flags.Usage = func() {
+		fmt.Fprint(os.Stderr, stubUsage)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if *types == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+	names := strings.Split(*types, ",")
+	files, dir, err := goFiles(flags.Args())
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		*output = filepath.Join(dir, strings.ToLower(names[0])+"_hprose.go")
+	}
+	g := &stubGenerator{
+		fset:       token.NewFileSet(),
+		interfaces: make(map[string]interfaceDecl),
+		imports:    make(map[string]string),
+		namespace:  *namespace,
+	}
+	if err = g.parse(files, *output); err != nil {
+		return err
+	}
+	src, err := g.generate(names)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(*output, src, 0644)
+}
+
+// goFiles returns the Go files (excluding the tests) of args and their
+// directory.
+func goFiles(args []string) (files []string, dir string, err error) {
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+	if len(args) == 1 {
+		if info, err := os.Stat(args[0]); err == nil && info.IsDir() {
+			dir = args[0]
+			if files, err = filepath.Glob(filepath.Join(dir, "*.go")); err != nil {
+				return nil, "", err
+			}
+			n := 0
+			for _, file := range files {
+				if !strings.HasSuffix(file, "_test.go") {
+					files[n] = file
+					n++
+				}
+			}
+			return files[:n], dir, nil
+		}
+	}
+	return args, filepath.Dir(args[0]), nil
+}
+
+func (g *stubGenerator) parse(files []string, output string) error {
+	for _, filename := range files {
+		if same(filename, output) {
+			continue
+		}
+		file, err := parser.ParseFile(g.fset, filename, nil, parser.ParseComments)
+		if err != nil {
+			return err
+		}
+		if g.pkg == "" {
+			g.pkg = file.Name.Name
+		} else if g.pkg != file.Name.Name {
+			return fmt.Errorf("found packages %s and %s", g.pkg, file.Name.Name)
+		}
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				spec := spec.(*ast.TypeSpec)
+				if iface, ok := spec.Type.(*ast.InterfaceType); ok {
+					g.interfaces[spec.Name.Name] = interfaceDecl{iface, file}
+				}
+			}
+		}
+	}
+	if g.pkg == "" {
+		return fmt.Errorf("no Go files")
+	}
+	return nil
+}
+
+func same(a, b string) bool {
+	a, _ = filepath.Abs(a)
+	b, _ = filepath.Abs(b)
+	return a == b
+}
+
+// importName guesses the package name of the import path
+func importName(importPath string) string {
+	name := path.Base(importPath)
+	if len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+		name = path.Base(path.Dir(importPath))
+	}
+	if i := strings.Index(name, "."); i > 0 {
+		name = name[:i]
+	}
+	name = strings.TrimPrefix(name, "go-")
+	return strings.Replace(name, "-", "_", -1)
+}
+
+// isStd reports whether the import path is a standard package
+func isStd(importPath string) bool {
+	return !strings.Contains(strings.SplitN(importPath, "/", 2)[0], ".")
+}
+
+// resolveImport returns the import path of the package name in file
+func resolveImport(file *ast.File, name string) (string, error) {
+	var guess []string
+	for _, spec := range file.Imports {
+		importPath, _ := strconv.Unquote(spec.Path.Value)
+		if spec.Name != nil {
+			if spec.Name.Name == name {
+				return importPath, nil
+			}
+			continue
+		}
+		if importName(importPath) == name {
+			return importPath, nil
+		}
+		if strings.Contains(importPath, name) {
+			guess = append(guess, importPath)
+		}
+	}
+	if len(guess) == 1 {
+		return guess[0], nil
+	}
+	return "", fmt.Errorf("cannot find the import of package %s", name)
+}
+
+func (g *stubGenerator) addImport(name, importPath string) error {
+	if p, ok := g.imports[name]; ok && p != importPath {
+		return fmt.Errorf("package name %s is used by %s and %s", name, p, importPath)
+	}
+	g.imports[name] = importPath
+	return nil
+}
+
+// typeString returns the source of the type expr, and imports the packages
+// used by it.
+func (g *stubGenerator) typeString(file *ast.File, expr ast.Expr) (string, error) {
+	var err error
+	ast.Inspect(expr, func(node ast.Node) bool {
+		if sel, ok := node.(*ast.SelectorExpr); ok && err == nil {
+			if ident, ok := sel.X.(*ast.Ident); ok {
+				var importPath string
+				if importPath, err = resolveImport(file, ident.Name); err == nil {
+					err = g.addImport(ident.Name, importPath)
+				}
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err = printer.Fprint(&buf, g.fset, expr); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// qualified returns the package name if expr is pkg.name and the import path
+// of pkg is importPath.
+func qualified(file *ast.File, expr ast.Expr, importPath, name string) (string, bool) {
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != name {
+		return "", false
+	}
+	ident, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return "", false
+	}
+	if p, err := resolveImport(file, ident.Name); err != nil || p != importPath {
+		return "", false
+	}
+	return ident.Name, true
+}
+
+func (g *stubGenerator) methods(
+	name string, seen map[string]bool) (methods []*stubMethod, err error) {
+	decl, ok := g.interfaces[name]
+	if !ok {
+		return nil, fmt.Errorf("interface %s is not found in package %s", name, g.pkg)
+	}
+	if seen[name] {
+		return nil, nil
+	}
+	seen[name] = true
+	for _, field := range decl.iface.Methods.List {
+		switch t := field.Type.(type) {
+		case *ast.FuncType:
+			for _, ident := range field.Names {
+				method, err := g.method(
+					decl.file, ident.Name, t, field.Doc, field.Comment)
+				if err != nil {
+					return nil, fmt.Errorf("%s.%s: %v", name, ident.Name, err)
+				}
+				methods = append(methods, method)
+			}
+		case *ast.Ident:
+			embedded, err := g.methods(t.Name, seen)
+			if err != nil {
+				return nil, err
+			}
+			methods = append(methods, embedded...)
+		default:
+			return nil, fmt.Errorf("%s: embedded interface %s is not supported",
+				name, g.source(field.Type))
+		}
+	}
+	return methods, nil
+}
+
+func (g *stubGenerator) source(node ast.Node) string {
+	var buf bytes.Buffer
+	printer.Fprint(&buf, g.fset, node)
+	return buf.String()
+}
+
+func (g *stubGenerator) method(
+	file *ast.File,
+	name string,
+	ft *ast.FuncType,
+	doc, comment *ast.CommentGroup) (method *stubMethod, err error) {
+	method = &stubMethod{name: name, remote: name}
+	if err = parseDirectives(doc, method); err != nil {
+		return nil, err
+	}
+	if err = parseDirectives(comment, method); err != nil {
+		return nil, err
+	}
+	var params []ast.Expr
+	for _, field := range ft.Params.List {
+		for i := 0; i < len(field.Names) || i == 0; i++ {
+			params = append(params, field.Type)
+		}
+	}
+	if len(params) > 0 {
+		if ctx, ok := qualified(file, params[0], "context", "Context"); ok {
+			if err = g.addImport(ctx, "context"); err != nil {
+				return nil, err
+			}
+			method.ctx = ctx
+			params = params[1:]
+		}
+	}
+	for i, param := range params {
+		if ellipsis, ok := param.(*ast.Ellipsis); ok {
+			method.variadic = true
+			param = ellipsis.Elt
+		}
+		typ, err := g.typeString(file, param)
+		if err != nil {
+			return nil, err
+		}
+		method.params = append(method.params, stubParam{fmt.Sprintf("arg%d", i), typ})
+	}
+	var results []ast.Expr
+	if ft.Results != nil {
+		for _, field := range ft.Results.List {
+			for i := 0; i < len(field.Names) || i == 0; i++ {
+				results = append(results, field.Type)
+			}
+		}
+	}
+	if n := len(results); n > 0 {
+		if ident, ok := results[n-1].(*ast.Ident); ok && ident.Name == "error" {
+			method.hasError = true
+			results = results[:n-1]
+		}
+	}
+	if len(results) == 1 && !method.hasError {
+		if star, ok := results[0].(*ast.StarExpr); ok {
+			_, method.future = qualified(file, star.X, rpcPath, "Future")
+		}
+	}
+	for _, result := range results {
+		typ, err := g.typeString(file, result)
+		if err != nil {
+			return nil, err
+		}
+		method.results = append(method.results, typ)
+	}
+	switch {
+	case method.settings.oneway && len(method.results) > 0:
+		return nil, fmt.Errorf("oneway method can only return error")
+	case method.settings.mode != "" && len(method.results) != 1:
+		return nil, fmt.Errorf("result=%s method must return one result",
+			strings.ToLower(method.settings.mode))
+	}
+	if g.namespace != "" {
+		method.remote = g.namespace + "_" + method.remote
+	}
+	return method, nil
+}
+
+func parseBool(key, value string) (bool, error) {
+	if value == "" {
+		return true, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid directive %s=%s", key, value)
+	}
+	return b, nil
+}
+
+func parseDirectives(doc *ast.CommentGroup, method *stubMethod) (err error) {
+	if doc == nil {
+		return nil
+	}
+	settings := &method.settings
+	for _, comment := range doc.List {
+		if !strings.HasPrefix(comment.Text, "//hprose:") {
+			continue
+		}
+		for _, directive := range strings.Fields(comment.Text[len("//hprose:"):]) {
+			key, value := directive, ""
+			if i := strings.Index(directive, "="); i >= 0 {
+				key, value = directive[:i], directive[i+1:]
+			}
+			switch strings.ToLower(key) {
+			case "name":
+				if value == "" {
+					return fmt.Errorf("invalid directive %s", directive)
+				}
+				method.remote = value
+			case "byref":
+				settings.byRef, err = parseBool(key, value)
+			case "simple":
+				settings.simple, err = parseBool(key, value)
+			case "idempotent":
+				settings.idempotent, err = parseBool(key, value)
+			case "failswitch":
+				settings.failswitch, err = parseBool(key, value)
+			case "oneway":
+				settings.oneway, err = parseBool(key, value)
+			case "timeout":
+				if settings.timeout, err = time.ParseDuration(value); err != nil {
+					var ns int64
+					if ns, err = strconv.ParseInt(value, 10, 64); err != nil {
+						return fmt.Errorf("invalid directive %s", directive)
+					}
+					settings.timeout = time.Duration(ns)
+				}
+			case "retry":
+				if settings.retry, err = strconv.Atoi(value); err != nil {
+					return fmt.Errorf("invalid directive %s", directive)
+				}
+			case "result":
+				switch strings.ToLower(value) {
+				case "normal":
+					settings.mode = ""
+				case "serialized":
+					settings.mode = "Serialized"
+				case "raw":
+					settings.mode = "Raw"
+				case "rawwithendtag":
+					settings.mode = "RawWithEndTag"
+				default:
+					return fmt.Errorf("invalid directive %s", directive)
+				}
+			default:
+				return fmt.Errorf("unknown directive %s", directive)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func lowerFirst(s string) string {
+	r, n := utf8.DecodeRuneInString(s)
+	return string(unicode.ToLower(r)) + s[n:]
+}
+
+func (g *stubGenerator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func (g *stubGenerator) generate(names []string) ([]byte, error) {
+	g.addImport("reflect", "reflect")
+	if err := g.addImport("rpc", rpcPath); err != nil {
+		return nil, err
+	}
+	methods := make([][]*stubMethod, len(names))
+	for i, name := range names {
+		var err error
+		if methods[i], err = g.methods(name, make(map[string]bool)); err != nil {
+			return nil, err
+		}
+	}
+	g.printf("// Code generated by hprose-gen stub; DO NOT EDIT.\n\n")
+	g.printf("package %s\n\nimport (\n", g.pkg)
+	paths := make([]string, 0, len(g.imports))
+	byPath := make(map[string]string, len(g.imports))
+	for name, importPath := range g.imports {
+		paths = append(paths, importPath)
+		byPath[importPath] = name
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		if si, sj := isStd(paths[i]), isStd(paths[j]); si != sj {
+			return si
+		}
+		return paths[i] < paths[j]
+	})
+	for i, importPath := range paths {
+		if i > 0 && isStd(importPath) != isStd(paths[i-1]) {
+			g.printf("\n")
+		}
+		if name := byPath[importPath]; name != importName(importPath) {
+			g.printf("\t%s %q\n", name, importPath)
+		} else {
+			g.printf("\t%q\n", importPath)
+		}
+	}
+	g.printf(")\n")
+	for i, name := range names {
+		g.generateClient(name, methods[i])
+		g.generateRegister(name, methods[i])
+	}
+	src, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("invalid generated code: %v\n%s", err, g.buf.Bytes())
+	}
+	return src, nil
+}
+
+func (g *stubGenerator) generateClient(name string, methods []*stubMethod) {
+	client := name + "Client"
+	g.printf(`
+// %[1]s implements %[2]s by invoking the remote methods with Client
+type %[1]s struct {
+	Client rpc.Client
+}
+
+// New%[1]s returns a %[1]s which invokes the remote methods
+// with client
+func New%[1]s(client rpc.Client) *%[1]s {
+	return &%[1]s{Client: client}
+}
+
+var _ %[2]s = (*%[1]s)(nil)
+`, client, name)
+	seen := make(map[string]bool)
+	for _, method := range methods {
+		if seen[method.name] {
+			continue
+		}
+		seen[method.name] = true
+		settings := lowerFirst(name) + method.name + "Settings"
+		g.generateSettings(settings, method)
+		g.generateMethod(client, settings, method)
+	}
+}
+
+func (g *stubGenerator) generateSettings(settings string, method *stubMethod) {
+	s := method.settings
+	g.printf("\nvar %s = &rpc.InvokeSettings{\n", settings)
+	if s.byRef {
+		g.printf("ByRef: true,\n")
+	}
+	if s.simple {
+		g.printf("Simple: true,\n")
+	}
+	if s.idempotent {
+		g.printf("Idempotent: true,\n")
+	}
+	if s.failswitch {
+		g.printf("Failswitch: true,\n")
+	}
+	if s.oneway {
+		g.printf("Oneway: true,\n")
+	}
+	if s.timeout > 0 {
+		g.printf("Timeout: %d, // %v\n", int64(s.timeout), s.timeout)
+	}
+	if s.retry > 0 {
+		g.printf("Retry: %d,\n", s.retry)
+	}
+	if s.mode != "" {
+		g.printf("Mode: rpc.%s,\n", s.mode)
+	}
+	results := method.results
+	if method.future {
+		results = []string{"interface{}"}
+	}
+	if len(results) > 0 {
+		g.printf("ResultTypes: []reflect.Type{\n")
+		for _, result := range results {
+			g.printf("reflect.TypeOf((*%s)(nil)).Elem(),\n", result)
+		}
+		g.printf("},\n")
+	}
+	g.printf("}\n")
+}
+
+// signature returns the parameters of the method
+func (method *stubMethod) signature() string {
+	params := make([]string, 0, len(method.params)+1)
+	if method.ctx != "" {
+		params = append(params, "ctx "+method.ctx+".Context")
+	}
+	for i, param := range method.params {
+		if method.variadic && i == len(method.params)-1 {
+			params = append(params, param.name+" ..."+param.typ)
+		} else {
+			params = append(params, param.name+" "+param.typ)
+		}
+	}
+	return strings.Join(params, ", ")
+}
+
+// arguments returns the arguments which call the method with ctx
+func (method *stubMethod) arguments(ctx string) string {
+	args := make([]string, 0, len(method.params)+1)
+	if method.ctx != "" {
+		args = append(args, ctx)
+	}
+	for i, param := range method.params {
+		if method.variadic && i == len(method.params)-1 {
+			args = append(args, param.name+"...")
+		} else {
+			args = append(args, param.name)
+		}
+	}
+	return strings.Join(args, ", ")
+}
+
+func (g *stubGenerator) generateMethod(
+	client, settings string, method *stubMethod) {
+	var results []string
+	for i, result := range method.results {
+		results = append(results, fmt.Sprintf("r%d %s", i, result))
+	}
+	if method.hasError {
+		results = append(results, "err error")
+	}
+	g.printf("\n// %s invokes the remote method %s\n", method.name, method.remote)
+	g.printf("func (stub *%s) %s(%s) (%s) {\n",
+		client, method.name, method.signature(), strings.Join(results, ", "))
+	n := len(method.params)
+	if method.variadic {
+		last := method.params[n-1].name
+		g.printf("args := make([]reflect.Value, 0, %d+len(%s))\n", n-1, last)
+		for _, param := range method.params[:n-1] {
+			g.printf("args = append(args, reflect.ValueOf(&%s).Elem())\n", param.name)
+		}
+		g.printf("for i := range %[1]s {\n"+
+			"args = append(args, reflect.ValueOf(&%[1]s[i]).Elem())\n}\n", last)
+	} else {
+		g.printf("args := []reflect.Value{")
+		for _, param := range method.params {
+			g.printf("\nreflect.ValueOf(&%s).Elem(),", param.name)
+		}
+		if n > 0 {
+			g.printf("\n")
+		}
+		g.printf("}\n")
+	}
+	invoke, ctx := "Invoke", ""
+	if method.future {
+		invoke = "InvokeAsync"
+	}
+	if method.ctx != "" {
+		invoke += "Context"
+		ctx = "ctx, "
+	}
+	call := fmt.Sprintf("stub.Client.%s(%s%q, args, %s)",
+		invoke, ctx, method.remote, settings)
+	if method.future {
+		g.printf("return %s\n}\n", call)
+		return
+	}
+	switch {
+	case len(method.results) == 0 && method.hasError:
+		g.printf("_, err = %s\nreturn\n}\n", call)
+		return
+	case len(method.results) == 0:
+		g.printf("if _, err := %s; err != nil {\npanic(err)\n}\n}\n", call)
+		return
+	}
+	g.printf("results, err := %s\n", call)
+	if method.hasError {
+		g.printf("if err == nil {\n")
+	} else {
+		g.printf("if err != nil {\npanic(err)\n}\n")
+	}
+	for i, result := range method.results {
+		g.printf("r%[1]d, _ = results[%[1]d].Interface().(%[2]s)\n", i, result)
+	}
+	if method.hasError {
+		g.printf("}\n")
+	}
+	g.printf("return\n}\n")
+}
+
+func (g *stubGenerator) generateRegister(name string, methods []*stubMethod) {
+	g.printf("\n// Register%[1]s publishes the methods of impl to service\n", name)
+	g.printf("func Register%[1]s(service rpc.Service, impl %[1]s) {\n", name)
+	seen := make(map[string]bool)
+	for _, method := range methods {
+		if seen[method.name] {
+			continue
+		}
+		seen[method.name] = true
+		var options []string
+		if method.settings.simple {
+			options = append(options, "Simple: true")
+		}
+		if method.settings.oneway {
+			options = append(options, "Oneway: true")
+		}
+		g.printf("service.AddFunction(%q, ", method.remote)
+		g.generateFunction(method)
+		g.printf(", rpc.Options{%s})\n", strings.Join(options, ", "))
+	}
+	g.printf("}\n")
+}
+
+// generateFunction generates the function which is published for method
+func (g *stubGenerator) generateFunction(method *stubMethod) {
+	if method.ctx == "" && !method.future {
+		g.printf("impl.%s", method.name)
+		return
+	}
+	params := method.signature()
+	if method.ctx != "" {
+		params = strings.TrimPrefix(params, "ctx "+method.ctx+".Context")
+		params = strings.TrimPrefix(params, ", ")
+	}
+	call := fmt.Sprintf("impl.%s(%s)", method.name,
+		method.arguments(method.ctx+".Background()"))
+	if method.future {
+		g.printf(`func(%s) (interface{}, error) {
+	results, err := %s.Wait()
+	if err != nil || len(results) == 0 || !results[0].IsValid() {
+		return nil, err
+	}
+	return results[0].Interface(), nil
+}`, params, call)
+		return
+	}
+	results := append([]string(nil), method.results...)
+	if method.hasError {
+		results = append(results, "error")
+	}
+	if len(results) == 0 {
+		g.printf("func(%s) {\n%s\n}", params, call)
+		return
+	}
+	g.printf("func(%s) (%s) {\nreturn %s\n}",
+		params, strings.Join(results, ", "), call)
+}
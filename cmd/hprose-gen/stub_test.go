@@ -0,0 +1,157 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * cmd/hprose-gen/stub_test.go                            *
+ *                                                        *
+ * hprose stub generator test for Go.                     *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"go/format"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+const goldenDir = "testdata/greeter"
+
+func newStubGenerator(namespace string) *stubGenerator {
+	return &stubGenerator{
+		fset:       token.NewFileSet(),
+		interfaces: make(map[string]interfaceDecl),
+		imports:    make(map[string]string),
+		namespace:  namespace,
+	}
+}
+
+func generateStub(dir, namespace string, names ...string) ([]byte, error) {
+	files, _, err := goFiles([]string{dir})
+	if err != nil {
+		return nil, err
+	}
+	g := newStubGenerator(namespace)
+	output := filepath.Join(dir, strings.ToLower(names[0])+"_hprose.go")
+	if err = g.parse(files, output); err != nil {
+		return nil, err
+	}
+	return g.generate(names)
+}
+
+func TestStubGolden(t *testing.T) {
+	src, err := generateStub(goldenDir, "", "Greeter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden := filepath.Join(goldenDir, "greeter_hprose.go")
+	if *update {
+		if err = ioutil.WriteFile(golden, src, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(src, want) {
+		t.Errorf("the generated code differs from %s, run go test -update\n%s", golden, src)
+	}
+	formatted, err := format.Source(src)
+	if err != nil || !bytes.Equal(formatted, src) {
+		t.Error("the generated code is not formatted", err)
+	}
+	// the existing output file is not parsed as the input
+	again, err := generateStub(goldenDir, "", "Greeter")
+	if err != nil || !bytes.Equal(again, src) {
+		t.Error("the generated code is not stable", err)
+	}
+}
+
+// TestStubCompile compiles the golden file, and runs the tests of the sample
+// package which invoke every method of the stub on a TCP server.
+func TestStubCompile(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping the compiling in short mode")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool is not found")
+	}
+	cmd := exec.Command(goTool, "test", "-count=1", "./"+goldenDir)
+	cmd.Env = os.Environ()
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("%v\n%s", err, output)
+	}
+}
+
+func TestStubNamespace(t *testing.T) {
+	src, err := generateStub(goldenDir, "ns", "Greeter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		`Invoke("ns_Ping", args, greeterPingSettings)`,
+		`InvokeContext(ctx, "ns_Hello", args, greeterHelloSettings)`,
+		`Invoke("ns_move", args, greeterMoveSettings)`,
+		`service.AddFunction("ns_Sum", impl.Sum, rpc.Options{})`,
+	} {
+		if !bytes.Contains(src, []byte(s)) {
+			t.Error("not found:", s)
+		}
+	}
+}
+
+func TestStubErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hprose-gen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tests := []struct {
+		name   string
+		source string
+		err    string
+	}{
+		{"Missing", "type A interface{ F() error }", "interface Missing is not found"},
+		{"A", "type A interface{\n//hprose:oneway\nF() (int, error)\n}", "oneway method can only return error"},
+		{"A", "type A interface{\n//hprose:result=raw\nF() error\n}", "result=raw method must return one result"},
+		{"A", "type A interface{\n//hprose:retry=x\nF() error\n}", "invalid directive retry=x"},
+		{"A", "type A interface{\n//hprose:timeout=x\nF() error\n}", "invalid directive timeout=x"},
+		{"A", "type A interface{\n//hprose:simple=x\nF() error\n}", "invalid directive simple=x"},
+		{"A", "type A interface{\n//hprose:result=x\nF() error\n}", "invalid directive result=x"},
+		{"A", "type A interface{\n//hprose:name\nF() error\n}", "invalid directive name"},
+		{"A", "type A interface{\n//hprose:bogus\nF() error\n}", "unknown directive bogus"},
+		{"A", "import \"io\"\ntype A interface{ io.Reader }", "embedded interface io.Reader is not supported"},
+	}
+	for i, test := range tests {
+		filename := filepath.Join(dir, "a.go")
+		source := "package a\n" + test.source + "\n"
+		if err := ioutil.WriteFile(filename, []byte(source), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := generateStub(dir, "", test.name)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%d: %v, want %s", i, err, test.err)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+// Package greeter is the sample package of the hprose-gen stub tests, the
+// golden file greeter_hprose.go is generated by:
+//
+//	go test ./cmd/hprose-gen -update
+package greeter
+
+import (
+	gocontext "context"
+	"time"
+
+	"github.com/hprose/hprose-golang/rpc"
+)
+
+// Point is a struct type of the package
+type Point struct{ X, Y int }
+
+// Base is embedded by Pinger
+type Base interface {
+	// Ping pings the service
+	//hprose:idempotent retry=2
+	Ping() error
+}
+
+// Pinger is embedded by Greeter
+type Pinger interface {
+	Base
+}
+
+// Greeter is the interface of the service
+type Greeter interface {
+	Pinger
+	// Hello says hello
+	//hprose:idempotent timeout=5s
+	Hello(ctx gocontext.Context, name string) (string, error)
+	Sum(nums ...int) (int, error)
+	//hprose:name=move simple
+	Move(p Point, d time.Duration) (Point, time.Duration, error)
+	Swap(a, b *int) error //hprose:byref
+	Tags(tags map[string][]string) (int, error)
+	NoError(s string) string
+	Maybe() (interface{}, error)
+	//hprose:oneway
+	Fire(s string) error
+	Later(ctx gocontext.Context, xs ...int) *rpc.Future
+	//hprose:result=raw failswitch
+	Raw() ([]byte, error)
+}
@@ -0,0 +1,233 @@
+// Code generated by hprose-gen stub; DO NOT EDIT.
+
+package greeter
+
+import (
+	gocontext "context"
+	"reflect"
+	"time"
+
+	"github.com/hprose/hprose-golang/rpc"
+)
+
+// GreeterClient implements Greeter by invoking the remote methods with Client
+type GreeterClient struct {
+	Client rpc.Client
+}
+
+// NewGreeterClient returns a GreeterClient which invokes the remote methods
+// with client
+func NewGreeterClient(client rpc.Client) *GreeterClient {
+	return &GreeterClient{Client: client}
+}
+
+var _ Greeter = (*GreeterClient)(nil)
+
+var greeterPingSettings = &rpc.InvokeSettings{
+	Idempotent: true,
+	Retry:      2,
+}
+
+// Ping invokes the remote method Ping
+func (stub *GreeterClient) Ping() (err error) {
+	args := []reflect.Value{}
+	_, err = stub.Client.Invoke("Ping", args, greeterPingSettings)
+	return
+}
+
+var greeterHelloSettings = &rpc.InvokeSettings{
+	Idempotent: true,
+	Timeout:    5000000000, // 5s
+	ResultTypes: []reflect.Type{
+		reflect.TypeOf((*string)(nil)).Elem(),
+	},
+}
+
+// Hello invokes the remote method Hello
+func (stub *GreeterClient) Hello(ctx gocontext.Context, arg0 string) (r0 string, err error) {
+	args := []reflect.Value{
+		reflect.ValueOf(&arg0).Elem(),
+	}
+	results, err := stub.Client.InvokeContext(ctx, "Hello", args, greeterHelloSettings)
+	if err == nil {
+		r0, _ = results[0].Interface().(string)
+	}
+	return
+}
+
+var greeterSumSettings = &rpc.InvokeSettings{
+	ResultTypes: []reflect.Type{
+		reflect.TypeOf((*int)(nil)).Elem(),
+	},
+}
+
+// Sum invokes the remote method Sum
+func (stub *GreeterClient) Sum(arg0 ...int) (r0 int, err error) {
+	args := make([]reflect.Value, 0, 0+len(arg0))
+	for i := range arg0 {
+		args = append(args, reflect.ValueOf(&arg0[i]).Elem())
+	}
+	results, err := stub.Client.Invoke("Sum", args, greeterSumSettings)
+	if err == nil {
+		r0, _ = results[0].Interface().(int)
+	}
+	return
+}
+
+var greeterMoveSettings = &rpc.InvokeSettings{
+	Simple: true,
+	ResultTypes: []reflect.Type{
+		reflect.TypeOf((*Point)(nil)).Elem(),
+		reflect.TypeOf((*time.Duration)(nil)).Elem(),
+	},
+}
+
+// Move invokes the remote method move
+func (stub *GreeterClient) Move(arg0 Point, arg1 time.Duration) (r0 Point, r1 time.Duration, err error) {
+	args := []reflect.Value{
+		reflect.ValueOf(&arg0).Elem(),
+		reflect.ValueOf(&arg1).Elem(),
+	}
+	results, err := stub.Client.Invoke("move", args, greeterMoveSettings)
+	if err == nil {
+		r0, _ = results[0].Interface().(Point)
+		r1, _ = results[1].Interface().(time.Duration)
+	}
+	return
+}
+
+var greeterSwapSettings = &rpc.InvokeSettings{
+	ByRef: true,
+}
+
+// Swap invokes the remote method Swap
+func (stub *GreeterClient) Swap(arg0 *int, arg1 *int) (err error) {
+	args := []reflect.Value{
+		reflect.ValueOf(&arg0).Elem(),
+		reflect.ValueOf(&arg1).Elem(),
+	}
+	_, err = stub.Client.Invoke("Swap", args, greeterSwapSettings)
+	return
+}
+
+var greeterTagsSettings = &rpc.InvokeSettings{
+	ResultTypes: []reflect.Type{
+		reflect.TypeOf((*int)(nil)).Elem(),
+	},
+}
+
+// Tags invokes the remote method Tags
+func (stub *GreeterClient) Tags(arg0 map[string][]string) (r0 int, err error) {
+	args := []reflect.Value{
+		reflect.ValueOf(&arg0).Elem(),
+	}
+	results, err := stub.Client.Invoke("Tags", args, greeterTagsSettings)
+	if err == nil {
+		r0, _ = results[0].Interface().(int)
+	}
+	return
+}
+
+var greeterNoErrorSettings = &rpc.InvokeSettings{
+	ResultTypes: []reflect.Type{
+		reflect.TypeOf((*string)(nil)).Elem(),
+	},
+}
+
+// NoError invokes the remote method NoError
+func (stub *GreeterClient) NoError(arg0 string) (r0 string) {
+	args := []reflect.Value{
+		reflect.ValueOf(&arg0).Elem(),
+	}
+	results, err := stub.Client.Invoke("NoError", args, greeterNoErrorSettings)
+	if err != nil {
+		panic(err)
+	}
+	r0, _ = results[0].Interface().(string)
+	return
+}
+
+var greeterMaybeSettings = &rpc.InvokeSettings{
+	ResultTypes: []reflect.Type{
+		reflect.TypeOf((*interface{})(nil)).Elem(),
+	},
+}
+
+// Maybe invokes the remote method Maybe
+func (stub *GreeterClient) Maybe() (r0 interface{}, err error) {
+	args := []reflect.Value{}
+	results, err := stub.Client.Invoke("Maybe", args, greeterMaybeSettings)
+	if err == nil {
+		r0, _ = results[0].Interface().(interface{})
+	}
+	return
+}
+
+var greeterFireSettings = &rpc.InvokeSettings{
+	Oneway: true,
+}
+
+// Fire invokes the remote method Fire
+func (stub *GreeterClient) Fire(arg0 string) (err error) {
+	args := []reflect.Value{
+		reflect.ValueOf(&arg0).Elem(),
+	}
+	_, err = stub.Client.Invoke("Fire", args, greeterFireSettings)
+	return
+}
+
+var greeterLaterSettings = &rpc.InvokeSettings{
+	ResultTypes: []reflect.Type{
+		reflect.TypeOf((*interface{})(nil)).Elem(),
+	},
+}
+
+// Later invokes the remote method Later
+func (stub *GreeterClient) Later(ctx gocontext.Context, arg0 ...int) (r0 *rpc.Future) {
+	args := make([]reflect.Value, 0, 0+len(arg0))
+	for i := range arg0 {
+		args = append(args, reflect.ValueOf(&arg0[i]).Elem())
+	}
+	return stub.Client.InvokeAsyncContext(ctx, "Later", args, greeterLaterSettings)
+}
+
+var greeterRawSettings = &rpc.InvokeSettings{
+	Failswitch: true,
+	Mode:       rpc.Raw,
+	ResultTypes: []reflect.Type{
+		reflect.TypeOf((*[]byte)(nil)).Elem(),
+	},
+}
+
+// Raw invokes the remote method Raw
+func (stub *GreeterClient) Raw() (r0 []byte, err error) {
+	args := []reflect.Value{}
+	results, err := stub.Client.Invoke("Raw", args, greeterRawSettings)
+	if err == nil {
+		r0, _ = results[0].Interface().([]byte)
+	}
+	return
+}
+
+// RegisterGreeter publishes the methods of impl to service
+func RegisterGreeter(service rpc.Service, impl Greeter) {
+	service.AddFunction("Ping", impl.Ping, rpc.Options{})
+	service.AddFunction("Hello", func(arg0 string) (string, error) {
+		return impl.Hello(gocontext.Background(), arg0)
+	}, rpc.Options{})
+	service.AddFunction("Sum", impl.Sum, rpc.Options{})
+	service.AddFunction("move", impl.Move, rpc.Options{Simple: true})
+	service.AddFunction("Swap", impl.Swap, rpc.Options{})
+	service.AddFunction("Tags", impl.Tags, rpc.Options{})
+	service.AddFunction("NoError", impl.NoError, rpc.Options{})
+	service.AddFunction("Maybe", impl.Maybe, rpc.Options{})
+	service.AddFunction("Fire", impl.Fire, rpc.Options{Oneway: true})
+	service.AddFunction("Later", func(arg0 ...int) (interface{}, error) {
+		results, err := impl.Later(gocontext.Background(), arg0...).Wait()
+		if err != nil || len(results) == 0 || !results[0].IsValid() {
+			return nil, err
+		}
+		return results[0].Interface(), nil
+	}, rpc.Options{})
+	service.AddFunction("Raw", impl.Raw, rpc.Options{})
+}
@@ -0,0 +1,108 @@
+package greeter
+
+import (
+	gocontext "context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hprose/hprose-golang/rpc"
+)
+
+type greeter struct {
+	fired chan string
+}
+
+func (greeter) Ping() error { return nil }
+
+func (greeter) Hello(ctx gocontext.Context, name string) (string, error) {
+	return "Hello " + name, nil
+}
+
+func (greeter) Sum(nums ...int) (int, error) {
+	sum := 0
+	for _, n := range nums {
+		sum += n
+	}
+	return sum, nil
+}
+
+func (greeter) Move(p Point, d time.Duration) (Point, time.Duration, error) {
+	return Point{p.X + 1, p.Y + 1}, d * 2, nil
+}
+
+func (greeter) Swap(a, b *int) error {
+	*a, *b = *b, *a
+	return nil
+}
+
+func (greeter) Tags(tags map[string][]string) (int, error) {
+	return len(tags), nil
+}
+
+func (greeter) NoError(s string) string { return s + "!" }
+
+func (greeter) Maybe() (interface{}, error) { return nil, errors.New("maybe") }
+
+func (g greeter) Fire(s string) error {
+	g.fired <- s
+	return nil
+}
+
+func (greeter) Later(ctx gocontext.Context, xs ...int) *rpc.Future {
+	return rpc.All()
+}
+
+func (greeter) Raw() ([]byte, error) { return []byte("raw"), nil }
+
+func TestGreeter(t *testing.T) {
+	server := rpc.NewTCPServer("tcp://127.0.0.1:0")
+	impl := greeter{make(chan string, 1)}
+	RegisterGreeter(server, impl)
+	if err := server.Handle(); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	stub := NewGreeterClient(rpc.NewTCPClient(server.URI()))
+	defer stub.Client.Close()
+	if err := stub.Ping(); err != nil {
+		t.Error(err)
+	}
+	if s, err := stub.Hello(gocontext.Background(), "world"); s != "Hello world" || err != nil {
+		t.Error(s, err)
+	}
+	if n, err := stub.Sum(1, 2, 3); n != 6 || err != nil {
+		t.Error(n, err)
+	}
+	if n, err := stub.Sum(); n != 0 || err != nil {
+		t.Error(n, err)
+	}
+	if p, d, err := stub.Move(Point{1, 2}, time.Second); p != (Point{2, 3}) || d != 2*time.Second || err != nil {
+		t.Error(p, d, err)
+	}
+	a, b := 1, 2
+	if err := stub.Swap(&a, &b); a != 2 || b != 1 || err != nil {
+		t.Error(a, b, err)
+	}
+	if n, err := stub.Tags(map[string][]string{"a": {"b"}}); n != 1 || err != nil {
+		t.Error(n, err)
+	}
+	if s := stub.NoError("a"); s != "a!" {
+		t.Error(s)
+	}
+	if v, err := stub.Maybe(); v != nil || err == nil || err.Error() != "maybe" {
+		t.Error(v, err)
+	}
+	if err := stub.Fire("fire"); err != nil {
+		t.Error(err)
+	}
+	if s := <-impl.fired; s != "fire" {
+		t.Error(s)
+	}
+	if _, err := stub.Later(gocontext.Background(), 1, 2).Wait(); err != nil {
+		t.Error(err)
+	}
+	if data, err := stub.Raw(); len(data) == 0 || err != nil {
+		t.Error(data, err)
+	}
+}
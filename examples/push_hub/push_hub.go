@@ -0,0 +1,29 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/hprose/hprose-golang/rpc"
+)
+
+// Run the hub, then start the push servers with
+//
+//	server.SetPushBroker(rpc.NewTCPPushBroker("tcp4://127.0.0.1:2017/"))
+func main() {
+	uri := flag.String("uri", "tcp4://0.0.0.0:2017/", "the listen address")
+	flag.Parse()
+	hub := rpc.NewPushHub(*uri)
+	if err := hub.Handle(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Println("push hub is listening on " + hub.URI())
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
+	<-c
+	hub.Close()
+}
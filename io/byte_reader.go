@@ -12,7 +12,7 @@
  *                                                        *
  * byte reader for Go.                                    *
  *                                                        *
- * LastModified: Oct 15, 2016                             *
+ * LastModified: Oct 18, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -262,7 +262,7 @@ func (r *ByteReader) readString() (result string) {
 func (r *ByteReader) readInf() float64 {
 	// '+' - '+' == 0 >= 0, return positive infinity
 	// '+' - '-' == -2 < 0, return negative infinity
-	return math.Inf(int(TagPos - r.readByte()))
+	return math.Inf(int(TagPos) - int(r.readByte()))
 }
 
 func (r *ByteReader) readNsec() (nsec int, tag byte) {
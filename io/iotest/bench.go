@@ -0,0 +1,153 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * io/iotest/bench.go                                     *
+ *                                                        *
+ * hprose serialization benchmarks for Go.                *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package iotest
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/hprose/hprose-golang/io"
+)
+
+// Format is a serialization format for comparison
+type Format struct {
+	Name      string
+	Marshal   func(v interface{}) ([]byte, error)
+	Unmarshal func(data []byte, p interface{}) error
+}
+
+// Hprose returns the hprose serialization format
+func Hprose(simple bool) Format {
+	name := "hprose"
+	if simple {
+		name += " (simple)"
+	}
+	return Format{
+		Name: name,
+		Marshal: func(v interface{}) (data []byte, err error) {
+			defer func() {
+				if e := recover(); e != nil {
+					err = fmt.Errorf("%v", e)
+				}
+			}()
+			return io.NewWriter(simple).Serialize(v).Bytes(), nil
+		},
+		Unmarshal: func(data []byte, p interface{}) (err error) {
+			defer func() {
+				if e := recover(); e != nil {
+					err = fmt.Errorf("%v", e)
+				}
+			}()
+			io.NewReader(data, simple).Unserialize(p)
+			return nil
+		},
+	}
+}
+
+// JSON is the encoding/json format
+var JSON = Format{"json", json.Marshal, json.Unmarshal}
+
+// Gob is the encoding/gob format
+var Gob = Format{
+	Name: "gob",
+	Marshal: func(v interface{}) ([]byte, error) {
+		var buf bytes.Buffer
+		err := gob.NewEncoder(&buf).Encode(v)
+		return buf.Bytes(), err
+	},
+	Unmarshal: func(data []byte, p interface{}) error {
+		return gob.NewDecoder(bytes.NewReader(data)).Decode(p)
+	},
+}
+
+// Result is the benchmark result of a format
+type Result struct {
+	Format    string
+	Size      int
+	Marshal   testing.BenchmarkResult
+	Unmarshal testing.BenchmarkResult
+	Err       error
+}
+
+func (r Result) String() string {
+	if r.Err != nil {
+		return fmt.Sprintf("%-16s error: %v", r.Format, r.Err)
+	}
+	return fmt.Sprintf("%-16s %8d bytes %12d ns/marshal %12d ns/unmarshal",
+		r.Format, r.Size, r.Marshal.NsPerOp(), r.Unmarshal.NsPerOp())
+}
+
+// BenchmarkMarshal benchmarks the marshaling of v with format
+func BenchmarkMarshal(b *testing.B, format Format, v interface{}) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := format.Marshal(v); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkUnmarshal benchmarks the unmarshaling of v with format
+func BenchmarkUnmarshal(b *testing.B, format Format, v interface{}) {
+	data, err := format.Marshal(v)
+	if err != nil {
+		b.Fatal(err)
+	}
+	typ := reflect.TypeOf(v)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := format.Unmarshal(data, reflect.New(typ).Interface()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Compare benchmarks v with formats, the default formats are hprose,
+// hprose in simple mode, encoding/json and encoding/gob.
+func Compare(v interface{}, formats ...Format) []Result {
+	if len(formats) == 0 {
+		formats = []Format{Hprose(false), Hprose(true), JSON, Gob}
+	}
+	results := make([]Result, len(formats))
+	for i, format := range formats {
+		results[i].Format = format.Name
+		data, err := format.Marshal(v)
+		if err == nil {
+			err = format.Unmarshal(data, reflect.New(reflect.TypeOf(v)).Interface())
+		}
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		results[i].Size = len(data)
+		results[i].Marshal = testing.Benchmark(func(b *testing.B) {
+			BenchmarkMarshal(b, format, v)
+		})
+		results[i].Unmarshal = testing.Benchmark(func(b *testing.B) {
+			BenchmarkUnmarshal(b, format, v)
+		})
+	}
+	return results
+}
@@ -0,0 +1,147 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * io/iotest/golden.go                                    *
+ *                                                        *
+ * hprose serialization golden vectors for Go.            *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package iotest
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"time"
+)
+
+// Vector is a golden vector of the hprose serialization
+//
+// Data is serialized in non-simple mode. When DecodeOnly is true, Data is a
+// valid encoding of Value which is different from what the Go Writer produces,
+// it is only used for checking the decoder.
+type Vector struct {
+	Name       string
+	Value      interface{}
+	Data       string
+	DecodeOnly bool
+}
+
+// Point is the struct used in the golden vectors
+type Point struct {
+	X int
+	Y int
+}
+
+func bigInt(s string) *big.Int {
+	i, _ := new(big.Int).SetString(s, 10)
+	return i
+}
+
+// Vectors returns the golden vectors
+func Vectors() []Vector {
+	return []Vector{
+		{"nil", nil, "n", false},
+		{"zero", 0, "0", false},
+		{"digit", 9, "9", false},
+		{"int", 123, "i123;", false},
+		{"negative int", -123, "i-123;", false},
+		{"max int32", math.MaxInt32, "i2147483647;", false},
+		{"long", int64(math.MaxInt32) + 1, "l2147483648;", false},
+		{"max uint64", uint64(math.MaxUint64), "l18446744073709551615;", false},
+		{"double", 3.14, "d3.14;", false},
+		{"float32", float32(1.5), "d1.5;", false},
+		{"positive infinity", math.Inf(1), "I+", false},
+		{"negative infinity", math.Inf(-1), "I-", false},
+		{"true", true, "t", false},
+		{"false", false, "f", false},
+		{"empty string", "", "e", false},
+		{"char", "A", "uA", false},
+		{"unicode char", "你", "u你", false},
+		{"string", "hello", `s5"hello"`, false},
+		{"unicode string", "你好", `s2"你好"`, false},
+		{"surrogate pair", "😀", `s2"😀"`, false},
+		{"bytes", []byte("hello"), `b5"hello"`, false},
+		{"empty bytes", []byte{}, `b""`, false},
+		{"list", []int{1, 2, 3}, "a3{123}", false},
+		{"empty list", []int{}, "a{}", false},
+		{"map", map[string]int{"a": 1}, "m1{ua1}", false},
+		{"empty map", map[string]int{}, "m{}", false},
+		{"big int", bigInt("123456789012345678901234567890"),
+			"l123456789012345678901234567890;", false},
+		{"date", time.Date(1980, 12, 1, 0, 0, 0, 0, time.UTC),
+			"D19801201Z", false},
+		{"time", time.Date(1970, 1, 1, 12, 34, 56, 789000000, time.UTC),
+			"T123456.789Z", false},
+		{"datetime", time.Date(1980, 12, 1, 12, 34, 56, 0, time.UTC),
+			"D19801201T123456Z", false},
+		{"object", Point{1, 2}, `c5"Point"2{s1"x"s1"y"}o0{12}`, false},
+		{"objects", []*Point{{1, 2}, {3, 4}},
+			`a2{c5"Point"2{s1"x"s1"y"}o0{12}o0{34}}`, false},
+		// the equivalent encodings which the Go Writer does not produce
+		{"string ref", []string{"hello", "hello"},
+			`a2{s5"hello"r1;}`, true},
+		{"long as int", 123, "l123;", true},
+		{"integral double", 1.0, "d1;", true},
+		{"exponent double", 1e100, "d1e+100;", true},
+		{"map of strings", map[string]string{"name": "Tom", "city": "Tom"},
+			`m2{s4"name"s3"Tom"s4"city"r2;}`, true},
+		{"object of map", Point{1, 2}, `m2{ux1uy2}`, true},
+	}
+}
+
+// CheckEncoder checks encode with the golden vectors which are not decode only,
+// it returns the errors of the mismatched vectors.
+func CheckEncoder(encode func(v interface{}) ([]byte, error)) (errs []error) {
+	for _, vector := range Vectors() {
+		if vector.DecodeOnly {
+			continue
+		}
+		data, err := encode(vector.Value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", vector.Name, err))
+			continue
+		}
+		if !bytes.Equal(data, []byte(vector.Data)) {
+			errs = append(errs, fmt.Errorf("%s: expected %s, got %s",
+				vector.Name, vector.Data, data))
+		}
+	}
+	return
+}
+
+// CheckDecoder checks decode with all the golden vectors, decode should
+// unserialize data to p which is the pointer to the type of the vector value,
+// it returns the errors of the mismatched vectors.
+func CheckDecoder(decode func(data []byte, p interface{}) error) (errs []error) {
+	for _, vector := range Vectors() {
+		var p reflect.Value
+		if vector.Value == nil {
+			p = reflect.New(reflect.TypeOf((*interface{})(nil)).Elem())
+		} else {
+			p = reflect.New(reflect.TypeOf(vector.Value))
+		}
+		if err := decode([]byte(vector.Data), p.Interface()); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", vector.Name, err))
+			continue
+		}
+		if !Equal(p.Elem().Interface(), vector.Value) {
+			errs = append(errs, fmt.Errorf("%s: expected %#v, got %#v",
+				vector.Name, vector.Value, p.Elem().Interface()))
+		}
+	}
+	return
+}
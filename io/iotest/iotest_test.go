@@ -0,0 +1,116 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * io/iotest/iotest_test.go                               *
+ *                                                        *
+ * hprose serialization test utilities test for Go.       *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package iotest
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/hprose/hprose-golang/io"
+)
+
+func TestPayloadsRoundTrip(t *testing.T) {
+	for _, payload := range Payloads() {
+		if err := Check(payload.Value, false); err != nil {
+			t.Error(payload.Name, err)
+		}
+	}
+}
+
+func TestRefsRoundTrip(t *testing.T) {
+	result, err := RoundTrip(Refs(20, 5, 1), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	refs := result.([]*Person)
+	if refs[0] != refs[5] || refs[0] == refs[1] {
+		t.Error("the references are not kept")
+	}
+}
+
+type quickStruct struct {
+	A int8
+	B uint32
+	C float64
+	D string
+	E []int
+	F map[string]bool
+	G *quickStruct
+}
+
+func TestCheckType(t *testing.T) {
+	types := []reflect.Type{
+		reflect.TypeOf(0),
+		reflect.TypeOf(""),
+		reflect.TypeOf([]float32{}),
+		reflect.TypeOf(map[int]string{}),
+		reflect.TypeOf(quickStruct{}),
+	}
+	r := rand.New(rand.NewSource(1))
+	for _, typ := range types {
+		if err := CheckType(typ, 50, false, r); err != nil {
+			t.Error(typ, err)
+		}
+	}
+}
+
+func TestVectors(t *testing.T) {
+	errs := CheckEncoder(func(v interface{}) ([]byte, error) {
+		return io.Serialize(v, false), nil
+	})
+	for _, err := range errs {
+		t.Error(err)
+	}
+	errs = CheckDecoder(func(data []byte, p interface{}) error {
+		return Hprose(false).Unmarshal(data, p)
+	})
+	for _, err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping comparison in short mode")
+	}
+	for _, result := range Compare(People(10, 1)) {
+		if result.Err != nil {
+			t.Error(result.Format, result.Err)
+		}
+		t.Log(result)
+	}
+}
+
+func BenchmarkSerialize(b *testing.B) {
+	for _, payload := range Payloads() {
+		b.Run(payload.Name, func(b *testing.B) {
+			BenchmarkMarshal(b, Hprose(false), payload.Value)
+		})
+	}
+}
+
+func BenchmarkUnserialize(b *testing.B) {
+	for _, payload := range Payloads() {
+		b.Run(payload.Name, func(b *testing.B) {
+			BenchmarkUnmarshal(b, Hprose(false), payload.Value)
+		})
+	}
+}
@@ -0,0 +1,204 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * io/iotest/payload.go                                   *
+ *                                                        *
+ * hprose serialization test payloads for Go.             *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+/*
+Package iotest implements the utilities for testing and benchmarking the
+hprose serialization, it includes the payload generators, the round trip
+checking, the golden vectors and the comparison with other formats.
+*/
+package iotest
+
+import (
+	"math/big"
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+// Payload is a named value for testing and benchmarking
+type Payload struct {
+	Name  string
+	Value interface{}
+}
+
+// Address is a flat struct in the payloads
+type Address struct {
+	Street string
+	City   string
+	Zip    int
+}
+
+// Person is a struct with nested struct, slice, map and time fields
+type Person struct {
+	ID       int64
+	Name     string
+	Email    string
+	Age      int
+	Score    float64
+	Active   bool
+	Tags     []string
+	Address  *Address
+	Metrics  map[string]float64
+	Birthday time.Time
+}
+
+// Tree is a recursive struct
+type Tree struct {
+	Value    int
+	Children []*Tree
+}
+
+var words = []string{
+	"hprose", "rpc", "golang", "serialize", "reader", "writer",
+	"stream", "hello", "world", "你好", "世界", "😀",
+}
+
+func word(r *rand.Rand) string {
+	return words[r.Intn(len(words))]
+}
+
+// NewPerson returns a Person generated by r
+func NewPerson(r *rand.Rand) *Person {
+	p := &Person{
+		ID:     r.Int63(),
+		Name:   word(r) + " " + word(r),
+		Email:  word(r) + "@hprose.com",
+		Age:    r.Intn(100),
+		Score:  r.Float64() * 100,
+		Active: r.Intn(2) == 1,
+		Address: &Address{
+			Street: strconv.Itoa(r.Intn(1000)) + " " + word(r) + " Street",
+			City:   word(r),
+			Zip:    r.Intn(100000),
+		},
+		Metrics: map[string]float64{
+			"cpu":    r.Float64(),
+			"memory": r.Float64(),
+		},
+		Birthday: time.Date(1950+r.Intn(60), time.Month(1+r.Intn(12)),
+			1+r.Intn(28), 0, 0, 0, 0, time.UTC),
+	}
+	n := r.Intn(5) + 1
+	p.Tags = make([]string, n)
+	for i := 0; i < n; i++ {
+		p.Tags[i] = word(r)
+	}
+	return p
+}
+
+// People returns n Persons generated with seed
+func People(n int, seed int64) []*Person {
+	r := rand.New(rand.NewSource(seed))
+	people := make([]*Person, n)
+	for i := 0; i < n; i++ {
+		people[i] = NewPerson(r)
+	}
+	return people
+}
+
+// NewTree returns a complete tree with the depth and the width
+func NewTree(depth int, width int) *Tree {
+	value := 0
+	var build func(depth int) *Tree
+	build = func(depth int) *Tree {
+		value++
+		t := &Tree{Value: value}
+		if depth > 1 {
+			t.Children = make([]*Tree, width)
+			for i := 0; i < width; i++ {
+				t.Children[i] = build(depth - 1)
+			}
+		}
+		return t
+	}
+	return build(depth)
+}
+
+// Map returns a map with n mixed type values generated with seed
+func Map(n int, seed int64) map[string]interface{} {
+	r := rand.New(rand.NewSource(seed))
+	m := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		key := word(r) + strconv.Itoa(i)
+		switch i % 4 {
+		case 0:
+			m[key] = r.Intn(1 << 20)
+		case 1:
+			m[key] = r.Float64()
+		case 2:
+			m[key] = word(r)
+		default:
+			m[key] = []interface{}{i, word(r), true}
+		}
+	}
+	return m
+}
+
+// List returns a list of n integers from 0 to n - 1
+func List(n int) []int {
+	list := make([]int, n)
+	for i := 0; i < n; i++ {
+		list[i] = i
+	}
+	return list
+}
+
+// Refs returns a list of n elements which refer to the same m Persons,
+// it is only round tripped with the identity in non-simple mode.
+func Refs(n int, m int, seed int64) []*Person {
+	people := People(m, seed)
+	refs := make([]*Person, n)
+	for i := 0; i < n; i++ {
+		refs[i] = people[i%m]
+	}
+	return refs
+}
+
+// BigNumbers returns n big integers with 1 to n * 8 decimal digits
+func BigNumbers(n int, seed int64) []*big.Int {
+	r := rand.New(rand.NewSource(seed))
+	numbers := make([]*big.Int, n)
+	for i := 0; i < n; i++ {
+		digits := make([]byte, (i+1)*8)
+		digits[0] = byte('1' + r.Intn(9))
+		for j := 1; j < len(digits); j++ {
+			digits[j] = byte('0' + r.Intn(10))
+		}
+		if r.Intn(2) == 1 {
+			digits = append([]byte{'-'}, digits...)
+		}
+		numbers[i], _ = new(big.Int).SetString(string(digits), 10)
+	}
+	return numbers
+}
+
+// Payloads returns the representative payloads
+func Payloads() []Payload {
+	return []Payload{
+		{"int", 123456},
+		{"string", "hello hprose, 你好, 😀"},
+		{"struct", NewPerson(rand.New(rand.NewSource(1)))},
+		{"people", People(100, 1)},
+		{"tree", NewTree(6, 3)},
+		{"map", Map(100, 1)},
+		{"list", List(1000)},
+		{"refs", Refs(100, 10, 1)},
+		{"bignumbers", BigNumbers(10, 1)},
+	}
+}
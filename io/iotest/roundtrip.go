@@ -0,0 +1,174 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * io/iotest/roundtrip.go                                 *
+ *                                                        *
+ * hprose serialization round trip checking for Go.       *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package iotest
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing/quick"
+	"time"
+
+	"github.com/hprose/hprose-golang/io"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// RoundTrip serializes v with the Writer, then unserializes the data to a
+// new value of the same type with the Reader and returns it.
+func RoundTrip(v interface{}, simple bool) (result interface{}, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = fmt.Errorf("%v", e)
+		}
+	}()
+	data := io.NewWriter(simple).Serialize(v).Bytes()
+	if v == nil {
+		io.NewReader(data, simple).Unserialize(&result)
+		return
+	}
+	p := reflect.New(reflect.TypeOf(v))
+	io.NewReader(data, simple).Unserialize(p.Interface())
+	return p.Elem().Interface(), nil
+}
+
+type visit struct {
+	x, y uintptr
+	typ  reflect.Type
+}
+
+// Equal reports whether x and y are deeply equal. It is like
+// reflect.DeepEqual, but the nil and empty slices or maps are equal, because
+// the Reader unserializes the empty list to the nil slice, and time.Time
+// values are compared with the Equal method.
+func Equal(x, y interface{}) bool {
+	if x == nil || y == nil {
+		return x == y
+	}
+	v1, v2 := reflect.ValueOf(x), reflect.ValueOf(y)
+	if v1.Type() != v2.Type() {
+		return false
+	}
+	return deepEqual(v1, v2, make(map[visit]bool))
+}
+
+func deepEqual(v1, v2 reflect.Value, visited map[visit]bool) bool {
+	switch v1.Kind() {
+	case reflect.Bool:
+		return v1.Bool() == v2.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v1.Int() == v2.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		return v1.Uint() == v2.Uint()
+	case reflect.Float32, reflect.Float64:
+		return v1.Float() == v2.Float()
+	case reflect.Complex64, reflect.Complex128:
+		return v1.Complex() == v2.Complex()
+	case reflect.String:
+		return v1.String() == v2.String()
+	case reflect.Ptr:
+		if v1.IsNil() || v2.IsNil() {
+			return v1.IsNil() == v2.IsNil()
+		}
+		if v1.Pointer() == v2.Pointer() {
+			return true
+		}
+		key := visit{v1.Pointer(), v2.Pointer(), v1.Type()}
+		if visited[key] {
+			return true
+		}
+		visited[key] = true
+		return deepEqual(v1.Elem(), v2.Elem(), visited)
+	case reflect.Interface:
+		if v1.IsNil() || v2.IsNil() {
+			return v1.IsNil() == v2.IsNil()
+		}
+		if v1.Elem().Type() != v2.Elem().Type() {
+			return false
+		}
+		return deepEqual(v1.Elem(), v2.Elem(), visited)
+	case reflect.Slice, reflect.Array:
+		if v1.Len() != v2.Len() {
+			return false
+		}
+		for i := 0; i < v1.Len(); i++ {
+			if !deepEqual(v1.Index(i), v2.Index(i), visited) {
+				return false
+			}
+		}
+		return true
+	case reflect.Map:
+		if v1.Len() != v2.Len() {
+			return false
+		}
+		for _, k := range v1.MapKeys() {
+			e := v2.MapIndex(k)
+			if !e.IsValid() || !deepEqual(v1.MapIndex(k), e, visited) {
+				return false
+			}
+		}
+		return true
+	case reflect.Struct:
+		if v1.Type() == timeType && v1.CanInterface() {
+			return v1.Interface().(time.Time).Equal(v2.Interface().(time.Time))
+		}
+		for i := 0; i < v1.NumField(); i++ {
+			if !deepEqual(v1.Field(i), v2.Field(i), visited) {
+				return false
+			}
+		}
+		return true
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return v1.Pointer() == v2.Pointer()
+	}
+	return false
+}
+
+// Check returns an error if the round trip result of v is not equal to v
+func Check(v interface{}, simple bool) error {
+	result, err := RoundTrip(v, simple)
+	if err != nil {
+		return err
+	}
+	if !Equal(v, result) {
+		return fmt.Errorf("round trip %#v, got %#v", v, result)
+	}
+	return nil
+}
+
+// CheckType checks the round trip of count random values of typ, the values
+// are generated by testing/quick with r, so typ can't have interface or
+// unexported fields.
+func CheckType(typ reflect.Type, count int, simple bool, r *rand.Rand) error {
+	if r == nil {
+		r = rand.New(rand.NewSource(1))
+	}
+	for i := 0; i < count; i++ {
+		v, ok := quick.Value(typ, r)
+		if !ok {
+			return fmt.Errorf("can't generate the value of %s", typ)
+		}
+		if err := Check(v.Interface(), simple); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -12,7 +12,7 @@
  *                                                        *
  * hprose ptr decoder for Go.                             *
  *                                                        *
- * LastModified: Sep 10, 2016                             *
+ * LastModified: Oct 18, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -29,6 +29,15 @@ func ptrDecoder(r *Reader, v reflect.Value, tag byte) {
 		v.Set(reflect.Zero(v.Type()))
 		return
 	}
+	if tag == TagRef && !r.Simple {
+		off := r.off
+		ref, ok := r.readRef().(reflect.Value)
+		if ok && ref.CanAddr() && ref.Type() == v.Type().Elem() {
+			v.Set(ref.Addr())
+			return
+		}
+		r.off = off
+	}
 	if v.IsNil() {
 		v.Set(reflect.New(v.Type().Elem()))
 	}
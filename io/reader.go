@@ -12,7 +12,7 @@
  *                                                        *
  * hprose reader for Go.                                  *
  *                                                        *
- * LastModified: Oct 15, 2016                             *
+ * LastModified: Oct 18, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -31,6 +31,8 @@ import (
 
 // Reader is a fine-grained operation struct for Hprose unserialization
 // when JSONCompatible is true, the Map data will unserialize to map[string]interface as the default type
+// when Location is not nil, the UTC time will be converted to Location, and
+// the local time will be unserialized in Location instead of time.Local
 type Reader struct {
 	RawReader
 	Simple         bool
@@ -38,6 +40,7 @@ type Reader struct {
 	fieldsRef      [][]*fieldCache
 	ref            []interface{}
 	JSONCompatible bool
+	Location       *time.Location
 }
 
 // NewReader is the constructor for Hprose Reader
@@ -213,9 +216,16 @@ func (r *Reader) ReadTime() time.Time {
 
 // ReadDateTimeWithoutTag from the reader
 func (r *Reader) ReadDateTimeWithoutTag() (dt time.Time) {
-	year := r.read4Digit()
-	month := r.read2Digit()
-	day := r.read2Digit()
+	dt, _, _, _ = r.readDateTime()
+	return
+}
+
+// readDateTime returns the date time, and the year, month and day before it is
+// converted to Location.
+func (r *Reader) readDateTime() (dt time.Time, year, month, day int) {
+	year = r.read4Digit()
+	month = r.read2Digit()
+	day = r.read2Digit()
 	tag := r.readByte()
 	var hour, min, sec, nsec int
 	if tag == TagTime {
@@ -227,13 +237,7 @@ func (r *Reader) ReadDateTimeWithoutTag() (dt time.Time) {
 			nsec, tag = r.readNsec()
 		}
 	}
-	var loc *time.Location
-	if tag == TagUTC {
-		loc = time.UTC
-	} else {
-		loc = time.Local
-	}
-	dt = time.Date(year, time.Month(month), day, hour, min, sec, nsec, loc)
+	dt = r.toTime(year, month, day, hour, min, sec, nsec, tag)
 	if !r.Simple {
 		setReaderRef(r, &dt)
 	}
@@ -250,13 +254,7 @@ func (r *Reader) ReadTimeWithoutTag() (t time.Time) {
 	if tag == TagPoint {
 		nsec, tag = r.readNsec()
 	}
-	var loc *time.Location
-	if tag == TagUTC {
-		loc = time.UTC
-	} else {
-		loc = time.Local
-	}
-	t = time.Date(1970, 1, 1, hour, min, sec, nsec, loc)
+	t = r.toTime(1970, 1, 1, hour, min, sec, nsec, tag)
 	if !r.Simple {
 		setReaderRef(r, &t)
 	}
@@ -320,6 +318,22 @@ func (r *Reader) Reset() {
 
 // private methods & functions
 
+func (r *Reader) toTime(
+	year, month, day, hour, min, sec, nsec int, tag byte) time.Time {
+	if tag == TagUTC {
+		t := time.Date(year, time.Month(month), day, hour, min, sec, nsec, time.UTC)
+		if r.Location != nil {
+			return t.In(r.Location)
+		}
+		return t
+	}
+	loc := r.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	return time.Date(year, time.Month(month), day, hour, min, sec, nsec, loc)
+}
+
 func (r *Reader) readRef() interface{} {
 	if r.Simple {
 		panic(errors.New("reference unserialization can't support in simple mode"))
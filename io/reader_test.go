@@ -12,7 +12,7 @@
  *                                                        *
  * hprose Reader Test for Go.                             *
  *                                                        *
- * LastModified: Oct 13, 2016                             *
+ * LastModified: Oct 18, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -525,6 +525,22 @@ func TestUnserializeFloat64(t *testing.T) {
 	}
 }
 
+func TestUnserializeInf(t *testing.T) {
+	w := NewWriter(false)
+	w.Serialize(math.Inf(1))
+	w.Serialize(math.Inf(-1))
+	reader := NewReader(w.Bytes(), false)
+	var p float64
+	reader.Unserialize(&p)
+	if !math.IsInf(p, 1) {
+		t.Error(math.Inf(1), p)
+	}
+	reader.Unserialize(&p)
+	if !math.IsInf(p, -1) {
+		t.Error(math.Inf(-1), p)
+	}
+}
+
 func BenchmarkReadFloat64(b *testing.B) {
 	w := NewWriter(true)
 	w.Serialize(3.14159)
@@ -1511,6 +1527,61 @@ func TestUnserializeTime(t *testing.T) {
 	}
 }
 
+func TestUnserializeTimeWithLocation(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*3600)
+	t1 := time.Date(1980, 12, 1, 12, 34, 56, 0, time.UTC)
+	t2 := time.Date(1980, 12, 1, 12, 34, 56, 0, time.Local)
+	w := NewWriter(true)
+	w.Serialize(t1)
+	w.Serialize(t2)
+	reader := NewReader(w.Bytes(), true)
+	reader.Location = loc
+	var p time.Time
+	reader.Unserialize(&p)
+	if !p.Equal(t1) || p.Location() != loc {
+		t.Error(p, t1)
+	}
+	reader.Unserialize(&p)
+	if p != time.Date(1980, 12, 1, 12, 34, 56, 0, loc) {
+		t.Error(p)
+	}
+}
+
+func TestUnserializeDateOnly(t *testing.T) {
+	w := NewWriter(true)
+	w.Serialize(time.Date(2016, 10, 18, 0, 0, 0, 0, time.UTC))
+	reader := NewReader(w.Bytes(), true)
+	var d civilDate
+	reader.Unserialize(&d)
+	if d != (civilDate{2016, time.October, 18}) {
+		t.Error(d)
+	}
+}
+
+func TestCivilDateLocation(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	d := civilDate{2026, time.January, 1}
+	w := NewWriter(true)
+	w.DateOnly = true
+	w.UTC = true
+	w.Serialize(d)
+	w.Serialize(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	w.Serialize(time.Date(1970, 1, 1, 1, 0, 0, 0, time.UTC))
+	reader := NewReader(w.Bytes(), true)
+	reader.Location = loc
+	// the day is not shifted by the Location of the reader
+	for _, want := range []civilDate{d, d, {1970, time.January, 1}} {
+		var got civilDate
+		reader.Unserialize(&got)
+		if got != want {
+			t.Error(got, want)
+		}
+	}
+}
+
 func BenchmarkUnserializeTime(b *testing.B) {
 	w := NewWriter(true)
 	w.Serialize(123)
@@ -1652,6 +1723,25 @@ func TestUnserializeStruct(t *testing.T) {
 	}
 }
 
+func TestUnserializeStructPtrRef(t *testing.T) {
+	type Test struct {
+		Name string
+		Zip  int
+	}
+	test := &Test{"Tom", 100000}
+	w := NewWriter(false)
+	w.Serialize([]*Test{test, test})
+	reader := NewReader(w.Bytes(), false)
+	var p []*Test
+	reader.Unserialize(&p)
+	if len(p) != 2 || !reflect.DeepEqual(p[0], test) {
+		t.Error(p, test)
+	}
+	if len(p) == 2 && p[0] != p[1] {
+		t.Error("the reference is not kept")
+	}
+}
+
 func TestUnserializeStructAsInterface(t *testing.T) {
 	type Test struct {
 		Name string
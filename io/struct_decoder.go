@@ -12,7 +12,7 @@
  *                                                        *
  * hprose struct decoder for Go.                          *
  *                                                        *
- * LastModified: Oct 19, 2016                             *
+ * LastModified: Oct 18, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -163,15 +163,37 @@ func readTimeAsStruct(t time.Time, v reflect.Value, tag byte) {
 	case timeType:
 		v.Set(reflect.ValueOf(t))
 	default:
-		castError(tag, v.Type().String())
+		if !isCivilDate(v.Type()) {
+			castError(tag, v.Type().String())
+		}
+		year, month, day := t.Date()
+		setCivilDate(v, year, int(month), day)
 	}
 }
 
+func setCivilDate(v reflect.Value, year, month, day int) {
+	v.Field(0).SetInt(int64(year))
+	v.Field(1).SetInt(int64(month))
+	v.Field(2).SetInt(int64(day))
+}
+
 func readDateTimeStruct(r *Reader, v reflect.Value, tag byte) {
+	if isCivilDate(v.Type()) {
+		// the civil date is taken before the Location conversion, which may
+		// shift the day.
+		_, year, month, day := r.readDateTime()
+		setCivilDate(v, year, month, day)
+		return
+	}
 	readTimeAsStruct(r.ReadDateTimeWithoutTag(), v, tag)
 }
 
 func readTimeStruct(r *Reader, v reflect.Value, tag byte) {
+	if isCivilDate(v.Type()) {
+		r.ReadTimeWithoutTag()
+		setCivilDate(v, 1970, 1, 1)
+		return
+	}
 	readTimeAsStruct(r.ReadTimeWithoutTag(), v, tag)
 }
 
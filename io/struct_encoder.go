@@ -12,7 +12,7 @@
  *                                                        *
  * hprose struct encoder for Go.                          *
  *                                                        *
- * LastModified: Oct 15, 2015                             *
+ * LastModified: Oct 18, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -34,14 +34,18 @@ type fieldCache struct {
 	Index []int
 	Type  reflect.Type
 	Kind  reflect.Kind
+	Views []string
 }
 
 type structCache struct {
-	Alias    string
-	Tag      string
-	Fields   []*fieldCache
-	FieldMap map[string]*fieldCache
-	Data     []byte
+	Alias       string
+	Tag         string
+	Fields      []*fieldCache
+	FieldMap    map[string]*fieldCache
+	Data        []byte
+	allFields   []*fieldCache
+	views       map[string]*structCache
+	viewsLocker sync.RWMutex
 }
 
 var structTypeCache = map[uintptr]*structCache{}
@@ -52,7 +56,7 @@ var structTypesLocker = sync.RWMutex{}
 
 func getFieldAlias(f *reflect.StructField, tag string) (alias string) {
 	fname := f.Name
-	if fname != "" && 'A' <= fname[0] && fname[0] < 'Z' {
+	if fname != "" && 'A' <= fname[0] && fname[0] <= 'Z' {
 		if tag != "" && f.Tag != "" {
 			alias = strings.SplitN(f.Tag.Get(tag), ",", 2)[0]
 			alias = strings.TrimSpace(strings.SplitN(alias, ">", 2)[0])
@@ -67,6 +71,20 @@ func getFieldAlias(f *reflect.StructField, tag string) (alias string) {
 	return alias
 }
 
+func getFieldViews(f *reflect.StructField, tag string) (views []string) {
+	if tag == "" || f.Tag == "" {
+		return nil
+	}
+	options := strings.Split(f.Tag.Get(tag), ",")
+	for _, option := range options[1:] {
+		option = strings.TrimSpace(option)
+		if strings.HasPrefix(option, "view=") {
+			views = append(views, strings.TrimSpace(option[5:]))
+		}
+	}
+	return views
+}
+
 func getSubFields(t reflect.Type, tag string, index []int) []*fieldCache {
 	subFields := getFields(t, tag)
 	for _, subField := range subFields {
@@ -104,16 +122,79 @@ func getFields(t reflect.Type, tag string) []*fieldCache {
 		field.Type = ft
 		field.Kind = fkind
 		field.Index = f.Index
+		field.Views = getFieldViews(&f, tag)
 		fields = append(fields, &field)
 	}
 	return fields
 }
 
+func inView(field *fieldCache, view string) bool {
+	if len(field.Views) == 0 {
+		return true
+	}
+	for _, v := range field.Views {
+		if v == view {
+			return true
+		}
+	}
+	return false
+}
+
+func getViewFields(fields []*fieldCache, view string) []*fieldCache {
+	viewFields := make([]*fieldCache, 0, len(fields))
+	for _, field := range fields {
+		if inView(field, view) {
+			viewFields = append(viewFields, field)
+		}
+	}
+	return viewFields
+}
+
+func initStructCache(cache *structCache, fields []*fieldCache) {
+	cache.FieldMap = make(map[string]*fieldCache, len(fields))
+	for _, field := range fields {
+		cache.FieldMap[field.Alias] = field
+	}
+	cache.Fields = getViewFields(fields, "")
+	if len(cache.Fields) < len(fields) {
+		cache.allFields = fields
+		cache.views = map[string]*structCache{"": cache}
+	}
+	initStructCacheData(cache)
+}
+
+// view returns the struct cache which only contains the fields of the
+// specified view. The fields without view option belong to all views.
+func (cache *structCache) view(name string) *structCache {
+	if cache.views == nil {
+		return cache
+	}
+	cache.viewsLocker.RLock()
+	view, ok := cache.views[name]
+	cache.viewsLocker.RUnlock()
+	if ok {
+		return view
+	}
+	cache.viewsLocker.Lock()
+	defer cache.viewsLocker.Unlock()
+	if view, ok = cache.views[name]; ok {
+		return view
+	}
+	view = &structCache{
+		Alias:    cache.Alias,
+		Tag:      cache.Tag,
+		Fields:   getViewFields(cache.allFields, name),
+		FieldMap: cache.FieldMap,
+	}
+	initStructCacheData(view)
+	cache.views[name] = view
+	return view
+}
+
 func initStructCacheData(cache *structCache) {
 	w := &ByteWriter{}
 	fields := cache.Fields
 	count := len(fields)
-	cache.FieldMap = make(map[string]*fieldCache, count)
 	w.writeByte(TagClass)
 	var buf [20]byte
 	w.write(util.GetIntBytes(buf[:], int64(util.UTF16Length(cache.Alias))))
@@ -125,7 +206,6 @@ func initStructCacheData(cache *structCache) {
 	}
 	w.writeByte(TagOpenbrace)
 	for _, field := range fields {
-		cache.FieldMap[field.Alias] = field
 		w.writeByte(TagString)
 		w.write(util.GetIntBytes(buf[:], int64(util.UTF16Length(field.Alias))))
 		w.writeByte(TagQuote)
@@ -149,8 +229,7 @@ func getStructCache(structType reflect.Type) *structCache {
 	if !ok {
 		cache = &structCache{}
 		cache.Alias = structType.Name()
-		cache.Fields = getFields(structType, "")
-		initStructCacheData(cache)
+		initStructCache(cache, getFields(structType, ""))
 		structTypeCache[typ] = cache
 		structTypesLocker.Lock()
 		structTypes[cache.Alias] = structType
@@ -161,6 +240,13 @@ func getStructCache(structType reflect.Type) *structCache {
 }
 
 // Register structType with alias & tag.
+//
+// The field tag can also specify the views of the field, for example:
+//
+//	Email string `hprose:"email,view=admin"`
+//
+// The field with view options is only serialized when the View of Writer is
+// one of its views. The field without view option belongs to all views.
 func Register(structType reflect.Type, alias string, tag ...string) {
 	if structType.Kind() == reflect.Ptr {
 		structType = structType.Elem()
@@ -177,8 +263,7 @@ func Register(structType reflect.Type, alias string, tag ...string) {
 	if len(tag) == 1 {
 		cache.Tag = tag[0]
 	}
-	cache.Fields = getFields(structType, cache.Tag)
-	initStructCacheData(cache)
+	initStructCache(cache, getFields(structType, cache.Tag))
 	structTypeCache[(*emptyInterface)(unsafe.Pointer(&structType)).ptr] = cache
 	structTypeCacheLocker.Unlock()
 }
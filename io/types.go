@@ -12,7 +12,7 @@
  *                                                        *
  * reflect types for Go.                                  *
  *                                                        *
- * LastModified: Oct 19, 2016                             *
+ * LastModified: Oct 18, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -51,3 +51,17 @@ var listType = getType(list.List{})
 var reflectValueType = getType(reflect.Value{})
 
 var interfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+var intType = reflect.TypeOf(0)
+var monthType = reflect.TypeOf(time.January)
+
+// isCivilDate returns true if t is a struct type like civil.Date, which only
+// has Year int, Month time.Month and Day int fields.
+func isCivilDate(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct || t.NumField() != 3 {
+		return false
+	}
+	year, month, day := t.Field(0), t.Field(1), t.Field(2)
+	return year.Name == "Year" && year.Type == intType &&
+		month.Name == "Month" && month.Type == monthType &&
+		day.Name == "Day" && day.Type == intType
+}
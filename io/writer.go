@@ -12,7 +12,7 @@
  *                                                        *
  * hprose writer for Go.                                  *
  *                                                        *
- * LastModified: Oct 19, 2016                             *
+ * LastModified: Oct 18, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -32,12 +32,25 @@ import (
 )
 
 // Writer is a fine-grained operation struct for Hprose serialization
+//
+// View selects which fields of the registered struct are serialized,
+// the default view "" only includes the fields without view option.
+//
+// When UTC is true, time.Time is always serialized in UTC. TimePrecision
+// truncates the serialized time.Time to a multiple of it, for example
+// time.Millisecond or time.Microsecond, 0 means nanosecond precision.
+// When DateOnly is true, the civil.Date-like struct (a struct which only has
+// Year int, Month time.Month and Day int fields) is serialized as a date.
 type Writer struct {
 	ByteWriter
-	Simple    bool
-	structRef map[uintptr]int
-	ref       map[uintptr]int
-	refCount  int
+	Simple        bool
+	View          string
+	UTC           bool
+	DateOnly      bool
+	TimePrecision time.Duration
+	structRef     map[uintptr]int
+	ref           map[uintptr]int
+	refCount      int
 }
 
 // NewWriter is the constructor for Hprose Writer
@@ -227,6 +240,14 @@ func (w *Writer) WriteTime(t *time.Time) {
 		return
 	}
 	setWriterRef(w, ptr)
+	if w.UTC {
+		utc := t.UTC()
+		t = &utc
+	}
+	if w.TimePrecision > 0 {
+		truncated := t.Truncate(w.TimePrecision)
+		t = &truncated
+	}
 	year, month, day := t.Date()
 	hour, min, sec := t.Clock()
 	nsec := t.Nanosecond()
@@ -246,6 +267,16 @@ func (w *Writer) WriteTime(t *time.Time) {
 	w.writeByte(loc)
 }
 
+func (w *Writer) writeCivilDate(v reflect.Value) {
+	buf := make([]byte, 8)
+	year := int(v.Field(0).Int())
+	month := int(v.Field(1).Int())
+	day := int(v.Field(2).Int())
+	writeDate(w, buf, year, month, day)
+	// a civil date has no time zone, so it is never tagged as UTC.
+	w.writeByte(TagSemicolon)
+}
+
 // WriteList to the writer
 func (w *Writer) WriteList(lst *list.List) {
 	ptr := unsafe.Pointer(lst)
@@ -504,18 +535,24 @@ func writeMap(w *Writer, v reflect.Value) {
 
 func writeStruct(w *Writer, v reflect.Value) {
 	val := (*reflectValue)(unsafe.Pointer(&v))
-	cache := getStructCache(v.Type())
+	if w.DateOnly && isCivilDate(v.Type()) {
+		setWriterRef(w, val.ptr)
+		w.writeCivilDate(v)
+		return
+	}
+	cache := getStructCache(v.Type()).view(w.View)
 	if w.structRef == nil {
 		w.structRef = map[uintptr]int{}
 	}
-	index, found := w.structRef[val.typ]
+	key := uintptr(unsafe.Pointer(cache))
+	index, found := w.structRef[key]
 	if !found {
 		w.write(cache.Data)
 		if !w.Simple {
 			w.refCount += len(cache.Fields)
 		}
 		index = len(w.structRef)
-		w.structRef[val.typ] = index
+		w.structRef[key] = index
 	}
 	ptr := val.ptr
 	setWriterRef(w, ptr)
@@ -12,7 +12,7 @@
  *                                                        *
  * hprose writer test for Go.                             *
  *                                                        *
- * LastModified: Sep 16, 2016                             *
+ * LastModified: Oct 18, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -936,6 +936,62 @@ func TestWriteTime(t *testing.T) {
 	}
 }
 
+func TestWriteTimeWithOptions(t *testing.T) {
+	w := NewWriter(true)
+	w.UTC = true
+	w.TimePrecision = time.Millisecond
+	loc := time.FixedZone("UTC+8", 8*3600)
+	testdata := map[time.Time]string{
+		time.Date(1980, 12, 1, 8, 0, 0, 0, loc):                "D19801201Z",
+		time.Date(1980, 12, 1, 20, 34, 56, 789456123, loc):     "D19801201T123456.789Z",
+		time.Date(1970, 1, 1, 12, 34, 56, 789456123, time.UTC): "T123456.789Z",
+	}
+	for k, v := range testdata {
+		w.WriteTime(&k)
+		if w.String() != v {
+			t.Error(w.String())
+		}
+		w.Clear()
+	}
+	w.TimePrecision = time.Microsecond
+	tm := time.Date(1980, 12, 1, 12, 34, 56, 789456123, time.UTC)
+	w.WriteTime(&tm)
+	if w.String() != "D19801201T123456.789456Z" {
+		t.Error(w.String())
+	}
+}
+
+type civilDate struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+func TestSerializeDateOnly(t *testing.T) {
+	Register(reflect.TypeOf(civilDate{}), "CivilDate", "")
+	d := civilDate{2016, time.October, 18}
+	w := NewWriter(false)
+	w.DateOnly = true
+	w.Serialize(d)
+	w.Serialize(&d)
+	w.Serialize(&d)
+	if w.String() != "D20161018;D20161018;r1;" {
+		t.Error(w.String())
+	}
+	w = NewWriter(true)
+	w.DateOnly = true
+	w.UTC = true
+	w.Serialize(d)
+	if w.String() != "D20161018;" {
+		t.Error(w.String())
+	}
+	w = NewWriter(true)
+	w.Serialize(d)
+	if w.String() != `c9"CivilDate"3{s4"year"s5"month"s3"day"}o0{i2016;i10;i18;}` {
+		t.Error(w.String())
+	}
+}
+
 func TestSerializeTime(t *testing.T) {
 	w := NewWriter(true)
 	testdata := map[time.Time]string{
@@ -1186,6 +1242,45 @@ func BenchmarkSerializeStruct(b *testing.B) {
 	}
 }
 
+func TestSerializeStructView(t *testing.T) {
+	type ViewUser struct {
+		Name     string
+		Email    string `hprose:"email,view=admin"`
+		Password string `hprose:"password,view=root"`
+		Phone    string `hprose:"phone,view=admin,view=root"`
+	}
+	Register(reflect.TypeOf((*ViewUser)(nil)), "ViewUser", "hprose")
+	user := ViewUser{"Tom", "tom@hprose.com", "123", "456"}
+	testdata := map[string]string{
+		"":      `c8"ViewUser"1{s4"name"}o0{s3"Tom"}`,
+		"admin": `c8"ViewUser"3{s4"name"s5"email"s5"phone"}o0{s3"Tom"s14"tom@hprose.com"s3"456"}`,
+		"root":  `c8"ViewUser"3{s4"name"s8"password"s5"phone"}o0{s3"Tom"s3"123"s3"456"}`,
+	}
+	for view, data := range testdata {
+		w := NewWriter(true)
+		w.View = view
+		w.Serialize(user)
+		if w.String() != data {
+			t.Error(w.String())
+		}
+		var u ViewUser
+		Unmarshal(w.Bytes(), &u)
+		if u.Name != "Tom" || (view == "admin" && u.Email != user.Email) ||
+			(view == "" && (u.Email != "" || u.Phone != "")) {
+			t.Error(u)
+		}
+	}
+	w := NewWriter(true)
+	w.Serialize(user)
+	w.View = "admin"
+	w.Serialize(user)
+	s := `c8"ViewUser"1{s4"name"}o0{s3"Tom"}` +
+		`c8"ViewUser"3{s4"name"s5"email"s5"phone"}o1{s3"Tom"s14"tom@hprose.com"s3"456"}`
+	if w.String() != s {
+		t.Error(w.String())
+	}
+}
+
 func TestSerializeStructPtr(t *testing.T) {
 	type Quotient struct {
 		Quo, Rem int
@@ -1200,6 +1295,19 @@ func TestSerializeStructPtr(t *testing.T) {
 	}
 }
 
+func TestSerializeStructZField(t *testing.T) {
+	type Address struct {
+		Zip  int
+		City string
+	}
+	w := NewWriter(true)
+	w.Serialize(Address{100000, "Beijing"})
+	s := `c7"Address"2{s3"zip"s4"city"}o0{i100000;s7"Beijing"}`
+	if w.String() != s {
+		t.Error(w.String())
+	}
+}
+
 func TestSerializeBigIntPtr(t *testing.T) {
 	w := NewWriter(true)
 	bi := big.NewInt(123)
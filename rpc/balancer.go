@@ -0,0 +1,340 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/balancer.go                                        *
+ *                                                        *
+ * hprose client load balancer for Go.                    *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	hio "github.com/hprose/hprose-golang/io"
+)
+
+// Balancer selects a service address from the uri list of the client for
+// every request.
+//
+// SetURIList is called when the balancer is set to the client and when the
+// uri list of the client is changed. Select is called before every request
+// is sent (including the retried requests), and Done is called with the
+// selected uri and the send error when the request is finished. When the
+// selected uri is skipped (for example, its circuit is open) and Select keeps
+// returning the skipped ones, the client walks the rest of the uri list in
+// order, so Done may also be called with a uri not returned by Select.
+type Balancer interface {
+	SetURIList(uriList []string)
+	Select(name string, args []reflect.Value) string
+	Done(uri string, err error)
+}
+
+type roundRobinBalancer struct {
+	locker  sync.RWMutex
+	uriList []string
+	index   uint32
+}
+
+// NewRoundRobinBalancer returns a Balancer which selects the service
+// addresses in turn.
+func NewRoundRobinBalancer() Balancer {
+	return new(roundRobinBalancer)
+}
+
+func (b *roundRobinBalancer) SetURIList(uriList []string) {
+	b.locker.Lock()
+	b.uriList = uriList
+	b.locker.Unlock()
+}
+
+func (b *roundRobinBalancer) Select(name string, args []reflect.Value) string {
+	b.locker.RLock()
+	defer b.locker.RUnlock()
+	n := uint32(len(b.uriList))
+	if n == 0 {
+		return ""
+	}
+	return b.uriList[(atomic.AddUint32(&b.index, 1)-1)%n]
+}
+
+func (b *roundRobinBalancer) Done(uri string, err error) {}
+
+type weightedBalancer struct {
+	locker  sync.Mutex
+	uriList []string
+	weights []int
+	current []int
+	total   int
+}
+
+// NewWeightedBalancer returns a Balancer which selects the service addresses
+// by the smooth weighted round-robin algorithm.
+//
+// The weight is read from the weight query parameter of the uri, for example:
+//
+//		tcp://127.0.0.1:4321/?weight=3
+//
+// The default weight is 1, and the uri with weight 0 is never selected unless
+// all the weights are 0. The weight parameter is removed from the uri before
+// the request is sent.
+func NewWeightedBalancer() Balancer {
+	return new(weightedBalancer)
+}
+
+// removeWeight removes the weight query parameter from the uri
+func removeWeight(uri string) string {
+	if !strings.Contains(uri, "weight=") {
+		return uri
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+	query := u.Query()
+	if _, ok := query["weight"]; !ok {
+		return uri
+	}
+	query.Del("weight")
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+func getWeight(uri string) int {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return 1
+	}
+	value := u.Query().Get("weight")
+	if value == "" {
+		return 1
+	}
+	weight, err := strconv.Atoi(value)
+	if err != nil || weight < 0 {
+		return 1
+	}
+	return weight
+}
+
+func (b *weightedBalancer) SetURIList(uriList []string) {
+	n := len(uriList)
+	weights := make([]int, n)
+	total := 0
+	for i, uri := range uriList {
+		weights[i] = getWeight(uri)
+		total += weights[i]
+	}
+	if total == 0 {
+		for i := range weights {
+			weights[i] = 1
+		}
+		total = n
+	}
+	b.locker.Lock()
+	b.uriList = uriList
+	b.weights = weights
+	b.current = make([]int, n)
+	b.total = total
+	b.locker.Unlock()
+}
+
+func (b *weightedBalancer) Select(name string, args []reflect.Value) string {
+	b.locker.Lock()
+	defer b.locker.Unlock()
+	if len(b.uriList) == 0 {
+		return ""
+	}
+	best := 0
+	for i, weight := range b.weights {
+		b.current[i] += weight
+		if b.current[i] > b.current[best] {
+			best = i
+		}
+	}
+	b.current[best] -= b.total
+	return b.uriList[best]
+}
+
+func (b *weightedBalancer) Done(uri string, err error) {}
+
+type activeCounter struct {
+	locker  sync.Mutex
+	uriList []string
+	active  map[string]int
+}
+
+func (c *activeCounter) SetURIList(uriList []string) {
+	c.locker.Lock()
+	c.uriList = uriList
+	active := make(map[string]int, len(uriList))
+	for _, uri := range uriList {
+		if n := c.active[uri]; n > 0 {
+			active[uri] = n
+		}
+	}
+	c.active = active
+	c.locker.Unlock()
+}
+
+func (c *activeCounter) Done(uri string, err error) {
+	c.locker.Lock()
+	if c.active[uri] > 0 {
+		c.active[uri]--
+	}
+	c.locker.Unlock()
+}
+
+type leastActiveBalancer struct {
+	activeCounter
+	index int
+}
+
+// NewLeastActiveBalancer returns a Balancer which selects the service address
+// with the least outstanding requests of this client, the ties are selected
+// in turn.
+func NewLeastActiveBalancer() Balancer {
+	return new(leastActiveBalancer)
+}
+
+func (b *leastActiveBalancer) Select(name string, args []reflect.Value) string {
+	b.locker.Lock()
+	defer b.locker.Unlock()
+	n := len(b.uriList)
+	if n == 0 {
+		return ""
+	}
+	b.index = (b.index + 1) % n
+	best := b.uriList[b.index]
+	for i := 1; i < n; i++ {
+		uri := b.uriList[(b.index+i)%n]
+		if b.active[uri] < b.active[best] {
+			best = uri
+		}
+	}
+	b.active[best]++
+	return best
+}
+
+type p2cBalancer struct {
+	activeCounter
+}
+
+// NewP2CBalancer returns a Balancer which selects two service addresses at
+// random and uses the one with less outstanding requests of this client
+// (the power of two choices).
+func NewP2CBalancer() Balancer {
+	return new(p2cBalancer)
+}
+
+func (b *p2cBalancer) Select(name string, args []reflect.Value) string {
+	b.locker.Lock()
+	defer b.locker.Unlock()
+	n := len(b.uriList)
+	if n == 0 {
+		return ""
+	}
+	first := rand.Intn(n)
+	best := b.uriList[first]
+	if n > 1 {
+		i := rand.Intn(n - 1)
+		if i >= first {
+			i++
+		}
+		if uri := b.uriList[i]; b.active[uri] < b.active[best] {
+			best = uri
+		}
+	}
+	b.active[best]++
+	return best
+}
+
+const virtualNodes = 160
+
+type consistentHashBalancer struct {
+	locker sync.RWMutex
+	index  int
+	ring   []uint32
+	nodes  map[uint32]string
+}
+
+// NewConsistentHashBalancer returns a Balancer which selects the service
+// address by the consistent hash of the argument at index, so the requests
+// with the same argument are sent to the same service address as long as it
+// is in the uri list. If the remote method has no argument at index, the
+// method name is hashed instead.
+func NewConsistentHashBalancer(index int) Balancer {
+	return &consistentHashBalancer{index: index}
+}
+
+func hash(data []byte) uint32 {
+	h := fnv.New32a()
+	h.Write(data)
+	return h.Sum32()
+}
+
+func (b *consistentHashBalancer) SetURIList(uriList []string) {
+	ring := make([]uint32, 0, len(uriList)*virtualNodes)
+	nodes := make(map[uint32]string, len(uriList)*virtualNodes)
+	for _, uri := range uriList {
+		for i := 0; i < virtualNodes; i++ {
+			h := hash([]byte(uri + "#" + strconv.Itoa(i)))
+			if _, ok := nodes[h]; !ok {
+				nodes[h] = uri
+				ring = append(ring, h)
+			}
+		}
+	}
+	sort.Sort(uint32Slice(ring))
+	b.locker.Lock()
+	b.ring = ring
+	b.nodes = nodes
+	b.locker.Unlock()
+}
+
+func (b *consistentHashBalancer) Select(name string, args []reflect.Value) string {
+	var key []byte
+	if b.index >= 0 && b.index < len(args) && args[b.index].IsValid() {
+		key = hio.Serialize(args[b.index].Interface(), true)
+	} else {
+		key = []byte(name)
+	}
+	h := hash(key)
+	b.locker.RLock()
+	defer b.locker.RUnlock()
+	n := len(b.ring)
+	if n == 0 {
+		return ""
+	}
+	i := sort.Search(n, func(i int) bool { return b.ring[i] >= h })
+	if i == n {
+		i = 0
+	}
+	return b.nodes[b.ring[i]]
+}
+
+func (b *consistentHashBalancer) Done(uri string, err error) {}
+
+type uint32Slice []uint32
+
+func (s uint32Slice) Len() int           { return len(s) }
+func (s uint32Slice) Less(i, j int) bool { return s[i] < s[j] }
+func (s uint32Slice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
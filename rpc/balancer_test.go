@@ -0,0 +1,235 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/balancer_test.go                                   *
+ *                                                        *
+ * hprose client load balancer test for Go.               *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+var testURIList = []string{
+	"tcp://127.0.0.1:10001",
+	"tcp://127.0.0.1:10002",
+	"tcp://127.0.0.1:10003",
+}
+
+func selectURIs(b Balancer, n int, args ...interface{}) map[string]int {
+	values := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		values[i] = reflect.ValueOf(arg)
+	}
+	counts := make(map[string]int)
+	for i := 0; i < n; i++ {
+		counts[b.Select("test", values)]++
+	}
+	return counts
+}
+
+func TestRoundRobinBalancer(t *testing.T) {
+	b := NewRoundRobinBalancer()
+	if uri := b.Select("test", nil); uri != "" {
+		t.Error(uri)
+	}
+	b.SetURIList(testURIList)
+	for i := 0; i < 6; i++ {
+		if uri := b.Select("test", nil); uri != testURIList[i%3] {
+			t.Error(i, uri)
+		}
+	}
+}
+
+func TestWeightedBalancer(t *testing.T) {
+	b := NewWeightedBalancer()
+	b.SetURIList([]string{
+		"tcp://127.0.0.1:10001/?weight=3",
+		"tcp://127.0.0.1:10002/",
+		"tcp://127.0.0.1:10003/?weight=0",
+	})
+	counts := selectURIs(b, 8)
+	if counts["tcp://127.0.0.1:10001/?weight=3"] != 6 ||
+		counts["tcp://127.0.0.1:10002/"] != 2 ||
+		counts["tcp://127.0.0.1:10003/?weight=0"] != 0 {
+		t.Error(counts)
+	}
+	b.SetURIList([]string{"tcp://a:1/?weight=0", "tcp://b:1/?weight=0"})
+	counts = selectURIs(b, 4)
+	if counts["tcp://a:1/?weight=0"] != 2 || counts["tcp://b:1/?weight=0"] != 2 {
+		t.Error(counts)
+	}
+}
+
+func TestGetWeight(t *testing.T) {
+	tests := map[string]int{
+		"tcp://127.0.0.1:10001":           1,
+		"tcp://127.0.0.1:10001?weight=5":  5,
+		"tcp://127.0.0.1:10001?weight=0":  0,
+		"tcp://127.0.0.1:10001?weight=-1": 1,
+		"tcp://127.0.0.1:10001?weight=x":  1,
+	}
+	for uri, weight := range tests {
+		if w := getWeight(uri); w != weight {
+			t.Error(uri, w)
+		}
+	}
+}
+
+func TestRemoveWeight(t *testing.T) {
+	tests := map[string]string{
+		"tcp://127.0.0.1:10001":                "tcp://127.0.0.1:10001",
+		"tcp://127.0.0.1:10001?weight=3":       "tcp://127.0.0.1:10001",
+		"http://127.0.0.1/rpc?a=1&weight=3":    "http://127.0.0.1/rpc?a=1",
+		"http://127.0.0.1/rpc?noweight=3":      "http://127.0.0.1/rpc?noweight=3",
+		"unix:/tmp/hprose.sock?weight=2":       "unix:/tmp/hprose.sock",
+		"ws://127.0.0.1:8080/?weight=1&b=2&c=": "ws://127.0.0.1:8080/?b=2&c=",
+	}
+	for uri, want := range tests {
+		if got := removeWeight(uri); got != want {
+			t.Error(uri, got)
+		}
+	}
+	context := &ClientContext{uri: "http://127.0.0.1/rpc?weight=3"}
+	if uri := context.URI(); uri != "http://127.0.0.1/rpc" {
+		t.Error(uri)
+	}
+}
+
+func TestLeastActiveBalancer(t *testing.T) {
+	b := NewLeastActiveBalancer()
+	b.SetURIList(testURIList)
+	counts := selectURIs(b, 3)
+	if len(counts) != 3 {
+		t.Error(counts)
+	}
+	b.Done(testURIList[1], nil)
+	if uri := b.Select("test", nil); uri != testURIList[1] {
+		t.Error(uri)
+	}
+	for _, uri := range testURIList {
+		b.Done(uri, nil)
+		b.Done(uri, nil)
+	}
+	counts = selectURIs(b, 9)
+	for _, uri := range testURIList {
+		if counts[uri] != 3 {
+			t.Error(counts)
+		}
+	}
+}
+
+func TestP2CBalancer(t *testing.T) {
+	b := NewP2CBalancer()
+	b.SetURIList(testURIList[:2])
+	// the one with less outstanding requests is always selected of two
+	counts := selectURIs(b, 10)
+	if counts[testURIList[0]] != 5 || counts[testURIList[1]] != 5 {
+		t.Error(counts)
+	}
+	b.SetURIList(testURIList)
+	counts = selectURIs(b, 300)
+	for _, uri := range testURIList {
+		if counts[uri] == 0 {
+			t.Error(counts)
+		}
+	}
+}
+
+func TestActiveCounterPrune(t *testing.T) {
+	b := new(leastActiveBalancer)
+	b.SetURIList(testURIList)
+	selectURIs(b, 6)
+	b.SetURIList(testURIList[1:])
+	if len(b.active) != 2 || b.active[testURIList[0]] != 0 {
+		t.Error(b.active)
+	}
+	if b.active[testURIList[1]] != 2 || b.active[testURIList[2]] != 2 {
+		t.Error(b.active)
+	}
+}
+
+func TestConsistentHashBalancer(t *testing.T) {
+	b := NewConsistentHashBalancer(0)
+	if uri := b.Select("test", nil); uri != "" {
+		t.Error(uri)
+	}
+	b.SetURIList(testURIList)
+	selected := make(map[string]string)
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprint("key", i)
+		counts := selectURIs(b, 3, key)
+		if len(counts) != 1 {
+			t.Fatal(key, counts)
+		}
+		for uri := range counts {
+			selected[key] = uri
+		}
+	}
+	counts := make(map[string]int)
+	for _, uri := range selected {
+		counts[uri]++
+	}
+	if len(counts) != 3 {
+		t.Error(counts)
+	}
+	// the keys of the remaining addresses are not moved
+	b.SetURIList(testURIList[:2])
+	for key, uri := range selected {
+		if uri == testURIList[2] {
+			continue
+		}
+		if got := b.Select("test", []reflect.Value{reflect.ValueOf(key)}); got != uri {
+			t.Error(key, uri, got)
+		}
+	}
+	// the method name is hashed without the argument
+	if counts := selectURIs(b, 5); len(counts) != 1 {
+		t.Error(counts)
+	}
+}
+
+// TestSelectSkipsOpenCircuit checks that the client selects another service
+// address when the consistent hash balancer keeps selecting the open one.
+func TestSelectSkipsOpenCircuit(t *testing.T) {
+	client := NewTCPClient(testURIList...)
+	defer client.Close()
+	client.SetBalancer(NewConsistentHashBalancer(0))
+	breaker := NewCircuitBreaker()
+	breaker.ConsecutiveFailures = 1
+	client.SetCircuitBreaker(breaker)
+	context := new(ClientContext)
+	context.initBaseContext()
+	context.name = "test"
+	context.args = []reflect.Value{reflect.ValueOf("key")}
+	uri, err := client.selectURI(context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	breaker.record(uri, ErrTimeout)
+	other, err := client.selectURI(context)
+	if err != nil || other == uri {
+		t.Fatal(uri, other, err)
+	}
+	for _, uri := range testURIList {
+		breaker.record(uri, ErrTimeout)
+	}
+	if uri, err = client.selectURI(context); err != ErrCircuitOpen {
+		t.Error(uri, err)
+	}
+}
@@ -12,7 +12,7 @@
  *                                                        *
  * hprose rpc base client for Go.                         *
  *                                                        *
- * LastModified: Oct 19, 2016                             *
+ * LastModified: Oct 18, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -20,10 +20,10 @@
 package rpc
 
 import (
+	gocontext "context"
 	"crypto/tls"
 	"errors"
 	"fmt"
-	"io"
 	"math/rand"
 	"reflect"
 	"strconv"
@@ -33,15 +33,17 @@ import (
 	"time"
 
 	hio "github.com/hprose/hprose-golang/io"
-	"github.com/hprose/hprose-golang/util"
 )
 
+type topicCallback func(topic TopicName, results []reflect.Value)
+
 type clientTopic struct {
-	callbacks []Callback
+	callbacks []topicCallback
 	locker    sync.RWMutex
+	cancel    gocontext.CancelFunc
 }
 
-func (ct *clientTopic) addCallback(callback Callback) {
+func (ct *clientTopic) addCallback(callback topicCallback) {
 	ct.locker.Lock()
 	ct.callbacks = append(ct.callbacks, callback)
 	ct.locker.Unlock()
@@ -68,7 +70,19 @@ func (tm *topicManager) createTopic(topic string) {
 	if tm.allTopics[topic] == nil {
 		tm.allTopics[topic] = make(map[string]*clientTopic)
 	}
+	tm.locker.Unlock()
+}
+
+// removeTopic removes the topic of id if it is still topic.
+func (tm *topicManager) removeTopic(name string, id string, topic *clientTopic) {
 	tm.locker.Lock()
+	if topics := tm.allTopics[name]; topics != nil && topics[id] == topic {
+		delete(topics, id)
+		if len(topics) == 0 {
+			delete(tm.allTopics, name)
+		}
+	}
+	tm.locker.Unlock()
 }
 
 // IsSubscribed the topic
@@ -93,15 +107,28 @@ type baseClient struct {
 	handlerManager
 	filterManager
 	topicManager
+	endpointManager
+	latencyManager
 	uri            string
 	uriList        []string
 	index          int32
+	uriLocker      sync.RWMutex
+	setURIList     func(uriList []string)
+	resolver       Resolver
+	resolverLocker sync.Mutex
 	failround      int
 	retry          int
+	retryPolicy    RetryPolicy
 	timeout        time.Duration
 	event          ClientEvent
 	contextPool    sync.Pool
-	readerPool     sync.Pool
+	codec          Codec
+	balancer       Balancer
+	breaker        *CircuitBreaker
+	rateLimiter    *RateLimiter
+	functions      []string
+	funcLocker     sync.Mutex
+	pushBackoff    RetryPolicy
 	SendAndReceive func([]byte, *ClientContext) ([]byte, error)
 	id             string
 }
@@ -110,12 +137,13 @@ func (client *baseClient) initBaseClient() {
 	client.initHandlerManager()
 	client.timeout = 30 * time.Second
 	client.retry = 10
+	client.retryPolicy = LegacyRetryPolicy{}
+	client.pushBackoff = defaultSubscribeBackoff
 	client.contextPool = sync.Pool{
 		New: func() interface{} { return new(ClientContext) },
 	}
-	client.readerPool = sync.Pool{
-		New: func() interface{} { return new(hio.Reader) },
-	}
+	client.codec = defaultCodec
+	client.setURIList = client.SetURIList
 	client.override.invokeHandler = func(
 		name string, args []reflect.Value,
 		context Context) (results []reflect.Value, err error) {
@@ -134,6 +162,8 @@ func (client *baseClient) initBaseClient() {
 
 // URI returns the current hprose service address.
 func (client *baseClient) URI() string {
+	client.uriLocker.RLock()
+	defer client.uriLocker.RUnlock()
 	return client.uri
 }
 
@@ -147,6 +177,8 @@ func (client *baseClient) SetURI(uri string) {
 
 // URIList returns all of the hprose service addresses
 func (client *baseClient) URIList() []string {
+	client.uriLocker.RLock()
+	defer client.uriLocker.RUnlock()
 	return client.uriList
 }
 
@@ -161,11 +193,27 @@ func shuffleStringSlice(src []string) []string {
 }
 
 // SetURIList set a list of server addresses
+//
+// If the current service address is still in the list, it is kept as the
+// current service address.
 func (client *baseClient) SetURIList(uriList []string) {
+	client.uriLocker.Lock()
 	client.uriList = shuffleStringSlice(uriList)
-	client.index = 0
+	index := 0
+	for i, uri := range client.uriList {
+		if uri == client.uri {
+			index = i
+			break
+		}
+	}
+	atomic.StoreInt32(&client.index, int32(index))
 	client.failround = 0
-	client.uri = client.uriList[0]
+	client.uri = client.uriList[index]
+	uriList = client.uriList
+	client.uriLocker.Unlock()
+	if client.balancer != nil {
+		client.balancer.SetURIList(uriList)
+	}
 }
 
 // TLSClientConfig returns the tls config of hprose client
@@ -186,6 +234,20 @@ func (client *baseClient) SetRetry(value int) {
 	client.retry = value
 }
 
+// RetryPolicy returns the default retry policy of the client
+func (client *baseClient) RetryPolicy() RetryPolicy {
+	return client.retryPolicy
+}
+
+// SetRetryPolicy set the default retry policy of the client, it is used when
+// the RetryPolicy of InvokeSettings is nil.
+func (client *baseClient) SetRetryPolicy(policy RetryPolicy) {
+	if policy == nil {
+		policy = LegacyRetryPolicy{}
+	}
+	client.retryPolicy = policy
+}
+
 // Timeout returns the client timeout setting
 func (client *baseClient) Timeout() time.Duration {
 	return client.timeout
@@ -196,6 +258,57 @@ func (client *baseClient) SetTimeout(value time.Duration) {
 	client.timeout = value
 }
 
+// Codec returns the serialization codec of the client
+func (client *baseClient) Codec() Codec {
+	return client.codec
+}
+
+// SetCodec set the serialization codec of the client
+func (client *baseClient) SetCodec(codec Codec) {
+	if codec == nil {
+		codec = defaultCodec
+	}
+	client.codec = codec
+}
+
+// Balancer returns the load balancer of the client
+func (client *baseClient) Balancer() Balancer {
+	return client.balancer
+}
+
+// SetBalancer set the load balancer of the client
+//
+// When the balancer is nil (the default), all requests are sent to the
+// current service address until it fails and switches to the next one.
+func (client *baseClient) SetBalancer(balancer Balancer) {
+	if balancer != nil {
+		balancer.SetURIList(client.URIList())
+	}
+	client.balancer = balancer
+}
+
+// CircuitBreaker returns the circuit breaker of the client
+func (client *baseClient) CircuitBreaker() *CircuitBreaker {
+	return client.breaker
+}
+
+// SetCircuitBreaker set the circuit breaker of the client, nil means no
+// circuit breaker (the default).
+func (client *baseClient) SetCircuitBreaker(breaker *CircuitBreaker) {
+	client.breaker = breaker
+}
+
+// RateLimiter returns the rate limiter of the client
+func (client *baseClient) RateLimiter() *RateLimiter {
+	return client.rateLimiter
+}
+
+// SetRateLimiter set the rate limiter of the client, nil means no rate
+// limiter (the default).
+func (client *baseClient) SetRateLimiter(limiter *RateLimiter) {
+	client.rateLimiter = limiter
+}
+
 // Failround return the fail round
 func (client *baseClient) Failround() int {
 	return client.failround
@@ -268,7 +381,7 @@ func (client *baseClient) UseService(remoteService interface{}, namespace ...str
 	if v.Kind() != reflect.Ptr {
 		panic("UseService: remoteService argument must be a pointer")
 	}
-	buildRemoteService(client, v, ns)
+	buildRemoteService(client, nil, v, ns)
 }
 
 func (client *baseClient) acquireContext() (context *ClientContext) {
@@ -276,6 +389,11 @@ func (client *baseClient) acquireContext() (context *ClientContext) {
 }
 
 func (client *baseClient) releaseContext(context *ClientContext) {
+	context.ctx = nil
+	context.args = nil
+	context.hedging = nil
+	context.flight = nil
+	context.cache = nil
 	client.contextPool.Put(context)
 }
 
@@ -302,20 +420,42 @@ func (client *baseClient) initClientContext(
 
 // Invoke the remote method synchronous
 func (client *baseClient) Invoke(name string, args []reflect.Value, settings *InvokeSettings) (results []reflect.Value, err error) {
+	return client.InvokeContext(gocontext.Background(), name, args, settings)
+}
+
+// InvokeContext invoke the remote method synchronous with ctx
+//
+// When ctx is canceled or its deadline is exceeded, the in-flight request is
+// aborted and ctx.Err() is returned. The Timeout of settings still works.
+func (client *baseClient) InvokeContext(ctx gocontext.Context, name string, args []reflect.Value, settings *InvokeSettings) (results []reflect.Value, err error) {
+	if ctx == nil {
+		ctx = gocontext.Background()
+	}
 	context := client.acquireContext()
 	client.initClientContext(context, settings)
-	results, err = client.handlerManager.invokeHandler(name, args, context)
-	if results == nil && len(context.ResultTypes) > 0 {
-		n := len(context.ResultTypes)
-		results = make([]reflect.Value, n)
-		for i := 0; i < n; i++ {
-			results[i] = reflect.New(context.ResultTypes[i]).Elem()
-		}
+	context.ctx = ctx
+	if err = ctx.Err(); err == nil {
+		results, err = client.handlerManager.invokeHandler(name, args, context)
+	}
+	if results == nil {
+		results = zeroResults(context.ResultTypes)
 	}
 	client.releaseContext(context)
 	return
 }
 
+func zeroResults(resultTypes []reflect.Type) []reflect.Value {
+	n := len(resultTypes)
+	if n == 0 {
+		return nil
+	}
+	results := make([]reflect.Value, n)
+	for i := 0; i < n; i++ {
+		results[i] = reflect.New(resultTypes[i]).Elem()
+	}
+	return results
+}
+
 // Go invoke the remote method asynchronous
 func (client *baseClient) Go(name string, args []reflect.Value, callback Callback, settings *InvokeSettings) {
 	go func() {
@@ -325,7 +465,18 @@ func (client *baseClient) Go(name string, args []reflect.Value, callback Callbac
 }
 
 // Close the client
-func (client *baseClient) Close() {}
+func (client *baseClient) Close() {
+	client.SetResolver(nil)
+	client.SetHealthChecker(nil)
+	client.topicManager.locker.Lock()
+	for name, topics := range client.allTopics {
+		for _, topic := range topics {
+			topic.cancel()
+		}
+		delete(client.allTopics, name)
+	}
+	client.topicManager.locker.Unlock()
+}
 
 func (client *baseClient) fireErrorEvent(name string, err error) {
 	if e := recover(); e != nil {
@@ -343,7 +494,14 @@ func (client *baseClient) beforeFilter(
 	context *ClientContext) (response []byte, err error) {
 	request = client.outputFilter(request, context)
 	if context.Oneway {
-		go client.handlerManager.afterFilterHandler(request, context)
+		// context is released and ctx is usually canceled when the invoking
+		// returns, so the request is sent with a detached copy of it.
+		oneway := client.cloneContext(
+			context, detachedContext{context.Context()}, nil)
+		go func() {
+			client.handlerManager.afterFilterHandler(request, oneway)
+			client.releaseContext(oneway)
+		}()
 		return nil, nil
 	}
 	response, err = client.handlerManager.afterFilterHandler(request, context)
@@ -359,8 +517,35 @@ func (client *baseClient) afterFilter(
 func (client *baseClient) sendRequest(
 	request []byte,
 	context *ClientContext) (response []byte, err error) {
-	response, err = client.handlerManager.beforeFilterHandler(request, context)
-	if err != nil {
+	context.uri, err = client.selectURI(context)
+	if err == nil {
+		release := func() {}
+		if limiter := client.rateLimiter; limiter != nil {
+			release, err = limiter.acquire(context)
+		}
+		if err == nil {
+			start := time.Now()
+			response, err = client.handlerManager.beforeFilterHandler(
+				request, context)
+			release()
+			if err == nil {
+				client.endpointManager.recordLatency(
+					context.uri, time.Since(start))
+			} else if context.Context().Err() == nil {
+				client.endpointManager.recordError(context.uri, err)
+			}
+		}
+		if client.balancer != nil {
+			client.balancer.Done(context.uri, err)
+		}
+		if client.breaker != nil {
+			client.recordCircuit(context.uri, err, context)
+		}
+		if context.hedging != nil {
+			context.hedging.release(context.uri)
+		}
+	}
+	if err != nil && err != ErrRateLimited {
 		response, err = client.retrySendReqeust(request, err, context)
 	}
 	return
@@ -370,193 +555,253 @@ func (client *baseClient) retrySendReqeust(
 	request []byte,
 	err error,
 	context *ClientContext) ([]byte, error) {
+	if e := context.Context().Err(); e != nil {
+		return nil, e
+	}
 	if context.Failswitch {
 		client.failswitch()
 	}
 	if context.Idempotent && context.Retried < context.Retry {
-		context.Retried++
-		interval := context.Retried * 500
-		if context.Failswitch {
-			interval -= (len(client.uriList) - 1) * 500
+		policy := context.RetryPolicy
+		if policy == nil {
+			policy = client.retryPolicy
 		}
-		if interval > 5000 {
-			interval = 5000
+		delay, ok := policy.Backoff(
+			context.Retried+1, time.Since(context.start), err, context)
+		if !ok {
+			return nil, err
 		}
-		if interval > 0 {
-			time.Sleep(time.Duration(interval) * time.Millisecond)
+		context.Retried++
+		if event, ok := client.event.(onRetryEvent); ok {
+			event.OnRetry(context.name, context.Retried, err)
+		}
+		if delay > 0 {
+			timer := time.NewTimer(delay)
+			select {
+			case <-timer.C:
+			case <-context.Context().Done():
+				timer.Stop()
+				return nil, context.Context().Err()
+			}
 		}
 		return client.sendRequest(request, context)
 	}
 	return nil, err
 }
 
-func (client *baseClient) failswitch() {
-	n := int32(len(client.uriList))
-	if n > 1 {
-		if atomic.CompareAndSwapInt32(&client.index, n-1, 0) {
-			client.uri = client.uriList[0]
-			client.failround++
-		} else {
-			client.uri = client.uriList[atomic.AddInt32(&client.index, 1)]
+func (client *baseClient) selectURI(context *ClientContext) (string, error) {
+	if client.HealthChecker() != nil {
+		if uri, err := client.selectHealthyURI(context, true); err == nil {
+			return uri, nil
 		}
-	} else {
-		client.failround++
-	}
-	if event, ok := client.event.(onFailswitchEvent); ok {
-		event.OnFailswitch(client)
 	}
+	return client.selectHealthyURI(context, false)
 }
 
-func (client *baseClient) encode(
-	name string,
-	args []reflect.Value,
-	context *ClientContext) []byte {
-	writer := hio.NewWriter(context.Simple)
-	writer.WriteByte(hio.TagCall)
-	writer.WriteString(name)
-	if len(args) > 0 || context.ByRef {
-		writer.Reset()
-		writer.WriteSlice(args)
-		if context.ByRef {
-			writer.WriteBool(true)
-		}
-	}
-	writer.WriteByte(hio.TagEnd)
-	return writer.Bytes()
-}
-
-func readMultiResults(
-	reader *hio.Reader, resultTypes []reflect.Type) (results []reflect.Value) {
-	length := len(resultTypes)
-	reader.CheckTag(hio.TagList)
-	count := reader.ReadCount()
-	results = make([]reflect.Value, util.Max(length, count))
-	for i := 0; i < length; i++ {
-		results[i] = reflect.New(resultTypes[i]).Elem()
+// selectHealthyURI selects the service address by the balancer and the
+// circuit breaker, the unhealthy service addresses are skipped when
+// healthyOnly is true.
+func (client *baseClient) selectHealthyURI(
+	context *ClientContext, healthyOnly bool) (string, error) {
+	balancer := client.balancer
+	breaker := client.breaker
+	if breaker == nil && !healthyOnly && context.hedging == nil {
+		if balancer == nil {
+			return client.URI(), nil
+		}
+		return balancer.Select(context.name, context.args), nil
 	}
-	if length < count {
-		for i := length; i < count; i++ {
-			results[i] = reflect.New(interfaceType).Elem()
+	uriList := client.URIList()
+	n := len(uriList)
+	index := int(atomic.LoadInt32(&client.index))
+	tried := make(map[string]bool, n)
+	for i := 0; i < n; i++ {
+		var uri string
+		if balancer != nil {
+			uri = balancer.Select(context.name, context.args)
+			if tried[uri] {
+				balancer.Done(uri, nil)
+				uri = ""
+			}
+		}
+		if uri == "" {
+			// the balancer (for example, the consistent hash balancer)
+			// selects the tried uri again, so the rest are walked in order.
+			for j := 0; j < n && uri == ""; j++ {
+				if u := uriList[(index+i+j)%n]; !tried[u] {
+					uri = u
+				}
+			}
+			if uri == "" {
+				break
+			}
 		}
+		tried[uri] = true
+		if context.hedging != nil && context.hedging.used(uri) {
+			if balancer != nil {
+				balancer.Done(uri, nil)
+			}
+			continue
+		}
+		if healthyOnly && !client.endpointManager.isHealthy(uri) {
+			if balancer != nil {
+				balancer.Done(uri, errEndpointUnhealthy)
+			}
+			continue
+		}
+		if breaker != nil {
+			ok, changed := breaker.allow(uri)
+			if changed {
+				client.fireCircuitEvent(uri, CircuitHalfOpen)
+			}
+			if !ok {
+				if balancer != nil {
+					balancer.Done(uri, ErrCircuitOpen)
+				}
+				continue
+			}
+		}
+		if context.hedging != nil {
+			context.hedging.use(uri)
+		}
+		return uri, nil
 	}
-	reader.ReadSlice(results[:count])
-	return
-}
-
-func (client *baseClient) readResults(
-	reader *hio.Reader,
-	context *ClientContext) (results []reflect.Value) {
-	length := len(context.ResultTypes)
-	switch length {
-	case 0:
-		var e interface{}
-		reader.Unserialize(&e)
-	case 1:
-		results = make([]reflect.Value, 1)
-		results[0] = reflect.New(context.ResultTypes[0]).Elem()
-		reader.ReadValue(results[0])
-	default:
-		results = readMultiResults(reader, context.ResultTypes)
+	if breaker != nil {
+		return "", ErrCircuitOpen
 	}
-	return
+	if healthyOnly {
+		return "", errEndpointUnhealthy
+	}
+	return "", errNoIdleEndpoint
 }
 
-func (client *baseClient) readArguments(
-	reader *hio.Reader,
-	args []reflect.Value,
-	context *ClientContext) byte {
-	length := len(args)
-	reader.Reset()
-	reader.CheckTag(hio.TagList)
-	count := reader.ReadCount()
-	a := make([]reflect.Value, util.Max(length, count))
-	for i := 0; i < length; i++ {
-		a[i] = args[i].Elem()
+func (client *baseClient) recordCircuit(
+	uri string, err error, context *ClientContext) {
+	if err == ErrRateLimited ||
+		(err != nil && context.Context().Err() != nil) {
+		client.breaker.release(uri)
+		return
 	}
-	if length < count {
-		for i := length; i < count; i++ {
-			a[i] = reflect.New(interfaceType).Elem()
-		}
+	if state, changed := client.breaker.record(uri, err); changed {
+		client.fireCircuitEvent(uri, state)
 	}
-	reader.ReadSlice(a[:count])
-	tag, _ := reader.ReadByte()
-	return tag
 }
 
-func (client *baseClient) acquireReader(buf []byte) (reader *hio.Reader) {
-	reader = client.readerPool.Get().(*hio.Reader)
-	reader.Init(buf)
-	return
+func (client *baseClient) fireCircuitEvent(uri string, state CircuitState) {
+	switch state {
+	case CircuitOpen:
+		if event, ok := client.event.(onCircuitOpenEvent); ok {
+			event.OnCircuitOpen(uri)
+		}
+	case CircuitHalfOpen:
+		if event, ok := client.event.(onCircuitHalfOpenEvent); ok {
+			event.OnCircuitHalfOpen(uri)
+		}
+	case CircuitClosed:
+		if event, ok := client.event.(onCircuitCloseEvent); ok {
+			event.OnCircuitClose(uri)
+		}
+	}
 }
 
-func (client *baseClient) releaseReader(reader *hio.Reader) {
-	reader.Init(nil)
-	reader.Reset()
-	client.readerPool.Put(reader)
+func (client *baseClient) failswitch() {
+	checked := client.HealthChecker() != nil
+	client.uriLocker.Lock()
+	n := int32(len(client.uriList))
+	if n > 1 {
+		for i := int32(0); i < n; i++ {
+			if atomic.CompareAndSwapInt32(&client.index, n-1, 0) {
+				client.uri = client.uriList[0]
+				client.failround++
+			} else {
+				client.uri = client.uriList[atomic.AddInt32(&client.index, 1)]
+			}
+			if !checked || client.endpointManager.isHealthy(client.uri) {
+				break
+			}
+		}
+	} else {
+		client.failround++
+	}
+	client.uriLocker.Unlock()
+	if event, ok := client.event.(onFailswitchEvent); ok {
+		event.OnFailswitch(client)
+	}
 }
 
-func (client *baseClient) decode(
-	data []byte,
+func (client *baseClient) invoke(
+	name string,
 	args []reflect.Value,
 	context *ClientContext) (results []reflect.Value, err error) {
-	if context.Oneway {
-		return
+	context.name = name
+	context.args = args
+	context.start = time.Now()
+	request, err := client.encode(name, args, context)
+	if err != nil {
+		return nil, err
 	}
-	n := len(data)
-	if n == 0 {
-		return nil, io.ErrUnexpectedEOF
+	cache := context.cache
+	if context.Cache <= 0 || context.Oneway || context.ByRef {
+		cache = nil
 	}
-	if data[n-1] != hio.TagEnd {
-		return nil, fmt.Errorf("Wrong Response: \r\n%s", data)
+	var key string
+	if cache != nil {
+		key = string(request)
+		if response, ok := cache.get(name, key); ok {
+			return client.codec.DecodeResponse(response, args, context)
+		}
 	}
-	if context.Mode == RawWithEndTag {
-		results = make([]reflect.Value, 1)
-		results[0] = reflect.ValueOf(data)
-		return
+	var response []byte
+	if context.flight != nil && context.Idempotent &&
+		!context.Oneway && !context.ByRef {
+		response, err = context.flight.send(client, request, context)
+	} else {
+		response, err = client.send(request, context)
 	}
-	if context.Mode == Raw {
-		results = make([]reflect.Value, 1)
-		results[0] = reflect.ValueOf(data[:n-1])
-		return
+	if err != nil {
+		return nil, err
 	}
-	reader := client.acquireReader(data)
-	defer client.releaseReader(reader)
-	reader.JSONCompatible = context.JSONCompatible
-	tag, _ := reader.ReadByte()
-	if tag == hio.TagResult {
-		switch context.Mode {
-		case Normal:
-			results = client.readResults(reader, context)
-		case Serialized:
-			results = make([]reflect.Value, 1)
-			results[0] = reflect.ValueOf(reader.ReadRaw())
-		}
-		tag, _ = reader.ReadByte()
-		if tag == hio.TagArgument {
-			tag = client.readArguments(reader, args, context)
-		}
-	} else if tag == hio.TagError {
-		return nil, errors.New(reader.ReadString())
+	if context.HedgeQuantile > 0 {
+		client.latencyManager.record(name, time.Since(context.start))
 	}
-	if tag != hio.TagEnd {
-		return nil, fmt.Errorf("Wrong Response: \r\n%s", data)
+	results, err = client.codec.DecodeResponse(response, args, context)
+	if err == nil && cache != nil && client.cacheable(response, context) {
+		cache.set(name, key, response, context.Cache)
 	}
 	return
 }
 
-func (client *baseClient) invoke(
+// cacheable reports whether the response is not an error response. The error
+// response is not detected by DecodeResponse in Raw or RawWithEndTag mode, so
+// it is decoded again in Serialized mode to check it.
+func (client *baseClient) cacheable(
+	response []byte, context *ClientContext) bool {
+	mode := context.Mode
+	if mode != Raw && mode != RawWithEndTag {
+		return true
+	}
+	context.Mode = Serialized
+	defer func() { context.Mode = mode }()
+	_, err := client.codec.DecodeResponse(response, nil, context)
+	return err == nil
+}
+
+func (client *baseClient) encode(
 	name string,
 	args []reflect.Value,
-	context *ClientContext) (results []reflect.Value, err error) {
-	request := client.encode(name, args, context)
-	response, err := client.sendRequest(request, context)
-	if err != nil {
-		return nil, err
+	context *ClientContext) ([]byte, error) {
+	return client.codec.EncodeRequest(name, args, context)
+}
+
+func (client *baseClient) send(
+	request []byte, context *ClientContext) ([]byte, error) {
+	if delay, ok := client.hedgeDelay(context); ok {
+		return client.hedgeSendRequest(request, context, delay)
 	}
-	return client.decode(response, args, context)
+	return client.sendRequest(request, context)
 }
 
-func buildRemoteService(client *baseClient, v reflect.Value, ns string) {
+func buildRemoteService(client *baseClient, batch *Batch, v reflect.Value, ns string) {
 	v = v.Elem()
 	t := v.Type()
 	et := t
@@ -576,9 +821,9 @@ func buildRemoteService(client *baseClient, v reflect.Value, ns string) {
 		if f.CanSet() {
 			switch ft.Kind() {
 			case reflect.Struct:
-				buildRemoteSubService(client, f, ft, sf, ns)
+				buildRemoteSubService(client, batch, f, ft, sf, ns)
 			case reflect.Func:
-				buildRemoteMethod(client, f, ft, sf, ns)
+				buildRemoteMethod(client, batch, f, ft, sf, ns)
 			}
 		}
 	}
@@ -589,7 +834,7 @@ func buildRemoteService(client *baseClient, v reflect.Value, ns string) {
 	}
 }
 
-func buildRemoteSubService(client *baseClient, f reflect.Value, ft reflect.Type,
+func buildRemoteSubService(client *baseClient, batch *Batch, f reflect.Value, ft reflect.Type,
 	sf reflect.StructField, ns string) {
 	namespace := ns
 	if !sf.Anonymous {
@@ -600,7 +845,7 @@ func buildRemoteSubService(client *baseClient, f reflect.Value, ft reflect.Type,
 		}
 	}
 	fp := reflect.New(ft)
-	buildRemoteService(client, fp, namespace)
+	buildRemoteService(client, batch, fp, namespace)
 	if f.Kind() == reflect.Ptr {
 		f.Set(fp)
 	} else {
@@ -652,6 +897,77 @@ func getInt64Value(tag reflect.StructTag, key string) int64 {
 	return result
 }
 
+// getLocationValue returns nil and fires the OnError event of the client if
+// the location is invalid.
+func getLocationValue(
+	client *baseClient, name string, tag reflect.StructTag, key string) *time.Location {
+	value := tag.Get(key)
+	if value == "" {
+		return nil
+	}
+	loc, err := time.LoadLocation(value)
+	if err != nil {
+		client.fireErrorEvent(name, err)
+		return nil
+	}
+	return loc
+}
+
+// getPrecisionValue parses the value as a duration like "1ms", or as an
+// integer in nanoseconds.
+func getPrecisionValue(tag reflect.StructTag, key string) time.Duration {
+	value := tag.Get(key)
+	if value == "" {
+		return 0
+	}
+	if precision, err := time.ParseDuration(value); err == nil {
+		return precision
+	}
+	return time.Duration(getInt64Value(tag, key))
+}
+
+func getRetryPolicyValue(tag reflect.StructTag, key string) RetryPolicy {
+	value := tag.Get(key)
+	if value == "" {
+		return nil
+	}
+	return getRetryPolicy(value)
+}
+
+func getDurationValue(tag reflect.StructTag, key string) time.Duration {
+	value := tag.Get(key)
+	if value == "" {
+		return 0
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		panic(err)
+	}
+	return duration
+}
+
+func getHedgeValue(
+	tag reflect.StructTag, key string) (delay time.Duration, quantile float64) {
+	value := tag.Get(key)
+	if value == "" {
+		return
+	}
+	for _, v := range strings.Split(value, ",") {
+		v = strings.TrimSpace(v)
+		var err error
+		if strings.HasPrefix(v, "p") || strings.HasPrefix(v, "P") {
+			quantile, err = strconv.ParseFloat(v[1:], 64)
+			quantile /= 100
+		} else {
+			delay, err = time.ParseDuration(v)
+		}
+		if err != nil {
+			panic(err)
+		}
+	}
+	return
+}
+
 func getResultTypes(ft reflect.Type) ([]reflect.Type, bool) {
 	n := ft.NumOut()
 	if n == 0 {
@@ -703,17 +1019,38 @@ func getIn(in []reflect.Value) []reflect.Value {
 	return args
 }
 
+func getContext(in []reflect.Value) gocontext.Context {
+	if ctx, ok := in[0].Interface().(gocontext.Context); ok {
+		return ctx
+	}
+	return gocontext.Background()
+}
+
 func getSyncRemoteMethod(
 	client *baseClient,
+	batch *Batch,
 	name string,
 	settings *InvokeSettings,
-	isVariadic, hasError bool) func(in []reflect.Value) (out []reflect.Value) {
+	isVariadic, hasError, hasContext bool) func(in []reflect.Value) (out []reflect.Value) {
 	return func(in []reflect.Value) (out []reflect.Value) {
+		ctx := gocontext.Background()
+		if hasContext {
+			ctx = getContext(in)
+			in = in[1:]
+		}
 		if isVariadic {
 			in = getIn(in)
 		}
+		if batch != nil {
+			batch.Invoke(name, in, settings)
+			out = zeroResults(settings.ResultTypes)
+			if hasError {
+				out = append(out, reflect.Zero(errorType))
+			}
+			return
+		}
 		var err error
-		out, err = client.Invoke(name, in, settings)
+		out, err = client.InvokeContext(ctx, name, in, settings)
 		if hasError {
 			out = append(out, reflect.ValueOf(&err).Elem())
 		} else if err != nil {
@@ -729,34 +1066,81 @@ func getSyncRemoteMethod(
 
 func getAsyncRemoteMethod(
 	client *baseClient,
+	batch *Batch,
 	name string,
 	settings *InvokeSettings,
-	isVariadic, hasError bool) func(in []reflect.Value) (out []reflect.Value) {
+	isVariadic, hasError, hasContext bool) func(in []reflect.Value) (out []reflect.Value) {
 	return func(in []reflect.Value) (out []reflect.Value) {
-		go func() {
-			if isVariadic {
-				in = getIn(in)
-			}
-			callback := in[0]
+		ctx := gocontext.Background()
+		if hasContext {
+			ctx = getContext(in)
 			in = in[1:]
-			out, err := client.Invoke(name, in, settings)
+		}
+		if isVariadic {
+			in = getIn(in)
+		}
+		callback := in[0]
+		in = in[1:]
+		cb := func(out []reflect.Value, err error) {
 			if hasError {
 				out = append(out, reflect.ValueOf(&err).Elem())
 			}
 			defer client.fireErrorEvent(name, err)
 			callback.Call(out)
+		}
+		if batch != nil {
+			batch.Go(name, in, cb, settings)
+			return nil
+		}
+		go func() {
+			cb(client.InvokeContext(ctx, name, in, settings))
 		}()
 		return nil
 	}
 }
 
-func buildRemoteMethod(client *baseClient, f reflect.Value, ft reflect.Type, sf reflect.StructField, ns string) {
+func getFutureRemoteMethod(
+	client *baseClient,
+	batch *Batch,
+	name string,
+	settings *InvokeSettings,
+	isVariadic, hasContext bool) func(in []reflect.Value) (out []reflect.Value) {
+	return func(in []reflect.Value) (out []reflect.Value) {
+		ctx := gocontext.Background()
+		if hasContext {
+			ctx = getContext(in)
+			in = in[1:]
+		}
+		if isVariadic {
+			in = getIn(in)
+		}
+		var future *Future
+		if batch != nil {
+			future = newFuture()
+			batch.Go(name, in, future.resolve, settings)
+		} else {
+			future = client.InvokeAsyncContext(ctx, name, in, settings)
+		}
+		return []reflect.Value{reflect.ValueOf(future)}
+	}
+}
+
+func buildRemoteMethod(client *baseClient, batch *Batch, f reflect.Value, ft reflect.Type, sf reflect.StructField, ns string) {
 	name := getRemoteMethodName(sf, ns)
 	outTypes, hasError := getResultTypes(ft)
+	hasContext := ft.NumIn() > 0 && ft.In(0) == goContextType
+	first := 0
+	if hasContext {
+		first = 1
+	}
 	async := false
+	future := len(outTypes) == 1 && !hasError && outTypes[0] == futureType
+	if future {
+		outTypes = []reflect.Type{interfaceType}
+	}
 	if outTypes == nil && hasError == false {
-		if ft.NumIn() > 0 && ft.In(0).Kind() == reflect.Func {
-			cbft := ft.In(0)
+		if ft.NumIn() > first && ft.In(first).Kind() == reflect.Func {
+			cbft := ft.In(first)
 			if cbft.IsVariadic() {
 				panic("callback can't be variadic function")
 			}
@@ -764,6 +1148,7 @@ func buildRemoteMethod(client *baseClient, f reflect.Value, ft reflect.Type, sf
 			async = true
 		}
 	}
+	hedge, hedgeQuantile := getHedgeValue(sf.Tag, "hedge")
 	settings := &InvokeSettings{
 		ByRef:          getBoolValue(sf.Tag, "byref"),
 		Simple:         getBoolValue(sf.Tag, "simple"),
@@ -771,16 +1156,26 @@ func buildRemoteMethod(client *baseClient, f reflect.Value, ft reflect.Type, sf
 		Failswitch:     getBoolValue(sf.Tag, "failswitch"),
 		Oneway:         getBoolValue(sf.Tag, "oneway"),
 		JSONCompatible: getBoolValue(sf.Tag, "jsoncompat"),
+		UTC:            getBoolValue(sf.Tag, "utc"),
+		DateOnly:       getBoolValue(sf.Tag, "dateonly"),
+		TimePrecision:  getPrecisionValue(sf.Tag, "timeprecision"),
+		Location:       getLocationValue(client, name, sf.Tag, "location"),
 		Retry:          int(getInt64Value(sf.Tag, "retry")),
+		RetryPolicy:    getRetryPolicyValue(sf.Tag, "retrypolicy"),
+		Hedge:          hedge,
+		HedgeQuantile:  hedgeQuantile,
+		Cache:          getDurationValue(sf.Tag, "cache"),
 		Mode:           getResultMode(sf.Tag),
 		Timeout:        time.Duration(getInt64Value(sf.Tag, "timeout")),
 		ResultTypes:    outTypes,
 	}
 	var fn func(in []reflect.Value) (out []reflect.Value)
-	if async {
-		fn = getAsyncRemoteMethod(client, name, settings, ft.IsVariadic(), hasError)
+	if future {
+		fn = getFutureRemoteMethod(client, batch, name, settings, ft.IsVariadic(), hasContext)
+	} else if async {
+		fn = getAsyncRemoteMethod(client, batch, name, settings, ft.IsVariadic(), hasError, hasContext)
 	} else {
-		fn = getSyncRemoteMethod(client, name, settings, ft.IsVariadic(), hasError)
+		fn = getSyncRemoteMethod(client, batch, name, settings, ft.IsVariadic(), hasError, hasContext)
 	}
 	if f.Kind() == reflect.Ptr {
 		fp := reflect.New(ft)
@@ -821,15 +1216,17 @@ func (client *baseClient) ID() (string, error) {
 
 func (client *baseClient) processCallback(
 	name string,
-	callbacks []Callback,
+	topic TopicName,
+	callbacks []topicCallback,
 	resultTypes []reflect.Type,
-	results []reflect.Value,
-	err error) {
+	results []reflect.Value) {
 	defer client.fireErrorEvent(name, nil)
-	if resultTypes != nil && len(resultTypes) > 0 {
+	if len(resultTypes) == 0 {
+		results = nil
+	} else {
 		writer := hio.NewWriter(false)
 		writer.WriteValue(results[0])
-		reader := client.acquireReader(writer.Bytes())
+		reader := acquireReader(writer.Bytes())
 		if len(resultTypes) == 1 {
 			results = make([]reflect.Value, 1)
 			results[0] = reflect.New(resultTypes[0]).Elem()
@@ -837,34 +1234,276 @@ func (client *baseClient) processCallback(
 		} else {
 			results = readMultiResults(reader, resultTypes)
 		}
-		client.releaseReader(reader)
+		releaseReader(reader)
 	}
 	for _, callback := range callbacks {
-		callback(results, err)
+		callback(topic, results)
+	}
+}
+
+var defaultSubscribeBackoff = &ExponentialBackoff{
+	InitialInterval: 100 * time.Millisecond,
+	MaxInterval:     30 * time.Second,
+	Multiplier:      2,
+	Jitter:          0.5,
+	Retryable:       func(err error) bool { return true },
+}
+
+// SubscribeBackoff returns the backoff policy of the push subscriptions
+func (client *baseClient) SubscribeBackoff() RetryPolicy {
+	return client.pushBackoff
+}
+
+// SetSubscribeBackoff set the backoff policy of the push subscriptions, it
+// decides the delay before the subscription reconnects after an error, the
+// subscription is stopped and removed if the policy returns false. The default
+// policy is an exponential backoff from 100ms to 30s with 50% jitter.
+func (client *baseClient) SetSubscribeBackoff(policy RetryPolicy) {
+	if policy == nil {
+		policy = defaultSubscribeBackoff
+	}
+	client.pushBackoff = policy
+}
+
+// pushDedupWindow is the number of the received message ids remembered by a
+// push subscription, the messages which are received again are dropped.
+const pushDedupWindow = 1024
+
+type dedupWindow struct {
+	seen  map[string]bool
+	ids   []string
+	index int
+}
+
+// add returns false if id is in the window.
+func (window *dedupWindow) add(id string) bool {
+	if window.seen == nil {
+		window.seen = make(map[string]bool, pushDedupWindow)
+		window.ids = make([]string, pushDedupWindow)
+	}
+	if window.seen[id] {
+		return false
+	}
+	delete(window.seen, window.ids[window.index])
+	window.ids[window.index] = id
+	window.index = (window.index + 1) % pushDedupWindow
+	window.seen[id] = true
+	return true
+}
+
+type pushBatch struct {
+	id       string
+	ids      []string
+	topics   []string
+	messages []reflect.Value
+}
+
+func parsePushStrings(value reflect.Value) (list []string, ok bool) {
+	if value.Kind() != reflect.Slice {
+		return nil, false
+	}
+	list = make([]string, value.Len())
+	for i := range list {
+		s := value.Index(i)
+		if s.Kind() == reflect.Interface {
+			s = s.Elem()
+		}
+		if s.Kind() != reflect.String {
+			return nil, false
+		}
+		list[i] = s.String()
+	}
+	return list, true
+}
+
+// parsePushBatch parses the batch of messages with ids which is pushed to the
+// clients that resume from the last received message id, ok is false if
+// result is not a batch.
+func parsePushBatch(result reflect.Value) (batch pushBatch, ok bool) {
+	if result.Kind() == reflect.Interface {
+		result = result.Elem()
+	}
+	if result.Kind() != reflect.Map || result.Len() < 2 || result.Len() > 4 {
+		return
+	}
+	for _, key := range result.MapKeys() {
+		k := key
+		if k.Kind() == reflect.Interface {
+			k = k.Elem()
+		}
+		if k.Kind() != reflect.String {
+			return
+		}
+		value := result.MapIndex(key)
+		if value.Kind() == reflect.Interface {
+			value = value.Elem()
+		}
+		switch k.String() {
+		case pushMessageID:
+			if value.Kind() != reflect.String {
+				return
+			}
+			batch.id = value.String()
+		case pushMessageIDs:
+			if batch.ids, ok = parsePushStrings(value); !ok {
+				return
+			}
+		case pushMessageTopics:
+			if batch.topics, ok = parsePushStrings(value); !ok {
+				return
+			}
+		case pushMessageMessages:
+			if value.Kind() != reflect.Slice {
+				return
+			}
+			batch.messages = make([]reflect.Value, value.Len())
+			for i := range batch.messages {
+				batch.messages[i] = reflect.New(interfaceType).Elem()
+				if message := value.Index(i); message.IsValid() {
+					batch.messages[i].Set(message)
+				}
+			}
+		default:
+			return
+		}
 	}
+	if batch.ids != nil && len(batch.ids) != len(batch.messages) ||
+		batch.topics != nil && len(batch.topics) != len(batch.messages) {
+		return batch, false
+	}
+	return batch, batch.id != ""
+}
+
+func (client *baseClient) subscribeDelay(
+	name string, attempt int, elapsed time.Duration,
+	err error, settings *InvokeSettings) (time.Duration, bool) {
+	context := client.acquireContext()
+	defer client.releaseContext(context)
+	client.initClientContext(context, settings)
+	context.name = name
+	return client.pushBackoff.Backoff(attempt, elapsed, err, context)
 }
 
 func (client *baseClient) subscribe(
-	name string, id string, settings *InvokeSettings) {
+	ctx gocontext.Context, name string, id string,
+	topic *clientTopic, settings InvokeSettings) {
 	resultTypes := settings.ResultTypes
 	settings.ResultTypes = []reflect.Type{interfaceType}
-	args := []reflect.Value{reflect.ValueOf(id)}
-	for {
-		topic := client.getTopic(name, id)
-		if topic == nil {
+	lastID := ""
+	var acks []string
+	var window dedupWindow
+	connected := false
+	attempt := 0
+	var failed time.Time
+	defer client.fireSubscribeStoppedEvent(name, id)
+	for ctx.Err() == nil {
+		args := []reflect.Value{
+			reflect.ValueOf(id),
+			reflect.ValueOf(lastID),
+			reflect.ValueOf(strings.Join(acks, ",")),
+		}
+		results, err := client.InvokeContext(ctx, name, args, &settings)
+		if ctx.Err() != nil {
 			return
 		}
+		if err != nil {
+			if attempt == 0 {
+				failed = time.Now()
+			}
+			attempt++
+			delay, ok := client.subscribeDelay(
+				name, attempt, time.Since(failed), err, &settings)
+			if !ok {
+				// the stopped subscription is removed, so that it can be
+				// subscribed again.
+				client.removeTopic(name, id, topic)
+				client.fireErrorEvent(name, err)
+				return
+			}
+			if connected || attempt == 1 {
+				connected = false
+				client.fireSubscribeReconnectingEvent(name, id, err)
+			}
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			continue
+		}
+		attempt = 0
+		acks = acks[:0]
+		if !connected {
+			connected = true
+			client.fireSubscribeConnectedEvent(name, id)
+		}
+		if len(results) == 0 {
+			continue
+		}
+		batch, ok := parsePushBatch(results[0])
+		if ok {
+			lastID = batch.id
+		} else if results[0].IsNil() {
+			continue
+		} else {
+			batch.messages = results[:1]
+		}
 		topic.locker.RLock()
 		callbacks := topic.callbacks
 		topic.locker.RUnlock()
-		results, err := client.Invoke(name, args, settings)
-		if !results[0].IsNil() {
-			client.processCallback(name, callbacks, resultTypes, results, err)
+		for i, message := range batch.messages {
+			if batch.ids != nil {
+				acks = append(acks, batch.ids[i])
+				if !window.add(batch.ids[i]) {
+					continue
+				}
+			}
+			topicName := TopicName(name)
+			if batch.topics != nil {
+				topicName = TopicName(batch.topics[i])
+			}
+			client.processCallback(name, topicName,
+				callbacks, resultTypes, []reflect.Value{message})
 		}
 	}
 }
 
+func (client *baseClient) fireSubscribeConnectedEvent(name string, id string) {
+	defer client.fireErrorEvent(name, nil)
+	if event, ok := client.event.(onSubscribeConnectedEvent); ok {
+		event.OnSubscribeConnected(name, id)
+	}
+}
+
+func (client *baseClient) fireSubscribeReconnectingEvent(
+	name string, id string, err error) {
+	defer client.fireErrorEvent(name, nil)
+	if event, ok := client.event.(onSubscribeReconnectingEvent); ok {
+		event.OnSubscribeReconnecting(name, id, err)
+	}
+}
+
+func (client *baseClient) fireSubscribeStoppedEvent(name string, id string) {
+	defer client.fireErrorEvent(name, nil)
+	if event, ok := client.event.(onSubscribeStoppedEvent); ok {
+		event.OnSubscribeStopped(name, id)
+	}
+}
+
 // Subscribe a push topic
+//
+// The subscription reconnects with the backoff policy of SubscribeBackoff
+// after an error, and it is stopped by Unsubscribe or Close. If the service
+// supports it, the subscription resumes from the last received message, so
+// the messages pushed during the reconnection are not lost. The received
+// messages are acknowledged in the next subscription request, and the
+// messages which are received again are dropped.
+//
+// The name can be a wildcard pattern (see IsTopicPattern) if the service
+// publishes the pattern or creates it by TopicPolicy. If the first parameter
+// of callback is a TopicName, it receives the topic name of the message.
 func (client *baseClient) Subscribe(
 	name string, id string,
 	settings *InvokeSettings, callback interface{}) (err error) {
@@ -874,13 +1513,25 @@ func (client *baseClient) Subscribe(
 			return err
 		}
 	}
+	if strings.ContainsAny(name, "*>") && !validTopicName(name) {
+		return errors.New("Subscribe: invalid topic pattern " + name)
+	}
 	f := reflect.ValueOf(callback)
 	if f.Kind() != reflect.Func {
 		return errors.New("Subscribe: callback must be a function")
 	}
-	resultTypes, hasError := getCallbackResultTypes(f.Type())
-	cb := func(results []reflect.Value, err error) {
+	ft := f.Type()
+	hasTopic := ft.NumIn() > 0 && ft.In(0) == topicNameType
+	resultTypes, hasError := getCallbackResultTypes(ft)
+	if hasTopic {
+		resultTypes = resultTypes[1:]
+	}
+	cb := func(topic TopicName, results []reflect.Value) {
+		if hasTopic {
+			results = append([]reflect.Value{reflect.ValueOf(topic)}, results...)
+		}
 		if hasError {
+			var err error
 			results = append(results, reflect.ValueOf(&err).Elem())
 		}
 		f.Call(results)
@@ -895,15 +1546,18 @@ func (client *baseClient) Subscribe(
 	settings.Simple = true
 	settings.ResultTypes = resultTypes
 	client.createTopic(name)
-	topic := client.getTopic(name, id)
+	client.topicManager.locker.Lock()
+	topic := client.allTopics[name][id]
 	if topic == nil {
 		topic = new(clientTopic)
 		topic.addCallback(cb)
-		client.topicManager.locker.Lock()
+		ctx, cancel := gocontext.WithCancel(gocontext.Background())
+		topic.cancel = cancel
 		client.allTopics[name][id] = topic
 		client.topicManager.locker.Unlock()
-		go client.subscribe(name, id, settings)
+		go client.subscribe(ctx, name, id, topic, *settings)
 	} else {
+		client.topicManager.locker.Unlock()
 		topic.addCallback(cb)
 	}
 	return nil
@@ -912,19 +1566,26 @@ func (client *baseClient) Subscribe(
 // Unsubscribe a push topic
 func (client *baseClient) Unsubscribe(name string, id ...string) {
 	client.topicManager.locker.Lock()
-	if client.allTopics[name] != nil {
+	if topics := client.allTopics[name]; topics != nil {
 		if len(id) == 0 {
 			if client.id == "" {
+				for _, topic := range topics {
+					topic.cancel()
+				}
 				delete(client.allTopics, name)
-			} else {
-				delete(client.allTopics[name], client.id)
+			} else if topic := topics[client.id]; topic != nil {
+				topic.cancel()
+				delete(topics, client.id)
 			}
 		} else {
 			for i := range id {
-				delete(client.allTopics[name], id[i])
+				if topic := topics[id[i]]; topic != nil {
+					topic.cancel()
+					delete(topics, id[i])
+				}
 			}
 		}
-		if len(client.allTopics[name]) == 0 {
+		if len(topics) == 0 {
 			delete(client.allTopics, name)
 		}
 	}
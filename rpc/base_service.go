@@ -12,7 +12,7 @@
  *                                                        *
  * hprose base service for Go.                            *
  *                                                        *
- * LastModified: Oct 19, 2016                             *
+ * LastModified: Oct 18, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -23,11 +23,12 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/hprose/hprose-golang/io"
 	"github.com/hprose/hprose-golang/util"
 )
 
@@ -37,15 +38,34 @@ type baseService struct {
 	filterManager
 	Clients
 	FixArguments func(args []reflect.Value, context ServiceContext)
+	Codec        Codec
 	Event        ServiceEvent
 	Debug        bool
 	Timeout      time.Duration
 	Heartbeat    time.Duration
 	ErrorDelay   time.Duration
 	UserData     map[string]interface{}
-	readerPool   sync.Pool
 	topics       map[string]*topic
+	patterns     map[string]*topic
 	topicLock    sync.RWMutex
+
+	// TopicPolicy decides whether the topic which is not published is created
+	// on the first subscription, and the options of it. The topic can be a
+	// wildcard pattern, see IsTopicPattern. The created topic is removed when
+	// its last client is offline.
+	TopicPolicy func(topic string, context ServiceContext) (
+		options PublishOptions, ok bool)
+
+	Registrar        Registrar
+	Metadata         map[string]string
+	RegisterInterval time.Duration
+	registration     *Registration
+	registerStop     chan struct{}
+	registerLock     sync.Mutex
+	pushBroker       PushBroker
+	pushNode         string
+	pushUnsubscribe  func()
+	pushLock         sync.RWMutex
 }
 
 func defaultFixArguments(args []reflect.Value, context ServiceContext) {
@@ -62,10 +82,9 @@ func (service *baseService) initBaseService() {
 	service.Timeout = 120 * time.Second
 	service.Heartbeat = 3 * time.Second
 	service.ErrorDelay = 10 * time.Second
+	service.RegisterInterval = 10 * time.Second
 	service.topics = make(map[string]*topic)
-	service.readerPool = sync.Pool{
-		New: func() interface{} { return new(io.Reader) },
-	}
+	service.patterns = make(map[string]*topic)
 	service.AddFunction("#", util.UUIDv4, Options{Simple: true})
 	service.override.invokeHandler = func(
 		name string, args []reflect.Value,
@@ -224,40 +243,6 @@ func callService(
 	return results, err
 }
 
-func doOutput(
-	args []reflect.Value,
-	results []reflect.Value,
-	context ServiceContext) []byte {
-	method := context.Method()
-	writer := io.NewWriter(method.Simple)
-	switch method.Mode {
-	case RawWithEndTag:
-		return results[0].Bytes()
-	case Raw:
-		writer.Write(results[0].Bytes())
-	default:
-		writer.WriteByte(io.TagResult)
-		if method.Mode == Serialized {
-			writer.Write(results[0].Bytes())
-		} else {
-			switch len(results) {
-			case 0:
-				writer.WriteNil()
-			case 1:
-				writer.WriteValue(results[0])
-			default:
-				writer.WriteSlice(results)
-			}
-		}
-		if context.ByRef() {
-			writer.WriteByte(io.TagArgument)
-			writer.Reset()
-			writer.WriteSlice(args)
-		}
-	}
-	return writer.Bytes()
-}
-
 func getErrorMessage(err error, debug bool) string {
 	if panicError, ok := err.(*PanicError); ok {
 		if debug {
@@ -323,18 +308,27 @@ func fireAfterInvokeEvent(
 	return err
 }
 
+func (service *baseService) codec() Codec {
+	if service.Codec == nil {
+		return defaultCodec
+	}
+	return service.Codec
+}
+
+func (service *baseService) contentType() string {
+	return service.codec().ContentType()
+}
+
 func (service *baseService) sendError(err error, context Context) []byte {
 	err = fireErrorEvent(service.Event, err, context)
-	w := io.NewWriter(true)
-	w.WriteByte(io.TagError)
-	w.WriteString(getErrorMessage(err, service.Debug))
-	return w.Bytes()
+	sc, _ := context.(ServiceContext)
+	return service.codec().EncodeError(getErrorMessage(err, service.Debug), sc)
 }
 
 func (service *baseService) endError(err error, context Context) []byte {
-	w := io.NewByteWriter(service.sendError(err, context))
-	w.WriteByte(io.TagEnd)
-	return w.Bytes()
+	sc, _ := context.(ServiceContext)
+	response := service.sendError(err, context)
+	return service.codec().MergeResponse([][]byte{response}, sc)
 }
 
 func invoke(
@@ -350,42 +344,47 @@ func invoke(
 	return callService(name, args, context)
 }
 
-func readArguments(
-	fixArguments func(args []reflect.Value, context ServiceContext),
-	reader *io.Reader,
+func (service *baseService) readArguments(
+	call Call,
 	method *Method,
-	context ServiceContext) (args []reflect.Value) {
-	if method != nil {
-		reader.JSONCompatible = method.JSONCompatible
-	}
+	context ServiceContext) (args []reflect.Value, err error) {
 	if method == nil || context.IsMissingMethod() {
-		return reader.ReadSliceWithoutTag()
+		return call.Args(method, func(count int) []reflect.Value {
+			args := make([]reflect.Value, count)
+			for i := 0; i < count; i++ {
+				args[i] = reflect.New(interfaceType).Elem()
+			}
+			return args
+		})
 	}
-	count := reader.ReadCount()
 	ft := method.Function.Type()
 	n := ft.NumIn()
 	if ft.IsVariadic() {
 		n--
 	}
-	max := util.Max(n, count)
-	args = make([]reflect.Value, max)
-	for i := 0; i < n; i++ {
-		args[i] = reflect.New(ft.In(i)).Elem()
-	}
-	if n < count {
-		if ft.IsVariadic() {
-			for i := n; i < count; i++ {
-				args[i] = reflect.New(ft.In(n).Elem()).Elem()
-			}
-		} else {
-			for i := n; i < count; i++ {
-				args[i] = reflect.New(interfaceType).Elem()
+	count := 0
+	args, err = call.Args(method, func(c int) []reflect.Value {
+		count = c
+		max := util.Max(n, count)
+		args := make([]reflect.Value, max)
+		for i := 0; i < n; i++ {
+			args[i] = reflect.New(ft.In(i)).Elem()
+		}
+		if n < count {
+			if ft.IsVariadic() {
+				for i := n; i < count; i++ {
+					args[i] = reflect.New(ft.In(n).Elem()).Elem()
+				}
+			} else {
+				for i := n; i < count; i++ {
+					args[i] = reflect.New(interfaceType).Elem()
+				}
 			}
 		}
-	}
-	reader.ReadSlice(args[:count])
-	if !ft.IsVariadic() && n > count {
-		fixArguments(args, context)
+		return args
+	})
+	if err == nil && args != nil && !ft.IsVariadic() && n > count {
+		service.FixArguments(args, context)
 	}
 	return
 }
@@ -407,107 +406,63 @@ func (service *baseService) beforeInvoke(
 	if err != nil {
 		return nil, err
 	}
-	return doOutput(args, results, context), nil
-}
-
-func mergeResult(results [][]byte) []byte {
-	n := len(results)
-	if n == 1 {
-		return append(results[0], io.TagEnd)
-	}
-	writer := io.NewByteWriter(results[0])
-	for i := 1; i < n; i++ {
-		writer.Write(results[i])
-	}
-	writer.WriteByte(io.TagEnd)
-	return writer.Bytes()
+	return service.codec().EncodeResult(args, results, context), nil
 }
 
 func (service *baseService) doSingleInvoke(
-	reader *io.Reader, context ServiceContext) (result []byte, tag byte) {
-	name := reader.ReadString()
+	call Call, context ServiceContext) []byte {
+	name := call.Name()
 	alias := strings.ToLower(name)
 	method := service.RemoteMethods[alias]
+	if method == nil {
+		method = service.topicMethod(name, context)
+	}
 	if method == nil {
 		method = service.RemoteMethods["*"]
 		context.setIsMissingMethod(true)
 	}
-	tag = reader.CheckTags([]byte{io.TagList, io.TagEnd, io.TagCall})
-	var args []reflect.Value
-	if tag == io.TagList {
-		reader.Reset()
-		args = readArguments(service.FixArguments, reader, method, context)
-		tag = reader.CheckTags([]byte{io.TagTrue, io.TagEnd, io.TagCall})
-		if tag == io.TagTrue {
-			context.setByRef(true)
-			tag = reader.CheckTags([]byte{io.TagEnd, io.TagCall})
-		}
+	context.setByRef(call.ByRef())
+	args, err := service.readArguments(call, method, context)
+	if err != nil {
+		return service.sendError(err, context)
 	}
 	if method == nil {
 		err := errors.New("Can't find this method " + name)
-		return service.sendError(err, context), tag
+		return service.sendError(err, context)
 	}
 	context.setMethod(method)
 	result, err := service.beforeInvoke(name, args, context)
 	if err != nil {
-		return service.sendError(err, context), tag
+		return service.sendError(err, context)
 	}
-	return result, tag
+	return result
 }
 
 func (service *baseService) doInvoke(
-	reader *io.Reader,
+	calls []Call,
 	context ServiceContext) []byte {
-	var results [][]byte
-	for {
-		result, tag := service.doSingleInvoke(reader, context)
-		results = append(results, result)
-		if tag != io.TagCall {
-			break
-		}
-		reader.Reset()
+	results := make([][]byte, len(calls))
+	for i, call := range calls {
+		results[i] = service.doSingleInvoke(call, context)
 	}
-	return mergeResult(results)
+	return service.codec().MergeResponse(results, context)
 }
 
 func (service *baseService) doFunctionList(context ServiceContext) []byte {
-	writer := io.NewWriter(true)
-	writer.WriteByte(io.TagFunctions)
-	writer.WriteStringSlice(service.MethodNames)
-	writer.WriteByte(io.TagEnd)
-	return writer.Bytes()
-}
-
-func (service *baseService) acquireReader(buf []byte) (reader *io.Reader) {
-	reader = service.readerPool.Get().(*io.Reader)
-	reader.Init(buf)
-	return
-}
-
-func (service *baseService) releaseReader(reader *io.Reader) {
-	reader.Init(nil)
-	reader.Reset()
-	service.readerPool.Put(reader)
+	return service.codec().EncodeFunctions(service.MethodNames, context)
 }
 
 func (service *baseService) afterFilter(
 	request []byte,
 	context ServiceContext) (response []byte, err error) {
-	reader := service.acquireReader(request)
-	defer service.releaseReader(reader)
-	reader.Init(request)
-	tag, err := reader.ReadByte()
+	calls, err := service.codec().DecodeRequest(request, context)
 	if err != nil {
 		return nil, err
 	}
-	switch tag {
-	case io.TagCall:
-		return service.doInvoke(reader, context), nil
-	case io.TagEnd:
+	if len(calls) == 0 {
 		return service.doFunctionList(context), nil
-	default:
-		return nil, fmt.Errorf("Wrong Request: \r\n%s", request)
 	}
+	return service.doInvoke(calls, context), nil
 }
 
 func (service *baseService) delayError(
@@ -563,11 +518,54 @@ func fireUnsubscribeEvent(
 	}
 }
 
-func (service *baseService) offline(t *topic, topic string, id string) {
-	if t.exist(id) {
-		t.remove(id)
+func fireDeliveryFailedEvent(
+	topic string,
+	id string,
+	result interface{},
+	service *baseService) {
+	defer recover()
+	if event, ok := service.Event.(deliveryFailedEvent); ok {
+		event.OnDeliveryFailed(topic, id, result)
+	}
+}
+
+func (service *baseService) offline(
+	t *topic, topic string, id string, s *subscriber) {
+	if t.remove(id, s) {
 		fireUnsubscribeEvent(topic, id, service)
+		if t.dynamic {
+			service.removeTopic(t)
+		}
+	}
+}
+
+// removeTopic removes the topic created by TopicPolicy if it has no client,
+// so the topics of the offline clients don't pile up.
+func (service *baseService) removeTopic(t *topic) {
+	service.topicLock.Lock()
+	defer service.topicLock.Unlock()
+	if service.topics[t.name] != t || !t.close() {
+		return
+	}
+	delete(service.topics, t.name)
+	if t.pattern {
+		delete(service.patterns, t.name)
+	}
+}
+
+// restoreTopic adds the removed topic t back, or returns the topic which is
+// created after t is removed.
+func (service *baseService) restoreTopic(t *topic) *topic {
+	service.topicLock.Lock()
+	defer service.topicLock.Unlock()
+	if old := service.topics[t.name]; old != nil {
+		return old
 	}
+	t.Lock()
+	t.removed = false
+	t.Unlock()
+	service.addTopic(t)
+	return t
 }
 
 // Publish the hprose push topic
@@ -575,104 +573,388 @@ func (service *baseService) Publish(
 	topic string,
 	timeout time.Duration,
 	heartbeat time.Duration) Service {
-	if timeout <= 0 {
-		timeout = service.Timeout
-	}
-	if heartbeat <= 0 {
-		heartbeat = service.Heartbeat
-	}
-	t := newTopic(heartbeat)
+	return service.PublishWithOptions(topic, PublishOptions{
+		Timeout:   timeout,
+		Heartbeat: heartbeat,
+	})
+}
+
+// PublishWithOptions publish the hprose push topic with options
+//
+// The pushed messages are queued for every client until they are fetched by
+// the subscription requests of the client. The clients which subscribe the
+// topic with the last received message id (the second argument) get a batch
+// of messages with their ids, and the messages which are not acknowledged by
+// the next subscription request are sent again, so no message is lost when
+// the client reconnects in time. The acknowledged message ids of the
+// DeliveryAtLeastOnce topics are the third argument, separated by commas.
+//
+// The topic can be a wildcard pattern (see IsTopicPattern), the messages
+// pushed to the topics which match the pattern are pushed to the clients of
+// the pattern too.
+func (service *baseService) PublishWithOptions(
+	topic string, options PublishOptions) Service {
+	t := service.newTopic(topic, options)
 	service.topicLock.Lock()
-	service.topics[topic] = t
+	service.addTopic(t)
 	service.topicLock.Unlock()
-	return service.AddFunction(topic, func(id string) interface{} {
-		message := t.get(id)
-		if message == nil {
-			message = make(chan interface{})
-			t.put(id, message)
-			fireSubscribeEvent(topic, id, service)
+	return service.AddFunction(topic, t.method.Function, Options{})
+}
+
+// addTopic adds t to the topics, topicLock must be locked.
+func (service *baseService) addTopic(t *topic) {
+	if old := service.topics[t.name]; old != nil && old.pattern {
+		delete(service.patterns, t.name)
+	}
+	service.topics[t.name] = t
+	if t.pattern {
+		service.patterns[t.name] = t
+	}
+}
+
+func (service *baseService) newTopic(
+	topic string, options PublishOptions) *topic {
+	if options.Timeout <= 0 {
+		options.Timeout = service.Timeout
+	}
+	if options.Heartbeat <= 0 {
+		options.Heartbeat = service.Heartbeat
+	}
+	t := newTopic(topic, options, func(id string, result interface{}) {
+		fireDeliveryFailedEvent(topic, id, result, service)
+	})
+	t.method = &Method{Function: reflect.ValueOf(func(id string, args ...string) interface{} {
+		return service.subscribe(t, id, args)
+	})}
+	return t
+}
+
+// subscribe handles a subscription request of id to t.
+func (service *baseService) subscribe(
+	t *topic, id string, args []string) interface{} {
+	s, created := t.poll(id)
+	if s == nil {
+		// t is removed after its last client was offline
+		return service.subscribe(service.restoreTopic(t), id, args)
+	}
+	if created {
+		fireSubscribeEvent(t.name, id, service)
+	}
+	defer t.done(id, s, func() { service.offline(t, t.name, id, s) })
+	resume := len(args) > 0
+	if resume {
+		lastID, _ := strconv.ParseInt(args[0], 10, 64)
+		var acks map[int64]bool
+		if len(args) > 1 && args[1] != "" {
+			ids := strings.Split(args[1], ",")
+			acks = make(map[int64]bool, len(ids))
+			for _, id := range ids {
+				if n, err := strconv.ParseInt(id, 10, 64); err == nil {
+					acks[n] = true
+				}
+			}
+		}
+		if lastID > 0 || acks != nil {
+			t.ack(s, lastID, acks)
+		}
+	}
+	timer := time.NewTimer(t.timeout)
+	defer timer.Stop()
+	for {
+		messages, wait := t.next(id, s, resume)
+		if messages != nil {
+			if !resume {
+				return messages[0].result
+			}
+			return encodeTopicMessages(t, messages)
+		}
+		var redeliver *time.Timer
+		var redeliverC <-chan time.Time
+		if wait > 0 {
+			redeliver = time.NewTimer(wait)
+			redeliverC = redeliver.C
 		}
+		expired := false
 		select {
-		case result := <-message:
-			return result
-		case <-time.After(timeout):
-			service.offline(t, topic, id)
+		case <-s.notify:
+		case <-redeliverC:
+		case <-timer.C:
+			expired = true
+		}
+		if redeliver != nil {
+			redeliver.Stop()
+		}
+		if expired {
 			return nil
 		}
-	}, Options{})
+	}
+}
+
+// encodeTopicMessages returns the batch of messages with ids, the topic names
+// of the messages are included if t is a pattern.
+func encodeTopicMessages(t *topic, messages []*topicMessage) interface{} {
+	ids := make([]string, len(messages))
+	results := make([]interface{}, len(messages))
+	for i, m := range messages {
+		ids[i] = strconv.FormatInt(m.id, 10)
+		results[i] = m.result
+	}
+	batch := map[string]interface{}{
+		pushMessageID:       ids[len(ids)-1],
+		pushMessageIDs:      ids,
+		pushMessageMessages: results,
+	}
+	if t.pattern {
+		topics := make([]string, len(messages))
+		for i, m := range messages {
+			topics[i] = m.topic
+		}
+		batch[pushMessageTopics] = topics
+	}
+	return batch
 }
 
+// topicMethod returns the method of the topic which is not published by
+// Publish, the topic is created if TopicPolicy allows it.
+func (service *baseService) topicMethod(
+	name string, context ServiceContext) *Method {
+	service.topicLock.RLock()
+	t := service.topics[name]
+	service.topicLock.RUnlock()
+	if t != nil {
+		return t.method
+	}
+	policy := service.TopicPolicy
+	if policy == nil || !validTopicName(name) {
+		return nil
+	}
+	options, ok := policy(name, context)
+	if !ok {
+		return nil
+	}
+	t = service.newTopic(name, options)
+	t.dynamic = true
+	service.topicLock.Lock()
+	defer service.topicLock.Unlock()
+	if old := service.topics[name]; old != nil {
+		return old.method
+	}
+	service.addTopic(t)
+	return t.method
+}
+
+// getTopic returns the topic, it panics if the topic is not published and
+// the service has no TopicPolicy.
 func (service *baseService) getTopic(topic string) (t *topic) {
 	service.topicLock.RLock()
 	t = service.topics[topic]
 	service.topicLock.RUnlock()
-	if t == nil {
+	if t == nil && service.TopicPolicy == nil {
 		panic("topic \"" + topic + "\" is not published.")
 	}
 	return
 }
 
-func (service *baseService) unicast(t *topic,
-	topic string, id string, result interface{}, callback func(bool)) {
-	message := t.get(id)
-	if message == nil {
-		if callback != nil {
-			callback(false)
+// matchTopics returns the topic and the patterns which match the topic.
+func (service *baseService) matchTopics(topic string) (topics []*topic) {
+	service.topicLock.RLock()
+	if t := service.topics[topic]; t != nil {
+		topics = append(topics, t)
+	}
+	for pattern, t := range service.patterns {
+		if pattern != topic && MatchTopic(pattern, topic) {
+			topics = append(topics, t)
 		}
+	}
+	service.topicLock.RUnlock()
+	return
+}
+
+// pushTopics returns the topics which the messages pushed to the topic reach,
+// it panics if there is none and the service has no TopicPolicy.
+func (service *baseService) pushTopics(topic string) []*topic {
+	topics := service.matchTopics(topic)
+	if topics == nil && service.TopicPolicy == nil {
+		panic("topic \"" + topic + "\" is not published.")
+	}
+	return topics
+}
+
+// unicast pushes the result of the topic source to the client id of t
+func (service *baseService) unicast(t *topic,
+	source string, id string, result interface{}, callback func(bool)) {
+	s, ok := t.push(id, source, result, callback)
+	if ok {
 		return
 	}
-	go func() {
-		select {
-		case message <- result:
-			if callback != nil {
-				callback(true)
-			}
-			break
-		case <-time.After(t.heartbeat):
-			service.offline(t, topic, id)
-			if callback != nil {
-				callback(false)
-			}
-		}
-	}()
+	if s != nil && t.overflow == OverflowDisconnect {
+		service.offline(t, t.name, id, s)
+	}
+	if callback != nil {
+		callback(false)
+	}
 }
 
 // IDList returns the push client id list
 func (service *baseService) IDList(topic string) []string {
-	return service.getTopic(topic).idlist()
+	if t := service.getTopic(topic); t != nil {
+		return t.idlist()
+	}
+	return nil
 }
 
 // Exist returns true if the client id exist.
 func (service *baseService) Exist(topic string, id string) bool {
-	return service.getTopic(topic).exist(id)
+	if t := service.getTopic(topic); t != nil {
+		return t.exist(id)
+	}
+	return false
+}
+
+// QueueDepth returns the number of the queued messages of the client id,
+// including the messages which are sent but not acknowledged.
+func (service *baseService) QueueDepth(topic string, id string) int {
+	if t := service.getTopic(topic); t != nil {
+		return t.depth(id)
+	}
+	return 0
+}
+
+// PushBroker returns the push broker of the service
+func (service *baseService) PushBroker() PushBroker {
+	service.pushLock.RLock()
+	defer service.pushLock.RUnlock()
+	return service.pushBroker
+}
+
+// SetPushBroker set the push broker of the service, Push, Broadcast,
+// Multicast and Unicast route the messages through the broker, so that they
+// reach the clients on every service node which publishes the topic. The
+// callbacks of Broadcast, Multicast and Unicast only report the clients on
+// this node. The broker is removed if it is nil.
+func (service *baseService) SetPushBroker(broker PushBroker) error {
+	service.pushLock.Lock()
+	defer service.pushLock.Unlock()
+	if service.pushUnsubscribe != nil {
+		service.pushUnsubscribe()
+		service.pushUnsubscribe = nil
+	}
+	service.pushBroker = nil
+	if broker == nil {
+		return nil
+	}
+	if service.pushNode == "" {
+		service.pushNode = util.UUIDv4()
+	}
+	unsubscribe, err := broker.Subscribe(service.receivePush)
+	if err != nil {
+		return err
+	}
+	service.pushBroker = broker
+	service.pushUnsubscribe = unsubscribe
+	return nil
+}
+
+func firePushBrokerErrorEvent(
+	topic string,
+	err error,
+	service *baseService) {
+	defer recover()
+	if event, ok := service.Event.(pushBrokerErrorEvent); ok {
+		event.OnPushBrokerError(topic, err)
+	}
+}
+
+// publishPush publishes the message to the other service nodes
+func (service *baseService) publishPush(
+	topic string, ids []string, result interface{}) {
+	service.pushLock.RLock()
+	broker := service.pushBroker
+	node := service.pushNode
+	service.pushLock.RUnlock()
+	if broker == nil {
+		return
+	}
+	message := &PushMessage{Node: node, Topic: topic, IDs: ids, Result: result}
+	if err := broker.Publish(message); err != nil {
+		firePushBrokerErrorEvent(topic, err, service)
+	}
+}
+
+// receivePush pushes the message of the other service nodes to the clients
+// on this node
+func (service *baseService) receivePush(message *PushMessage) {
+	service.pushLock.RLock()
+	node := service.pushNode
+	service.pushLock.RUnlock()
+	if message.Node == node {
+		return
+	}
+	topics := service.matchTopics(message.Topic)
+	for _, target := range pushTargets(topics, message.IDs) {
+		service.unicast(
+			target.t, message.Topic, target.id, message.Result, nil)
+	}
+}
+
+type pushTarget struct {
+	t  *topic
+	id string
+}
+
+func pushTargets(topics []*topic, ids []string) (targets []pushTarget) {
+	for _, t := range topics {
+		list := ids
+		if list == nil {
+			list = t.idlist()
+		}
+		for _, id := range list {
+			targets = append(targets, pushTarget{t, id})
+		}
+	}
+	return
 }
 
 // Push result to clients
 func (service *baseService) Push(topic string, result interface{}, id ...string) {
-	t := service.getTopic(topic)
-	n := len(id)
-	if n == 0 {
-		id = t.idlist()
-		n = len(id)
-		if n == 0 {
-			return
-		}
+	topics := service.pushTopics(topic)
+	if len(id) == 0 {
+		id = nil
 	}
-	for i := 0; i < n; i++ {
-		service.unicast(t, topic, id[i], result, nil)
+	service.publishPush(topic, id, result)
+	for _, target := range pushTargets(topics, id) {
+		service.unicast(target.t, topic, target.id, result, nil)
 	}
 }
 
 // Broadcast push result to all clients
 func (service *baseService) Broadcast(topic string, result interface{}, callback func([]string)) {
-	service.Multicast(topic, service.IDList(topic), result, callback)
+	topics := service.pushTopics(topic)
+	service.publishPush(topic, nil, result)
+	service.multicast(pushTargets(topics, nil), topic, result, callback)
 }
 
 // Multicast result to the specified clients
 func (service *baseService) Multicast(topic string, ids []string, result interface{}, callback func([]string)) {
-	t := service.getTopic(topic)
-	m := 0
-	n := len(ids)
+	topics := service.pushTopics(topic)
+	if len(ids) == 0 {
+		ids = []string{}
+	} else {
+		service.publishPush(topic, ids, result)
+	}
+	service.multicast(pushTargets(topics, ids), topic, result, callback)
+}
+
+func (service *baseService) multicast(targets []pushTarget,
+	topic string, result interface{}, callback func([]string)) {
+	var m int32
+	n := len(targets)
+	if callback == nil {
+		for _, target := range targets {
+			service.unicast(target.t, topic, target.id, result, nil)
+		}
+		return
+	}
 	if n == 0 {
 		callback(nil)
 		return
@@ -685,14 +967,13 @@ func (service *baseService) Multicast(topic string, ids []string, result interfa
 		}
 		callback(sended)
 	}()
-	for i := 0; i < n; i++ {
-		id := ids[i]
-		service.unicast(t, topic, id, result, func(ok bool) {
+	for _, target := range targets {
+		id := target.id
+		service.unicast(target.t, topic, id, result, func(ok bool) {
 			if ok {
 				sid <- id
 			}
-			m++
-			if m == n {
+			if atomic.AddInt32(&m, 1) == int32(n) {
 				close(sid)
 			}
 		})
@@ -702,5 +983,15 @@ func (service *baseService) Multicast(topic string, ids []string, result interfa
 // Unicast result to then specified client
 func (service *baseService) Unicast(
 	topic string, id string, result interface{}, callback func(bool)) {
-	service.unicast(service.getTopic(topic), topic, id, result, callback)
+	topics := service.pushTopics(topic)
+	service.publishPush(topic, []string{id}, result)
+	if len(topics) == 1 {
+		service.unicast(topics[0], topic, id, result, callback)
+		return
+	}
+	var cb func([]string)
+	if callback != nil {
+		cb = func(sended []string) { callback(len(sended) > 0) }
+	}
+	service.multicast(pushTargets(topics, []string{id}), topic, result, cb)
 }
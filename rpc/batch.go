@@ -0,0 +1,225 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/batch.go                                           *
+ *                                                        *
+ * hprose client batch invocation for Go.                 *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	gocontext "context"
+	"errors"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// BatchResult is the result of a call in the batch
+type BatchResult struct {
+	Results []reflect.Value
+	Err     error
+}
+
+type batchCall struct {
+	name     string
+	args     []reflect.Value
+	settings *InvokeSettings
+	callback Callback
+}
+
+// Batch queues the remote calls and sends them in one request when Flush is
+// called.
+//
+// Every call is encoded and decoded with its own settings, but the request is
+// sent with the Settings of the batch, so Timeout, Retry, Idempotent,
+// Failswitch and Oneway of the call settings are ignored. The invoke handlers
+// are not called for the calls in the batch, the filters and the filter
+// handlers work on the batch request.
+//
+// If the Codec of the client is not a BatchCodec, the calls are invoked one
+// by one when Flush is called.
+type Batch struct {
+	Settings *InvokeSettings
+	client   *baseClient
+	calls    []batchCall
+	locker   sync.Mutex
+}
+
+// Batch returns a new batch of the client
+func (client *baseClient) Batch() *Batch {
+	return &Batch{client: client}
+}
+
+// Len returns the count of the queued calls
+func (batch *Batch) Len() int {
+	batch.locker.Lock()
+	defer batch.locker.Unlock()
+	return len(batch.calls)
+}
+
+// Invoke queues the remote call, it returns the index of the call in the
+// results of Flush.
+func (batch *Batch) Invoke(
+	name string, args []reflect.Value, settings *InvokeSettings) int {
+	return batch.Go(name, args, nil, settings)
+}
+
+// Go queues the remote call with the callback which is called when the batch
+// is flushed, it returns the index of the call in the results of Flush.
+func (batch *Batch) Go(
+	name string, args []reflect.Value,
+	callback Callback, settings *InvokeSettings) int {
+	batch.locker.Lock()
+	defer batch.locker.Unlock()
+	batch.calls = append(batch.calls, batchCall{name, args, settings, callback})
+	return len(batch.calls) - 1
+}
+
+// UseService build a remote service proxy object with namespace, the calls of
+// the proxy object are queued in the batch.
+//
+// The synchronous methods return the zero values, the results are returned by
+// Flush. The asynchronous methods (with callback) are called back by Flush.
+func (batch *Batch) UseService(
+	remoteService interface{}, namespace ...string) {
+	ns := ""
+	if len(namespace) == 1 {
+		ns = namespace[0]
+	}
+	v := reflect.ValueOf(remoteService)
+	if v.Kind() != reflect.Ptr {
+		panic("UseService: remoteService argument must be a pointer")
+	}
+	buildRemoteService(batch.client, batch, v, ns)
+}
+
+// Flush sends the queued calls in one request and returns their results
+func (batch *Batch) Flush() ([]BatchResult, error) {
+	return batch.FlushContext(gocontext.Background())
+}
+
+// FlushContext sends the queued calls in one request with ctx and returns
+// their results. The returned error is the error of sending the request, it
+// is also the error of every call.
+func (batch *Batch) FlushContext(
+	ctx gocontext.Context) (results []BatchResult, err error) {
+	if ctx == nil {
+		ctx = gocontext.Background()
+	}
+	batch.locker.Lock()
+	calls := batch.calls
+	batch.calls = nil
+	batch.locker.Unlock()
+	if len(calls) == 0 {
+		return nil, nil
+	}
+	client := batch.client
+	results = make([]BatchResult, len(calls))
+	if codec, ok := client.codec.(BatchCodec); ok {
+		err = batch.send(ctx, codec, calls, results)
+	} else {
+		for i, call := range calls {
+			results[i].Results, results[i].Err = client.InvokeContext(
+				ctx, call.name, call.args, call.settings)
+		}
+	}
+	for i, call := range calls {
+		if call.callback != nil {
+			func() {
+				defer client.fireErrorEvent(call.name, nil)
+				call.callback(results[i].Results, results[i].Err)
+			}()
+		}
+	}
+	return results, err
+}
+
+func (batch *Batch) send(
+	ctx gocontext.Context,
+	codec BatchCodec,
+	calls []batchCall,
+	results []BatchResult) error {
+	client := batch.client
+	contexts := make([]*ClientContext, len(calls))
+	requests := make([][]byte, 0, len(calls))
+	indexes := make([]int, 0, len(calls))
+	for i, call := range calls {
+		context := client.acquireContext()
+		client.initClientContext(context, call.settings)
+		context.ctx = ctx
+		context.name = call.name
+		context.args = call.args
+		context.Oneway = false
+		contexts[i] = context
+		request, err := codec.EncodeRequest(call.name, call.args, context)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		requests = append(requests, request)
+		indexes = append(indexes, i)
+	}
+	defer func() {
+		for _, context := range contexts {
+			client.releaseContext(context)
+		}
+	}()
+	if len(requests) == 0 {
+		return nil
+	}
+	context := client.acquireContext()
+	defer client.releaseContext(context)
+	client.initClientContext(context, batch.Settings)
+	context.ctx = ctx
+	context.start = time.Now()
+	request, err := codec.EncodeBatch(requests, context)
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	var response []byte
+	if err == nil {
+		response, err = client.sendRequest(request, context)
+	}
+	var responses [][]byte
+	if err == nil && !context.Oneway {
+		responses, err = codec.SplitResponse(response, len(requests), context)
+	}
+	if err != nil {
+		for _, i := range indexes {
+			results[i].Err = err
+		}
+		return err
+	}
+	if context.Oneway {
+		return nil
+	}
+	// the service may stop at an error, so the results of the rest calls
+	// are missing, it is not an error of sending the request.
+	missing := errors.New(
+		"Wrong Response: missing the result of the batch call")
+	for j, i := range indexes {
+		if j < len(responses) {
+			results[i].Results, results[i].Err = codec.DecodeResponse(
+				responses[j], calls[i].args, contexts[i])
+		} else {
+			results[i].Err = missing
+		}
+		if results[i].Results == nil {
+			results[i].Results = zeroResults(contexts[i].ResultTypes)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/batch_test.go                                      *
+ *                                                        *
+ * hprose client batch invocation test for Go.            *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+type batchStub struct {
+	Hello   func(string) (string, error)
+	Sum     func(...int) int
+	HelloCB func(func(string, error), string) `name:"hello"`
+	Fail    func() error
+	Swap    func(a *int, b *int) `byref:"true"`
+}
+
+func addBatchFunctions(service contextService) {
+	service.AddFunction("hello", func(s string) string {
+		return "Hello " + s
+	}, Options{})
+	service.AddFunction("sum", func(a ...int) (n int) {
+		for _, x := range a {
+			n += x
+		}
+		return
+	}, Options{})
+	service.AddFunction("fail", func() error {
+		return errors.New("fail!")
+	}, Options{})
+	service.AddFunction("swap", func(a *int, b *int) {
+		*a, *b = *b, *a
+	}, Options{})
+}
+
+func testBatch(t *testing.T, name string, client Client) {
+	batch := client.Batch()
+	var stub *batchStub
+	batch.UseService(&stub)
+	if s, err := stub.Hello("a"); s != "" || err != nil {
+		t.Error(name, s, err)
+	}
+	stub.Sum(1, 2, 3)
+	var result string
+	stub.HelloCB(func(s string, err error) { result = s }, "callback")
+	stub.Fail()
+	i := batch.Invoke("hello", []reflect.Value{reflect.ValueOf("direct")},
+		&InvokeSettings{ResultTypes: []reflect.Type{stringType}})
+	if batch.Len() != 5 {
+		t.Error(name, batch.Len())
+	}
+	results, err := batch.Flush()
+	if err != nil {
+		t.Fatal(name, err)
+	}
+	if len(results) != 5 || results[0].Results[0].String() != "Hello a" ||
+		results[1].Results[0].Int() != 6 || result != "Hello callback" ||
+		results[3].Err == nil || results[3].Err.Error() != "fail!" ||
+		results[i].Results[0].String() != "Hello direct" {
+		t.Error(name, results, result)
+	}
+	if results, err = batch.Flush(); results != nil || err != nil {
+		t.Error(name, "the batch is not empty", results, err)
+	}
+	stub.Hello("one")
+	results, err = batch.Flush()
+	if err != nil || results[0].Results[0].String() != "Hello one" {
+		t.Error(name, results, err)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	for name, codec := range testCodecs {
+		service := NewHTTPService()
+		service.Codec = codec
+		addBatchFunctions(service)
+		server := httptest.NewServer(service)
+		client := NewHTTPClient(server.URL)
+		client.SetCodec(codec)
+		testBatch(t, name, client)
+		server.Close()
+	}
+	server := NewTCPServer("tcp://127.0.0.1:0")
+	addBatchFunctions(server)
+	server.Handle()
+	defer server.Close()
+	client := NewTCPClient(server.URI())
+	defer client.Close()
+	testBatch(t, "tcp", client)
+}
+
+// truncatedCodec drops the last response of the batch
+type truncatedCodec struct {
+	JSONCodec
+}
+
+func (codec truncatedCodec) SplitResponse(
+	response []byte, count int, context *ClientContext) ([][]byte, error) {
+	responses, err := codec.JSONCodec.SplitResponse(response, count, context)
+	if len(responses) > 0 {
+		responses = responses[:len(responses)-1]
+	}
+	return responses, err
+}
+
+func TestBatchMissingResults(t *testing.T) {
+	service := NewHTTPService()
+	service.Codec = JSONCodec{}
+	addBatchFunctions(service)
+	server := httptest.NewServer(service)
+	defer server.Close()
+	client := NewHTTPClient(server.URL)
+	client.SetCodec(truncatedCodec{})
+	batch := client.Batch()
+	settings := &InvokeSettings{ResultTypes: []reflect.Type{stringType}}
+	batch.Invoke("fail", nil, nil)
+	batch.Invoke("hello", []reflect.Value{reflect.ValueOf("a")}, settings)
+	batch.Invoke("hello", []reflect.Value{reflect.ValueOf("b")}, settings)
+	results, err := batch.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Err == nil || results[0].Err.Error() != "fail!" {
+		t.Error(results[0].Err)
+	}
+	if results[1].Err != nil || results[1].Results[0].String() != "Hello a" {
+		t.Error(results[1])
+	}
+	if results[2].Err == nil || results[2].Err.Error() !=
+		"Wrong Response: missing the result of the batch call" {
+		t.Error(results[2].Err)
+	}
+	if len(results[2].Results) != 1 || results[2].Results[0].String() != "" {
+		t.Error(results[2].Results)
+	}
+}
+
+func TestBatchSendError(t *testing.T) {
+	server := httptest.NewServer(NewHTTPService())
+	uri := server.URL
+	server.Close()
+	client := NewHTTPClient(uri)
+	client.SetRetry(0)
+	batch := client.Batch()
+	batch.Invoke("hello", []reflect.Value{reflect.ValueOf("a")}, nil)
+	batch.Invoke("hello", []reflect.Value{reflect.ValueOf("b")}, nil)
+	results, err := batch.Flush()
+	if err == nil {
+		t.Fatal("expected the send error")
+	}
+	for i, result := range results {
+		if result.Err != err {
+			t.Error(i, result.Err)
+		}
+	}
+}
+
+func TestCodecBatch(t *testing.T) {
+	for name, c := range testCodecs {
+		codec := c.(BatchCodec)
+		context := new(ClientContext)
+		context.initBaseContext()
+		requests := make([][]byte, 2)
+		for i := range requests {
+			requests[i], _ = codec.EncodeRequest(
+				"inc", []reflect.Value{reflect.ValueOf(i)}, context)
+		}
+		request, err := codec.EncodeBatch(requests, context)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		serviceContext := newTestServiceContext(func(int) int { return 0 })
+		calls, err := codec.DecodeRequest(request, serviceContext)
+		if err != nil || len(calls) != 2 {
+			t.Fatal(name, calls, err)
+		}
+		responses := make([][]byte, 2)
+		for i, call := range calls {
+			args, err := decodeTestArgs(call, serviceContext.Method())
+			if err != nil || args[0].Int() != int64(i) {
+				t.Fatal(name, args, err)
+			}
+			result := reflect.ValueOf(int(args[0].Int()) + 1)
+			responses[i] = codec.EncodeResult(
+				args, []reflect.Value{result}, serviceContext)
+		}
+		responses[1] = codec.EncodeError("failed", serviceContext)
+		response := codec.MergeResponse(responses, serviceContext)
+		split, err := codec.SplitResponse(response, 2, context)
+		if err != nil || len(split) != 2 {
+			t.Fatal(name, split, err)
+		}
+		context.ResultTypes = []reflect.Type{reflect.TypeOf(0)}
+		results, err := codec.DecodeResponse(split[0], nil, context)
+		if err != nil || results[0].Int() != 1 {
+			t.Error(name, results, err)
+		}
+		if _, err = codec.DecodeResponse(split[1], nil, context); err == nil {
+			t.Error(name, "expected error")
+		}
+	}
+}
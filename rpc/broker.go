@@ -0,0 +1,102 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/broker.go                                          *
+ *                                                        *
+ * hprose push broker for Go.                             *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import "sync"
+
+// PushMessage is a message routed by PushBroker.
+//
+// Node is the id of the service node which publishes the message, Topic is
+// the push topic, IDs is the client ids, or nil for all the clients of the
+// topic, and Result is the pushed result.
+type PushMessage struct {
+	Node   string
+	Topic  string
+	IDs    []string
+	Result interface{}
+}
+
+// PushBroker routes the pushed messages between the service nodes, so that
+// the messages pushed on any node reach the clients on every node.
+//
+// Publish sends the message to the handlers of all the nodes, and Subscribe
+// adds a handler which receives the published messages, it returns the
+// function to remove the handler.
+type PushBroker interface {
+	Publish(message *PushMessage) error
+	Subscribe(handler func(message *PushMessage)) (unsubscribe func(), err error)
+}
+
+type pushHandlers struct {
+	handlers map[int]func(message *PushMessage)
+	next     int
+	locker   sync.RWMutex
+}
+
+func (ph *pushHandlers) add(handler func(message *PushMessage)) func() {
+	ph.locker.Lock()
+	if ph.handlers == nil {
+		ph.handlers = make(map[int]func(message *PushMessage))
+	}
+	key := ph.next
+	ph.next++
+	ph.handlers[key] = handler
+	ph.locker.Unlock()
+	return func() {
+		ph.locker.Lock()
+		delete(ph.handlers, key)
+		ph.locker.Unlock()
+	}
+}
+
+func (ph *pushHandlers) dispatch(message *PushMessage) {
+	ph.locker.RLock()
+	handlers := make([]func(message *PushMessage), 0, len(ph.handlers))
+	for _, handler := range ph.handlers {
+		handlers = append(handlers, handler)
+	}
+	ph.locker.RUnlock()
+	for _, handler := range handlers {
+		handler(message)
+	}
+}
+
+// MemoryPushBroker is an in-process PushBroker, it routes the messages
+// between the services in the same process.
+type MemoryPushBroker struct {
+	handlers pushHandlers
+}
+
+// NewMemoryPushBroker is the constructor of MemoryPushBroker
+func NewMemoryPushBroker() *MemoryPushBroker {
+	return new(MemoryPushBroker)
+}
+
+// Publish the message to all the handlers
+func (broker *MemoryPushBroker) Publish(message *PushMessage) error {
+	broker.handlers.dispatch(message)
+	return nil
+}
+
+// Subscribe the published messages
+func (broker *MemoryPushBroker) Subscribe(
+	handler func(message *PushMessage)) (func(), error) {
+	return broker.handlers.add(handler), nil
+}
@@ -0,0 +1,182 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/cache.go                                           *
+ *                                                        *
+ * hprose client response cache for Go.                   *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"container/list"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheStore stores the responses cached by ResponseCache.
+//
+// name is the method name in lower case, key is the encoded request of the
+// invoking. Set should store a copy of response which expires after ttl.
+// Remove removes all the responses of the method name, or all the responses
+// if name is empty.
+type CacheStore interface {
+	Get(name string, key string) (response []byte, ok bool)
+	Set(name string, key string, response []byte, ttl time.Duration)
+	Remove(name string)
+}
+
+// ResponseCache caches the responses of the invokings with the Cache setting
+// (the cache tag of the stub field, for example `cache:"30s"`), the invokings
+// with the same method name and arguments return the cached response until it
+// expires. The response is decoded for every invoking with its own settings.
+//
+// The error responses are not cached, and the invokings which are oneway or
+// byref are not cached.
+//
+// Usage:
+//
+//	client.AddInvokeHandler(rpc.NewResponseCache().Handler)
+type ResponseCache struct {
+	Store CacheStore
+}
+
+// NewResponseCache is the constructor of ResponseCache, the default store is
+// a MemoryCacheStore with 1024 responses at most.
+func NewResponseCache() *ResponseCache {
+	return &ResponseCache{Store: NewMemoryCacheStore(1024)}
+}
+
+// Handler is the invoke handler of ResponseCache
+func (rc *ResponseCache) Handler(
+	name string,
+	args []reflect.Value,
+	context Context,
+	next NextInvokeHandler) (results []reflect.Value, err error) {
+	if context, ok := context.(*ClientContext); ok {
+		context.cache = rc
+	}
+	return next(name, args, context)
+}
+
+// Invalidate removes the cached responses of the method name
+func (rc *ResponseCache) Invalidate(name string) {
+	if name != "" {
+		rc.Store.Remove(strings.ToLower(name))
+	}
+}
+
+// Clear removes all the cached responses
+func (rc *ResponseCache) Clear() {
+	rc.Store.Remove("")
+}
+
+func (rc *ResponseCache) get(name string, key string) ([]byte, bool) {
+	return rc.Store.Get(strings.ToLower(name), key)
+}
+
+func (rc *ResponseCache) set(
+	name string, key string, response []byte, ttl time.Duration) {
+	rc.Store.Set(strings.ToLower(name), key, response, ttl)
+}
+
+type cacheEntry struct {
+	name     string
+	key      string
+	response []byte
+	expires  time.Time
+}
+
+// MemoryCacheStore is an in-memory CacheStore, the least recently used
+// responses are removed when there are more than Size responses.
+type MemoryCacheStore struct {
+	Size    int
+	entries map[string]*list.Element
+	lru     *list.List
+	locker  sync.Mutex
+}
+
+// NewMemoryCacheStore is the constructor of MemoryCacheStore
+func NewMemoryCacheStore(size int) *MemoryCacheStore {
+	return &MemoryCacheStore{
+		Size:    size,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+func (store *MemoryCacheStore) remove(element *list.Element) {
+	entry := store.lru.Remove(element).(*cacheEntry)
+	delete(store.entries, entry.name+"\x00"+entry.key)
+}
+
+// Get the response
+func (store *MemoryCacheStore) Get(name string, key string) ([]byte, bool) {
+	store.locker.Lock()
+	defer store.locker.Unlock()
+	element := store.entries[name+"\x00"+key]
+	if element == nil {
+		return nil, false
+	}
+	entry := element.Value.(*cacheEntry)
+	if !time.Now().Before(entry.expires) {
+		store.remove(element)
+		return nil, false
+	}
+	store.lru.MoveToFront(element)
+	return entry.response, true
+}
+
+// Set the response
+func (store *MemoryCacheStore) Set(
+	name string, key string, response []byte, ttl time.Duration) {
+	entry := &cacheEntry{
+		name:     name,
+		key:      key,
+		response: append([]byte(nil), response...),
+		expires:  time.Now().Add(ttl),
+	}
+	store.locker.Lock()
+	defer store.locker.Unlock()
+	if element := store.entries[name+"\x00"+key]; element != nil {
+		element.Value = entry
+		store.lru.MoveToFront(element)
+		return
+	}
+	store.entries[name+"\x00"+key] = store.lru.PushFront(entry)
+	for store.Size > 0 && store.lru.Len() > store.Size {
+		store.remove(store.lru.Back())
+	}
+}
+
+// Remove the responses of the method name, or all the responses if name is
+// empty
+func (store *MemoryCacheStore) Remove(name string) {
+	store.locker.Lock()
+	defer store.locker.Unlock()
+	if name == "" {
+		store.entries = make(map[string]*list.Element)
+		store.lru.Init()
+		return
+	}
+	for element := store.lru.Front(); element != nil; {
+		next := element.Next()
+		if element.Value.(*cacheEntry).name == name {
+			store.remove(element)
+		}
+		element = next
+	}
+}
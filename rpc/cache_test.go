@@ -0,0 +1,213 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/cache_test.go                                      *
+ *                                                        *
+ * hprose client response cache test for Go.              *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"errors"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func checkCached(t *testing.T, store CacheStore, name, key string, want string) {
+	t.Helper()
+	response, ok := store.Get(name, key)
+	if want == "" {
+		if ok {
+			t.Error(name, key, "is cached")
+		}
+	} else if !ok || string(response) != want {
+		t.Error(name, key, string(response), ok)
+	}
+}
+
+func TestMemoryCacheStoreLRU(t *testing.T) {
+	store := NewMemoryCacheStore(2)
+	store.Set("a", "1", []byte("a1"), time.Minute)
+	store.Set("a", "2", []byte("a2"), time.Minute)
+	// a1 is used, so a2 is the least recently used
+	checkCached(t, store, "a", "1", "a1")
+	store.Set("b", "1", []byte("b1"), time.Minute)
+	checkCached(t, store, "a", "2", "")
+	checkCached(t, store, "a", "1", "a1")
+	checkCached(t, store, "b", "1", "b1")
+	// setting the existing response does not remove the others
+	store.Set("a", "1", []byte("A1"), time.Minute)
+	checkCached(t, store, "a", "1", "A1")
+	checkCached(t, store, "b", "1", "b1")
+	if store.lru.Len() != 2 || len(store.entries) != 2 {
+		t.Error(store.lru.Len(), len(store.entries))
+	}
+	// the name and the key are not mixed up
+	store.Set("a\x001", "", []byte("x"), time.Minute)
+	store.Set("a", "1\x00", []byte("y"), time.Minute)
+	checkCached(t, store, "a", "1\x00", "y")
+}
+
+func TestMemoryCacheStoreTTL(t *testing.T) {
+	store := NewMemoryCacheStore(0)
+	response := []byte("a1")
+	store.Set("a", "1", response, 20*time.Millisecond)
+	store.Set("a", "2", []byte("a2"), time.Minute)
+	// the store keeps a copy of the response
+	response[0] = 'x'
+	checkCached(t, store, "a", "1", "a1")
+	time.Sleep(30 * time.Millisecond)
+	checkCached(t, store, "a", "1", "")
+	checkCached(t, store, "a", "2", "a2")
+	if store.lru.Len() != 1 {
+		t.Error("the expired response is not removed", store.lru.Len())
+	}
+	// no limit when Size is 0
+	for i := 0; i < 2000; i++ {
+		store.Set("b", string(rune(i)), []byte("b"), time.Minute)
+	}
+	if store.lru.Len() != 2001 {
+		t.Error(store.lru.Len())
+	}
+}
+
+func TestMemoryCacheStoreRemove(t *testing.T) {
+	store := NewMemoryCacheStore(10)
+	store.Set("a", "1", []byte("a1"), time.Minute)
+	store.Set("a", "2", []byte("a2"), time.Minute)
+	store.Set("b", "1", []byte("b1"), time.Minute)
+	store.Remove("a")
+	checkCached(t, store, "a", "1", "")
+	checkCached(t, store, "a", "2", "")
+	checkCached(t, store, "b", "1", "b1")
+	store.Remove("")
+	checkCached(t, store, "b", "1", "")
+	if store.lru.Len() != 0 || len(store.entries) != 0 {
+		t.Error(store.lru.Len(), len(store.entries))
+	}
+}
+
+type cacheStub struct {
+	Sum        func(int, int) (int, error)    `name:"sum" cache:"200ms"`
+	SumString  func(int, int) (string, error) `name:"sum" cache:"200ms"`
+	SumNoCache func(int, int) (int, error)    `name:"sum"`
+	Fail       func() (int, error)            `cache:"1s"`
+	FailRaw    func() ([]byte, error)         `name:"fail" cache:"1s" result:"raw"`
+	FailEnd    func() ([]byte, error)         `name:"fail" cache:"1s" result:"rawwithendtag"`
+	SumRaw     func(int, int) ([]byte, error) `name:"sum" cache:"1s" result:"raw"`
+}
+
+func addCacheFunctions(service contextService, calls *int32) {
+	service.AddFunction("sum", func(a, b int) int {
+		atomic.AddInt32(calls, 1)
+		return a + b
+	}, Options{})
+	service.AddFunction("fail", func() (int, error) {
+		atomic.AddInt32(calls, 1)
+		return 0, errors.New("fail")
+	}, Options{})
+}
+
+func checkCalls(t *testing.T, calls *int32, want int32) {
+	t.Helper()
+	if n := atomic.SwapInt32(calls, 0); n != want {
+		t.Errorf("%d calls, want %d", n, want)
+	}
+}
+
+func TestResponseCache(t *testing.T) {
+	var calls int32
+	server := NewTCPServer("tcp://127.0.0.1:0")
+	addCacheFunctions(server, &calls)
+	server.Handle()
+	defer server.Close()
+	client := NewTCPClient(server.URI())
+	defer client.Close()
+	cache := NewResponseCache()
+	client.AddInvokeHandler(cache.Handler)
+	var stub *cacheStub
+	client.UseService(&stub)
+	for i := 0; i < 5; i++ {
+		if n, err := stub.Sum(1, 2); n != 3 || err != nil {
+			t.Error(n, err)
+		}
+		// the cached response is decoded with the settings of the invoking
+		if s, err := stub.SumString(1, 2); s != "3" || err != nil {
+			t.Error(s, err)
+		}
+	}
+	checkCalls(t, &calls, 1)
+	stub.SumNoCache(1, 2)
+	stub.Sum(2, 2)
+	checkCalls(t, &calls, 2)
+	cache.Invalidate("Sum")
+	stub.Sum(1, 2)
+	checkCalls(t, &calls, 1)
+	time.Sleep(250 * time.Millisecond)
+	stub.Sum(1, 2)
+	checkCalls(t, &calls, 1)
+	cache.Clear()
+	stub.Sum(1, 2)
+	checkCalls(t, &calls, 1)
+	cache.Store = NewMemoryCacheStore(2)
+	stub.Sum(1, 1)
+	stub.Sum(1, 2)
+	stub.Sum(1, 1)
+	stub.Sum(1, 3)
+	checkCalls(t, &calls, 3)
+	stub.Sum(1, 1)
+	stub.Sum(1, 3)
+	stub.Sum(1, 2)
+	checkCalls(t, &calls, 1)
+}
+
+// TestResponseCacheError checks that the error responses are not cached,
+// even if they are not decoded as errors in Raw or RawWithEndTag mode.
+func TestResponseCacheError(t *testing.T) {
+	for name, codec := range testCodecs {
+		var calls int32
+		service := NewHTTPService()
+		service.Codec = codec
+		addCacheFunctions(service, &calls)
+		server := httptest.NewServer(service)
+		client := NewHTTPClient(server.URL)
+		client.SetCodec(codec)
+		client.AddInvokeHandler(NewResponseCache().Handler)
+		var stub *cacheStub
+		client.UseService(&stub)
+		for i := 0; i < 2; i++ {
+			if _, err := stub.Fail(); err == nil || err.Error() != "fail" {
+				t.Error(name, err)
+			}
+			if _, err := stub.FailRaw(); err != nil {
+				t.Error(name, err)
+			}
+			if _, err := stub.FailEnd(); err != nil {
+				t.Error(name, err)
+			}
+		}
+		checkCalls(t, &calls, 6)
+		for i := 0; i < 3; i++ {
+			if data, err := stub.SumRaw(1, 2); len(data) == 0 || err != nil {
+				t.Error(name, data, err)
+			}
+		}
+		checkCalls(t, &calls, 1)
+		client.Close()
+		server.Close()
+	}
+}
@@ -0,0 +1,228 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/circuit_breaker.go                                 *
+ *                                                        *
+ * hprose client circuit breaker for Go.                  *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"sync"
+	"time"
+)
+
+// CircuitState is the state of the circuit of a service address
+type CircuitState int
+
+const (
+	// CircuitClosed means the requests are sent normally
+	CircuitClosed CircuitState = iota
+	// CircuitOpen means the service address is skipped
+	CircuitOpen
+	// CircuitHalfOpen means only the trial requests are sent
+	CircuitHalfOpen
+)
+
+func (state CircuitState) String() string {
+	switch state {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+type circuit struct {
+	state       CircuitState
+	failures    int
+	total       int
+	errors      int
+	windowStart time.Time
+	openedAt    time.Time
+	trials      int
+	successes   int
+}
+
+func (c *circuit) open(now time.Time) {
+	c.state = CircuitOpen
+	c.openedAt = now
+}
+
+func (c *circuit) close(now time.Time) {
+	c.state = CircuitClosed
+	c.failures = 0
+	c.total = 0
+	c.errors = 0
+	c.windowStart = now
+}
+
+// CircuitBreaker keeps a circuit for every service address of the client.
+//
+// The circuit of a service address is opened when the send errors reach
+// ConsecutiveFailures in a row, or when the error rate reaches ErrorRate
+// after at least MinRequests requests in Window. The opened service address
+// is skipped when selecting the service address. After OpenTimeout, the
+// circuit becomes half-open and HalfOpenRequests trial requests are sent,
+// the circuit is closed if all of them succeed, otherwise it is opened again.
+//
+// Only the errors of sending requests (for example, the connection or timeout
+// errors) are counted, the errors returned by the remote methods are not.
+type CircuitBreaker struct {
+	ConsecutiveFailures int
+	ErrorRate           float64
+	MinRequests         int
+	Window              time.Duration
+	OpenTimeout         time.Duration
+	HalfOpenRequests    int
+	circuits            map[string]*circuit
+	locker              sync.Mutex
+}
+
+// NewCircuitBreaker is the constructor of CircuitBreaker
+func NewCircuitBreaker() *CircuitBreaker {
+	return &CircuitBreaker{
+		ConsecutiveFailures: 5,
+		ErrorRate:           0.5,
+		MinRequests:         20,
+		Window:              10 * time.Second,
+		OpenTimeout:         30 * time.Second,
+		HalfOpenRequests:    1,
+		circuits:            make(map[string]*circuit),
+	}
+}
+
+func (cb *CircuitBreaker) getCircuit(uri string) *circuit {
+	if cb.circuits == nil {
+		cb.circuits = make(map[string]*circuit)
+	}
+	c := cb.circuits[uri]
+	if c == nil {
+		c = &circuit{windowStart: time.Now()}
+		cb.circuits[uri] = c
+	}
+	return c
+}
+
+func (cb *CircuitBreaker) halfOpenRequests() int {
+	if cb.HalfOpenRequests < 1 {
+		return 1
+	}
+	return cb.HalfOpenRequests
+}
+
+// State returns the circuit state of uri
+func (cb *CircuitBreaker) State(uri string) CircuitState {
+	cb.locker.Lock()
+	defer cb.locker.Unlock()
+	c := cb.circuits[uri]
+	if c == nil {
+		return CircuitClosed
+	}
+	if c.state == CircuitOpen && time.Since(c.openedAt) >= cb.OpenTimeout {
+		return CircuitHalfOpen
+	}
+	return c.state
+}
+
+// Reset closes the circuit of uri
+func (cb *CircuitBreaker) Reset(uri string) {
+	cb.locker.Lock()
+	delete(cb.circuits, uri)
+	cb.locker.Unlock()
+}
+
+// allow reports whether a request can be sent to uri, changed is true when
+// the circuit becomes half-open.
+func (cb *CircuitBreaker) allow(uri string) (ok bool, changed bool) {
+	cb.locker.Lock()
+	defer cb.locker.Unlock()
+	c := cb.getCircuit(uri)
+	switch c.state {
+	case CircuitOpen:
+		if time.Since(c.openedAt) < cb.OpenTimeout {
+			return false, false
+		}
+		c.state = CircuitHalfOpen
+		c.trials = 0
+		c.successes = 0
+		changed = true
+		fallthrough
+	case CircuitHalfOpen:
+		if c.trials >= cb.halfOpenRequests() {
+			return false, changed
+		}
+		c.trials++
+		return true, changed
+	}
+	return true, false
+}
+
+// release gives back the trial of the half-open circuit of uri without
+// counting the request, it is used when the request is canceled.
+func (cb *CircuitBreaker) release(uri string) {
+	cb.locker.Lock()
+	if c := cb.circuits[uri]; c != nil && c.state == CircuitHalfOpen {
+		c.trials--
+	}
+	cb.locker.Unlock()
+}
+
+// record counts the result of a request sent to uri and returns the circuit
+// state, changed is true when the state is changed.
+func (cb *CircuitBreaker) record(
+	uri string, err error) (state CircuitState, changed bool) {
+	cb.locker.Lock()
+	defer cb.locker.Unlock()
+	c := cb.getCircuit(uri)
+	now := time.Now()
+	switch c.state {
+	case CircuitOpen:
+		return c.state, false
+	case CircuitHalfOpen:
+		if err != nil {
+			c.open(now)
+			return c.state, true
+		}
+		c.successes++
+		if c.successes >= cb.halfOpenRequests() {
+			c.close(now)
+			return c.state, true
+		}
+		return c.state, false
+	}
+	if now.Sub(c.windowStart) > cb.Window {
+		c.total = 0
+		c.errors = 0
+		c.windowStart = now
+	}
+	c.total++
+	if err == nil {
+		c.failures = 0
+		return c.state, false
+	}
+	c.errors++
+	c.failures++
+	if (cb.ConsecutiveFailures > 0 && c.failures >= cb.ConsecutiveFailures) ||
+		(cb.ErrorRate > 0 && c.total >= cb.MinRequests &&
+			float64(c.errors) >= cb.ErrorRate*float64(c.total)) {
+		c.open(now)
+		return c.state, true
+	}
+	return c.state, false
+}
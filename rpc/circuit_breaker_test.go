@@ -0,0 +1,155 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/circuit_breaker_test.go                            *
+ *                                                        *
+ * hprose client circuit breaker test for Go.             *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"testing"
+	"time"
+)
+
+const testCircuitURI = "tcp://127.0.0.1:10001"
+
+func checkCircuit(t *testing.T, cb *CircuitBreaker, want CircuitState) {
+	if state := cb.State(testCircuitURI); state != want {
+		t.Helper()
+		t.Errorf("state is %s, want %s", state, want)
+	}
+}
+
+func TestCircuitConsecutiveFailures(t *testing.T) {
+	cb := NewCircuitBreaker()
+	cb.ConsecutiveFailures = 3
+	cb.ErrorRate = 0
+	for i := 0; i < 2; i++ {
+		if _, changed := cb.record(testCircuitURI, ErrTimeout); changed {
+			t.Error(i)
+		}
+	}
+	// a success resets the consecutive failures
+	cb.record(testCircuitURI, nil)
+	cb.record(testCircuitURI, ErrTimeout)
+	cb.record(testCircuitURI, ErrTimeout)
+	checkCircuit(t, cb, CircuitClosed)
+	state, changed := cb.record(testCircuitURI, ErrTimeout)
+	if state != CircuitOpen || !changed {
+		t.Error(state, changed)
+	}
+	if ok, _ := cb.allow(testCircuitURI); ok {
+		t.Error("the open circuit allows the request")
+	}
+}
+
+func TestCircuitErrorRate(t *testing.T) {
+	cb := NewCircuitBreaker()
+	cb.ConsecutiveFailures = 0
+	cb.ErrorRate = 0.5
+	cb.MinRequests = 10
+	for i := 0; i < 9; i++ {
+		var err error
+		if i%2 == 1 {
+			err = ErrTimeout
+		}
+		cb.record(testCircuitURI, err)
+	}
+	// 4 errors of 9 requests, less than MinRequests
+	checkCircuit(t, cb, CircuitClosed)
+	cb.record(testCircuitURI, ErrTimeout)
+	checkCircuit(t, cb, CircuitOpen)
+}
+
+func TestCircuitWindow(t *testing.T) {
+	cb := NewCircuitBreaker()
+	cb.ConsecutiveFailures = 0
+	cb.MinRequests = 4
+	cb.Window = 20 * time.Millisecond
+	cb.record(testCircuitURI, ErrTimeout)
+	cb.record(testCircuitURI, ErrTimeout)
+	cb.record(testCircuitURI, ErrTimeout)
+	time.Sleep(30 * time.Millisecond)
+	// the old requests are out of the window
+	cb.record(testCircuitURI, nil)
+	checkCircuit(t, cb, CircuitClosed)
+}
+
+func TestCircuitHalfOpen(t *testing.T) {
+	cb := NewCircuitBreaker()
+	cb.ConsecutiveFailures = 1
+	cb.OpenTimeout = 20 * time.Millisecond
+	cb.HalfOpenRequests = 2
+	cb.record(testCircuitURI, ErrTimeout)
+	checkCircuit(t, cb, CircuitOpen)
+	time.Sleep(30 * time.Millisecond)
+	checkCircuit(t, cb, CircuitHalfOpen)
+	ok, changed := cb.allow(testCircuitURI)
+	if !ok || !changed {
+		t.Error(ok, changed)
+	}
+	if ok, changed = cb.allow(testCircuitURI); !ok || changed {
+		t.Error(ok, changed)
+	}
+	// only HalfOpenRequests trial requests are allowed
+	if ok, _ = cb.allow(testCircuitURI); ok {
+		t.Error("too many trial requests")
+	}
+	// the canceled trial is given back
+	cb.release(testCircuitURI)
+	if ok, _ = cb.allow(testCircuitURI); !ok {
+		t.Error("the released trial is not allowed")
+	}
+	if _, changed = cb.record(testCircuitURI, nil); changed {
+		t.Error("closed after one success")
+	}
+	state, changed := cb.record(testCircuitURI, nil)
+	if state != CircuitClosed || !changed {
+		t.Error(state, changed)
+	}
+}
+
+func TestCircuitHalfOpenFailure(t *testing.T) {
+	cb := NewCircuitBreaker()
+	cb.ConsecutiveFailures = 1
+	cb.OpenTimeout = 20 * time.Millisecond
+	cb.record(testCircuitURI, ErrTimeout)
+	time.Sleep(30 * time.Millisecond)
+	if ok, _ := cb.allow(testCircuitURI); !ok {
+		t.Fatal("the trial request is not allowed")
+	}
+	state, changed := cb.record(testCircuitURI, ErrTimeout)
+	if state != CircuitOpen || !changed {
+		t.Error(state, changed)
+	}
+	checkCircuit(t, cb, CircuitOpen)
+	cb.Reset(testCircuitURI)
+	checkCircuit(t, cb, CircuitClosed)
+}
+
+func TestCircuitStateString(t *testing.T) {
+	states := map[CircuitState]string{
+		CircuitClosed:   "closed",
+		CircuitOpen:     "open",
+		CircuitHalfOpen: "half-open",
+		CircuitState(9): "unknown",
+	}
+	for state, s := range states {
+		if state.String() != s {
+			t.Error(state.String(), s)
+		}
+	}
+}
@@ -12,7 +12,7 @@
  *                                                        *
  * hprose rpc client for Go.                              *
  *                                                        *
- * LastModified: Oct 15, 2016                             *
+ * LastModified: Oct 18, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -20,6 +20,7 @@
 package rpc
 
 import (
+	gocontext "context"
 	"crypto/tls"
 	"errors"
 	"net/url"
@@ -38,9 +39,17 @@ type InvokeSettings struct {
 	Oneway         bool
 	JSONCompatible bool
 	Retry          int
+	RetryPolicy    RetryPolicy
 	Mode           ResultMode
+	UTC            bool
+	DateOnly       bool
+	TimePrecision  time.Duration
+	Location       *time.Location
 	Timeout        time.Duration
 	ResultTypes    []reflect.Type
+	Hedge          time.Duration
+	HedgeQuantile  float64
+	Cache          time.Duration
 }
 
 // Callback is the callback function type of Client.Go
@@ -56,8 +65,25 @@ type Client interface {
 	SetTLSClientConfig(config *tls.Config)
 	Retry() int
 	SetRetry(value int)
+	RetryPolicy() RetryPolicy
+	SetRetryPolicy(policy RetryPolicy)
+	SubscribeBackoff() RetryPolicy
+	SetSubscribeBackoff(policy RetryPolicy)
 	Timeout() time.Duration
 	SetTimeout(value time.Duration)
+	Codec() Codec
+	SetCodec(codec Codec)
+	Balancer() Balancer
+	SetBalancer(balancer Balancer)
+	CircuitBreaker() *CircuitBreaker
+	SetCircuitBreaker(breaker *CircuitBreaker)
+	RateLimiter() *RateLimiter
+	SetRateLimiter(limiter *RateLimiter)
+	HealthChecker() *HealthChecker
+	SetHealthChecker(checker *HealthChecker)
+	Endpoints() []EndpointStatus
+	Resolver() Resolver
+	SetResolver(resolver Resolver)
 	Failround() int
 	SetEvent(ClientEvent)
 	Filter() Filter
@@ -70,7 +96,15 @@ type Client interface {
 	AddBeforeFilterHandler(handler ...FilterHandler) Client
 	AddAfterFilterHandler(handler ...FilterHandler) Client
 	UseService(remoteService interface{}, namespace ...string)
+	UseServiceWithOptions(
+		remoteService interface{}, options UseServiceOptions) error
+	Functions() ([]string, error)
+	RefreshFunctions() ([]string, error)
+	Batch() *Batch
 	Invoke(string, []reflect.Value, *InvokeSettings) ([]reflect.Value, error)
+	InvokeContext(gocontext.Context, string, []reflect.Value, *InvokeSettings) ([]reflect.Value, error)
+	InvokeAsync(string, []reflect.Value, *InvokeSettings) *Future
+	InvokeAsyncContext(gocontext.Context, string, []reflect.Value, *InvokeSettings) *Future
 	Go(string, []reflect.Value, Callback, *InvokeSettings)
 	Close()
 	ID() (string, error)
@@ -86,6 +120,45 @@ type ClientContext struct {
 	InvokeSettings
 	Retried int
 	Client  Client
+	ctx     gocontext.Context
+	name    string
+	args    []reflect.Value
+	uri     string
+	start   time.Time
+	hedging *hedgeURIs
+	flight  *SingleFlight
+	cache   *ResponseCache
+}
+
+// Context returns the context.Context of the invoking, it is never nil
+func (context *ClientContext) Context() gocontext.Context {
+	if context.ctx == nil {
+		return gocontext.Background()
+	}
+	return context.ctx
+}
+
+// detachedContext keeps the values of the parent context, but it is never
+// canceled and has no deadline.
+type detachedContext struct {
+	gocontext.Context
+}
+
+func (detachedContext) Deadline() (deadline time.Time, ok bool) {
+	return
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+// URI returns the service address which the request is sent to
+func (context *ClientContext) URI() string {
+	return removeWeight(context.uri)
 }
 
 // NewClient is the constructor of Client
@@ -115,7 +188,7 @@ func checkAddresses(uriList []string, schemes []string) (scheme string) {
 		panic(err)
 	}
 	scheme = u.Scheme
-	if sort.SearchStrings(schemes, scheme) == len(schemes) {
+	if i := sort.SearchStrings(schemes, scheme); i == len(schemes) || schemes[i] != scheme {
 		panic(errors.New("This client desn't support " + scheme + " scheme."))
 	}
 	for i := 1; i < count; i++ {
@@ -12,7 +12,7 @@
  *                                                        *
  * hprose client event for Go.                            *
  *                                                        *
- * LastModified: Sep 23, 2016                             *
+ * LastModified: Oct 18, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -29,3 +29,35 @@ type onErrorEvent interface {
 type onFailswitchEvent interface {
 	OnFailswitch(Client)
 }
+
+type onRetryEvent interface {
+	OnRetry(name string, attempt int, err error)
+}
+
+type onCircuitOpenEvent interface {
+	OnCircuitOpen(uri string)
+}
+
+type onCircuitHalfOpenEvent interface {
+	OnCircuitHalfOpen(uri string)
+}
+
+type onCircuitCloseEvent interface {
+	OnCircuitClose(uri string)
+}
+
+type onHealthChangeEvent interface {
+	OnHealthChange(uri string, healthy bool)
+}
+
+type onSubscribeConnectedEvent interface {
+	OnSubscribeConnected(topic string, id string)
+}
+
+type onSubscribeReconnectingEvent interface {
+	OnSubscribeReconnecting(topic string, id string, err error)
+}
+
+type onSubscribeStoppedEvent interface {
+	OnSubscribeStopped(topic string, id string)
+}
@@ -12,7 +12,7 @@
  *                                                        *
  * hprose clients for Go.                                 *
  *                                                        *
- * LastModified: Sep 21, 2016                             *
+ * LastModified: Oct 18, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -23,6 +23,7 @@ package rpc
 type Clients interface {
 	IDList(topic string) []string
 	Exist(topic string, id string) bool
+	QueueDepth(topic string, id string) int
 	Push(topic string, result interface{}, id ...string)
 	Broadcast(topic string, result interface{}, callback func([]string))
 	Multicast(topic string, ids []string, result interface{}, callback func([]string))
@@ -0,0 +1,493 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/codec.go                                           *
+ *                                                        *
+ * hprose rpc codec for Go.                               *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+
+	hio "github.com/hprose/hprose-golang/io"
+	"github.com/hprose/hprose-golang/util"
+)
+
+// Codec is the serialization format of the rpc request and response.
+//
+// The client uses EncodeRequest and DecodeResponse, the service uses
+// DecodeRequest, EncodeResult, EncodeError, MergeResponse and EncodeFunctions.
+// The filters and handlers work on the encoded data, so they are
+// independent of the codec.
+type Codec interface {
+	// ContentType returns the MIME type of the encoded data
+	ContentType() string
+	// EncodeRequest encodes the remote call on the client
+	EncodeRequest(
+		name string, args []reflect.Value, context *ClientContext) ([]byte, error)
+	// DecodeResponse decodes the response on the client, args are the
+	// pointers to the arguments which are updated when ByRef is true
+	DecodeResponse(
+		response []byte,
+		args []reflect.Value,
+		context *ClientContext) ([]reflect.Value, error)
+	// DecodeRequest decodes the request on the service, it returns no calls
+	// if the request asks for the function list
+	DecodeRequest(request []byte, context ServiceContext) ([]Call, error)
+	// EncodeResult encodes the result of one call on the service
+	EncodeResult(
+		args []reflect.Value,
+		results []reflect.Value,
+		context ServiceContext) []byte
+	// EncodeError encodes the error of one call on the service
+	EncodeError(message string, context ServiceContext) []byte
+	// MergeResponse merges the results and errors of the calls to a response
+	MergeResponse(responses [][]byte, context ServiceContext) []byte
+	// EncodeFunctions encodes the function list response on the service
+	EncodeFunctions(names []string, context ServiceContext) []byte
+}
+
+// BatchCodec is the Codec which supports the batch invocation of the client
+type BatchCodec interface {
+	Codec
+	// EncodeBatch merges the requests encoded by EncodeRequest to one request
+	EncodeBatch(requests [][]byte, context *ClientContext) ([]byte, error)
+	// SplitResponse splits the response of the batch request to the responses
+	// which can be decoded by DecodeResponse, it returns less than count
+	// responses if the service stops at an error.
+	SplitResponse(
+		response []byte, count int, context *ClientContext) ([][]byte, error)
+}
+
+// FunctionsCodec is the Codec which supports fetching the function list of
+// the service on the client
+type FunctionsCodec interface {
+	Codec
+	// EncodeFunctionsRequest encodes the request which asks for the function
+	// list
+	EncodeFunctionsRequest(context *ClientContext) ([]byte, error)
+	// DecodeFunctions decodes the function list response
+	DecodeFunctions(response []byte, context *ClientContext) ([]string, error)
+}
+
+// Call is a remote call decoded from the request by the Codec
+type Call interface {
+	// Name returns the name of the remote method
+	Name() string
+	// ByRef returns true if the arguments are passed by reference
+	ByRef() bool
+	// Args decodes the arguments of the call, method is nil when the method
+	// is not found. newArgs returns the values for count arguments, the first
+	// count of them are filled and returned.
+	Args(
+		method *Method,
+		newArgs func(count int) []reflect.Value) ([]reflect.Value, error)
+}
+
+// HproseCodec is the hprose serialization codec, it is the default Codec
+type HproseCodec struct{}
+
+var defaultCodec Codec = HproseCodec{}
+
+var readerPool = sync.Pool{
+	New: func() interface{} { return new(hio.Reader) },
+}
+
+func acquireReader(buf []byte) (reader *hio.Reader) {
+	reader = readerPool.Get().(*hio.Reader)
+	reader.Init(buf)
+	return
+}
+
+func releaseReader(reader *hio.Reader) {
+	reader.Init(nil)
+	reader.Reset()
+	reader.JSONCompatible = false
+	reader.Location = nil
+	readerPool.Put(reader)
+}
+
+// ContentType returns the MIME type of hprose
+func (HproseCodec) ContentType() string {
+	return "application/hprose"
+}
+
+// EncodeRequest encodes the remote call on the client
+func (HproseCodec) EncodeRequest(
+	name string,
+	args []reflect.Value,
+	context *ClientContext) ([]byte, error) {
+	writer := hio.NewWriter(context.Simple)
+	writer.UTC = context.UTC
+	writer.DateOnly = context.DateOnly
+	writer.TimePrecision = context.TimePrecision
+	writer.WriteByte(hio.TagCall)
+	writer.WriteString(name)
+	if len(args) > 0 || context.ByRef {
+		writer.Reset()
+		writer.WriteSlice(args)
+		if context.ByRef {
+			writer.WriteBool(true)
+		}
+	}
+	writer.WriteByte(hio.TagEnd)
+	return writer.Bytes(), nil
+}
+
+func readMultiResults(
+	reader *hio.Reader, resultTypes []reflect.Type) (results []reflect.Value) {
+	length := len(resultTypes)
+	reader.CheckTag(hio.TagList)
+	count := reader.ReadCount()
+	results = make([]reflect.Value, util.Max(length, count))
+	for i := 0; i < length; i++ {
+		results[i] = reflect.New(resultTypes[i]).Elem()
+	}
+	if length < count {
+		for i := length; i < count; i++ {
+			results[i] = reflect.New(interfaceType).Elem()
+		}
+	}
+	reader.ReadSlice(results[:count])
+	return
+}
+
+func readResults(
+	reader *hio.Reader,
+	context *ClientContext) (results []reflect.Value) {
+	length := len(context.ResultTypes)
+	switch length {
+	case 0:
+		var e interface{}
+		reader.Unserialize(&e)
+	case 1:
+		results = make([]reflect.Value, 1)
+		results[0] = reflect.New(context.ResultTypes[0]).Elem()
+		reader.ReadValue(results[0])
+	default:
+		results = readMultiResults(reader, context.ResultTypes)
+	}
+	return
+}
+
+func readArguments(
+	reader *hio.Reader,
+	args []reflect.Value,
+	context *ClientContext) byte {
+	length := len(args)
+	reader.Reset()
+	reader.CheckTag(hio.TagList)
+	count := reader.ReadCount()
+	a := make([]reflect.Value, util.Max(length, count))
+	for i := 0; i < length; i++ {
+		a[i] = args[i].Elem()
+	}
+	if length < count {
+		for i := length; i < count; i++ {
+			a[i] = reflect.New(interfaceType).Elem()
+		}
+	}
+	reader.ReadSlice(a[:count])
+	tag, _ := reader.ReadByte()
+	return tag
+}
+
+// DecodeResponse decodes the response on the client
+func (HproseCodec) DecodeResponse(
+	data []byte,
+	args []reflect.Value,
+	context *ClientContext) (results []reflect.Value, err error) {
+	if context.Oneway {
+		return
+	}
+	n := len(data)
+	if n == 0 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if data[n-1] != hio.TagEnd {
+		return nil, fmt.Errorf("Wrong Response: \r\n%s", data)
+	}
+	if context.Mode == RawWithEndTag {
+		results = make([]reflect.Value, 1)
+		results[0] = reflect.ValueOf(data)
+		return
+	}
+	if context.Mode == Raw {
+		results = make([]reflect.Value, 1)
+		results[0] = reflect.ValueOf(data[:n-1])
+		return
+	}
+	reader := acquireReader(data)
+	defer releaseReader(reader)
+	reader.JSONCompatible = context.JSONCompatible
+	reader.Location = context.Location
+	tag, _ := reader.ReadByte()
+	if tag == hio.TagResult {
+		switch context.Mode {
+		case Normal:
+			results = readResults(reader, context)
+		case Serialized:
+			results = make([]reflect.Value, 1)
+			results[0] = reflect.ValueOf(reader.ReadRaw())
+		}
+		tag, _ = reader.ReadByte()
+		if tag == hio.TagArgument {
+			tag = readArguments(reader, args, context)
+		}
+	} else if tag == hio.TagError {
+		return nil, errors.New(reader.ReadString())
+	}
+	if tag != hio.TagEnd {
+		return nil, fmt.Errorf("Wrong Response: \r\n%s", data)
+	}
+	return
+}
+
+// EncodeBatch merges the requests to one request on the client
+func (HproseCodec) EncodeBatch(
+	requests [][]byte, context *ClientContext) ([]byte, error) {
+	writer := hio.NewByteWriter(nil)
+	for _, request := range requests {
+		writer.Write(request[:len(request)-1])
+	}
+	writer.WriteByte(hio.TagEnd)
+	return writer.Bytes(), nil
+}
+
+// SplitResponse splits the response of the batch request on the client
+func (HproseCodec) SplitResponse(
+	response []byte,
+	count int,
+	context *ClientContext) (responses [][]byte, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = NewPanicError(e)
+		}
+	}()
+	reader := acquireReader(response)
+	defer releaseReader(reader)
+	responses = make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		tag, err := reader.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		part := []byte{tag}
+		switch tag {
+		case hio.TagResult:
+			part = append(part, reader.ReadRaw()...)
+			if tag, err = reader.ReadByte(); err != nil {
+				return nil, err
+			}
+			if tag == hio.TagArgument {
+				part = append(part, tag)
+				part = append(part, reader.ReadRaw()...)
+			} else {
+				reader.UnreadByte()
+			}
+		case hio.TagError:
+			part = append(part, reader.ReadRaw()...)
+		case hio.TagEnd:
+			return responses, nil
+		default:
+			return nil, fmt.Errorf("Wrong Response: \r\n%s", response)
+		}
+		responses = append(responses, append(part, hio.TagEnd))
+	}
+	return responses, nil
+}
+
+type hproseCall struct {
+	name  string
+	args  []byte
+	byRef bool
+}
+
+func (call *hproseCall) Name() string {
+	return call.name
+}
+
+func (call *hproseCall) ByRef() bool {
+	return call.byRef
+}
+
+func (call *hproseCall) Args(
+	method *Method,
+	newArgs func(count int) []reflect.Value) (args []reflect.Value, err error) {
+	if call.args == nil {
+		return nil, nil
+	}
+	defer func() {
+		if e := recover(); e != nil {
+			err = NewPanicError(e)
+		}
+	}()
+	reader := acquireReader(call.args)
+	defer releaseReader(reader)
+	if method != nil {
+		reader.JSONCompatible = method.JSONCompatible
+		reader.Location = method.Location
+	}
+	reader.CheckTag(hio.TagList)
+	count := reader.ReadCount()
+	args = newArgs(count)
+	reader.ReadSlice(args[:count])
+	return args, nil
+}
+
+// EncodeFunctionsRequest encodes the function list request on the client
+func (HproseCodec) EncodeFunctionsRequest(
+	context *ClientContext) ([]byte, error) {
+	return []byte{hio.TagEnd}, nil
+}
+
+// DecodeFunctions decodes the function list response on the client
+func (HproseCodec) DecodeFunctions(
+	response []byte, context *ClientContext) (names []string, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = NewPanicError(e)
+		}
+	}()
+	reader := acquireReader(response)
+	defer releaseReader(reader)
+	tag, err := reader.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	switch tag {
+	case hio.TagFunctions:
+		reader.Unserialize(&names)
+		return names, nil
+	case hio.TagError:
+		return nil, errors.New(reader.ReadString())
+	}
+	return nil, fmt.Errorf("Wrong Response: \r\n%s", response)
+}
+
+// DecodeRequest decodes the request on the service
+func (HproseCodec) DecodeRequest(
+	request []byte, context ServiceContext) (calls []Call, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = NewPanicError(e)
+		}
+	}()
+	reader := acquireReader(request)
+	defer releaseReader(reader)
+	tag, err := reader.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	switch tag {
+	case hio.TagCall:
+	case hio.TagEnd:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("Wrong Request: \r\n%s", request)
+	}
+	for tag == hio.TagCall {
+		call := &hproseCall{name: reader.ReadString()}
+		tag = reader.CheckTags([]byte{hio.TagList, hio.TagEnd, hio.TagCall})
+		if tag == hio.TagList {
+			reader.UnreadByte()
+			call.args = reader.ReadRaw()
+			tag = reader.CheckTags([]byte{hio.TagTrue, hio.TagEnd, hio.TagCall})
+			if tag == hio.TagTrue {
+				call.byRef = true
+				tag = reader.CheckTags([]byte{hio.TagEnd, hio.TagCall})
+			}
+		}
+		calls = append(calls, call)
+	}
+	return calls, nil
+}
+
+// EncodeResult encodes the result of one call on the service
+func (HproseCodec) EncodeResult(
+	args []reflect.Value,
+	results []reflect.Value,
+	context ServiceContext) []byte {
+	method := context.Method()
+	writer := hio.NewWriter(method.Simple)
+	writer.View = context.View()
+	writer.UTC = method.UTC
+	writer.DateOnly = method.DateOnly
+	writer.TimePrecision = method.TimePrecision
+	switch method.Mode {
+	case RawWithEndTag:
+		return results[0].Bytes()
+	case Raw:
+		writer.Write(results[0].Bytes())
+	default:
+		writer.WriteByte(hio.TagResult)
+		if method.Mode == Serialized {
+			writer.Write(results[0].Bytes())
+		} else {
+			switch len(results) {
+			case 0:
+				writer.WriteNil()
+			case 1:
+				writer.WriteValue(results[0])
+			default:
+				writer.WriteSlice(results)
+			}
+		}
+		if context.ByRef() {
+			writer.WriteByte(hio.TagArgument)
+			writer.Reset()
+			writer.WriteSlice(args)
+		}
+	}
+	return writer.Bytes()
+}
+
+// EncodeError encodes the error of one call on the service
+func (HproseCodec) EncodeError(message string, context ServiceContext) []byte {
+	w := hio.NewWriter(true)
+	w.WriteByte(hio.TagError)
+	w.WriteString(message)
+	return w.Bytes()
+}
+
+// MergeResponse merges the results and errors of the calls to a response
+func (HproseCodec) MergeResponse(
+	responses [][]byte, context ServiceContext) []byte {
+	n := len(responses)
+	if n == 1 {
+		return append(responses[0], hio.TagEnd)
+	}
+	writer := hio.NewByteWriter(responses[0])
+	for i := 1; i < n; i++ {
+		writer.Write(responses[i])
+	}
+	writer.WriteByte(hio.TagEnd)
+	return writer.Bytes()
+}
+
+// EncodeFunctions encodes the function list response on the service
+func (HproseCodec) EncodeFunctions(
+	names []string, context ServiceContext) []byte {
+	writer := hio.NewWriter(true)
+	writer.WriteByte(hio.TagFunctions)
+	writer.WriteStringSlice(names)
+	writer.WriteByte(hio.TagEnd)
+	return writer.Bytes()
+}
@@ -0,0 +1,261 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/codec_test.go                                      *
+ *                                                        *
+ * hprose rpc codec test for Go.                          *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"bytes"
+	"math/rand"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var testCodecs = map[string]Codec{
+	"hprose":  HproseCodec{},
+	"json":    JSONCodec{},
+	"msgpack": MsgPackCodec{},
+}
+
+func newTestServiceContext(function interface{}) *serviceContext {
+	context := new(serviceContext)
+	context.initServiceContext(nil)
+	context.setMethod(&Method{Function: reflect.ValueOf(function)})
+	return context
+}
+
+// decodeTestArgs decodes the arguments of call like the service does
+func decodeTestArgs(call Call, method *Method) ([]reflect.Value, error) {
+	ft := method.Function.Type()
+	return call.Args(method, func(count int) []reflect.Value {
+		args := make([]reflect.Value, count)
+		for i := 0; i < count; i++ {
+			if i < ft.NumIn() {
+				args[i] = reflect.New(ft.In(i)).Elem()
+			} else {
+				args[i] = reflect.New(interfaceType).Elem()
+			}
+		}
+		return args
+	})
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	join := func(n int, s string, a []int) map[string]int { return nil }
+	for name, codec := range testCodecs {
+		context := new(ClientContext)
+		context.initBaseContext()
+		request, err := codec.EncodeRequest("join", []reflect.Value{
+			reflect.ValueOf(3),
+			reflect.ValueOf("hello"),
+			reflect.ValueOf([]int{1, 2, 3}),
+		}, context)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		serviceContext := newTestServiceContext(join)
+		calls, err := codec.DecodeRequest(request, serviceContext)
+		if err != nil || len(calls) != 1 {
+			t.Fatal(name, calls, err)
+		}
+		if calls[0].Name() != "join" || calls[0].ByRef() {
+			t.Error(name, calls[0].Name(), calls[0].ByRef())
+		}
+		args, err := decodeTestArgs(calls[0], serviceContext.Method())
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		if args[0].Int() != 3 || args[1].String() != "hello" ||
+			!reflect.DeepEqual(args[2].Interface(), []int{1, 2, 3}) {
+			t.Error(name, args)
+		}
+		want := map[string]int{"a": 1, "b": 2}
+		response := codec.MergeResponse([][]byte{codec.EncodeResult(
+			args, []reflect.Value{reflect.ValueOf(want)}, serviceContext),
+		}, serviceContext)
+		context.ResultTypes = []reflect.Type{reflect.TypeOf(want)}
+		results, err := codec.DecodeResponse(response, nil, context)
+		if err != nil || len(results) != 1 {
+			t.Fatal(name, results, err)
+		}
+		if !reflect.DeepEqual(results[0].Interface(), want) {
+			t.Error(name, results[0].Interface())
+		}
+	}
+}
+
+func TestCodecContentType(t *testing.T) {
+	for name, codec := range testCodecs {
+		service := NewHTTPService()
+		service.AddFunction("hello", func() string { return "hello" }, Options{})
+		if name != "hprose" {
+			service.Codec = codec
+		}
+		request := httptest.NewRequest("GET", "/", nil)
+		response := httptest.NewRecorder()
+		service.ServeHTTP(response, request)
+		if got := response.Header().Get("Content-Type"); got != codec.ContentType() {
+			t.Error(name, got, codec.ContentType())
+		}
+	}
+}
+
+func TestCodecError(t *testing.T) {
+	for name, codec := range testCodecs {
+		serviceContext := newTestServiceContext(func() {})
+		context := new(ClientContext)
+		context.initBaseContext()
+		for _, message := range []string{"oops", ""} {
+			response := codec.MergeResponse([][]byte{
+				codec.EncodeError(message, serviceContext),
+			}, serviceContext)
+			_, err := codec.DecodeResponse(response, nil, context)
+			if err == nil || err.Error() != message {
+				t.Errorf("%s: %q %v", name, message, err)
+			}
+		}
+	}
+}
+
+func TestMsgPackTimePrecision(t *testing.T) {
+	codec := MsgPackCodec{}
+	context := new(ClientContext)
+	context.initBaseContext()
+	context.TimePrecision = time.Millisecond
+	now := time.Now()
+	request, err := codec.EncodeRequest(
+		"now", []reflect.Value{reflect.ValueOf(now)}, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serviceContext := newTestServiceContext(func(time.Time) {})
+	calls, err := codec.DecodeRequest(request, serviceContext)
+	if err != nil || len(calls) != 1 {
+		t.Fatal(calls, err)
+	}
+	args, err := decodeTestArgs(calls[0], serviceContext.Method())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tm := args[0].Interface().(time.Time); !tm.Equal(now.Truncate(time.Millisecond)) {
+		t.Error(tm, now)
+	}
+}
+
+func TestMsgPackMalformed(t *testing.T) {
+	inputs := [][]byte{
+		{0x81, 0xc4, 0x01, 'a', 0xc0},
+		{0x81, 0x91, 0x01, 0xc0},
+		{0x81, 0x80, 0xc0},
+		{0xdd, 0xff, 0xff, 0xff, 0xff},
+		{0xdf, 0xff, 0xff, 0xff, 0xff},
+		{0xdc, 0xff, 0xff, 0xc0},
+		{0xdb, 0xff, 0xff, 0xff, 0xff},
+		{0xc6, 0xff, 0xff, 0xff, 0xff},
+		{0xc9, 0xff, 0xff, 0xff, 0xff, 0xff},
+		{0xd6, 0x01, 0, 0, 0, 0},
+		{0xc1},
+		{0x82, 0xa6, 'm', 'e', 't', 'h', 'o', 'd'},
+		{0x2a},
+	}
+	codec := MsgPackCodec{}
+	context := new(ClientContext)
+	context.initBaseContext()
+	context.ResultTypes = []reflect.Type{reflect.TypeOf(0)}
+	for _, input := range inputs {
+		serviceContext := newTestServiceContext(func(int) {})
+		if _, err := codec.DecodeRequest(input, serviceContext); err == nil {
+			t.Errorf("DecodeRequest(% x) expected error", input)
+		}
+	}
+	// the response ignores the values of the unknown keys
+	for _, input := range inputs[3:] {
+		if _, err := codec.DecodeResponse(input, nil, context); err == nil {
+			t.Errorf("DecodeResponse(% x) expected error", input)
+		}
+	}
+}
+
+func TestMsgPackMaxDepth(t *testing.T) {
+	nested := func(depth int) []byte {
+		return append(bytes.Repeat([]byte{0x91}, depth), 0xc0)
+	}
+	r := &msgpackReader{buf: nested(msgpackMaxDepth)}
+	if _, err := r.readValue(); err != nil || r.depth != 0 {
+		t.Error(err, r.depth)
+	}
+	r = &msgpackReader{buf: nested(msgpackMaxDepth + 1)}
+	if _, err := r.readValue(); err != errMsgpackTooDeep {
+		t.Error(err)
+	}
+	// the deep input is rejected instead of overflowing the stack
+	input := append([]byte{0x81, 0xa1, 'a'}, nested(1000000)...)
+	serviceContext := newTestServiceContext(func(int) {})
+	if _, err := (MsgPackCodec{}).DecodeRequest(input, serviceContext); err != errMsgpackTooDeep {
+		t.Error(err)
+	}
+}
+
+func TestJSONMalformed(t *testing.T) {
+	inputs := []string{
+		`{`,
+		`[{"method":"a"},`,
+		`{"method":1}`,
+		`{"method":"a","params":1}`,
+		`"method"`,
+		`[1, 2]`,
+	}
+	codec := JSONCodec{}
+	for _, input := range inputs {
+		serviceContext := newTestServiceContext(func(int) {})
+		if _, err := codec.DecodeRequest([]byte(input), serviceContext); err == nil {
+			t.Errorf("DecodeRequest(%s) expected error", input)
+		}
+	}
+	context := new(ClientContext)
+	context.initBaseContext()
+	context.ResultTypes = []reflect.Type{reflect.TypeOf(0)}
+	for _, input := range []string{`{`, `{"result":"a"}`, `{"error":""}`, `[]`} {
+		if _, err := codec.DecodeResponse([]byte(input), nil, context); err == nil {
+			t.Errorf("DecodeResponse(%s) expected error", input)
+		}
+	}
+}
+
+// TestCodecRandomInput checks that the codecs don't panic on random input
+func TestCodecRandomInput(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	context := new(ClientContext)
+	context.initBaseContext()
+	context.ResultTypes = []reflect.Type{reflect.TypeOf(map[string][]int{})}
+	for _, codec := range testCodecs {
+		for i := 0; i < 2000; i++ {
+			input := make([]byte, r.Intn(32))
+			r.Read(input)
+			serviceContext := newTestServiceContext(func(string, []int) {})
+			if calls, err := codec.DecodeRequest(input, serviceContext); err == nil {
+				for _, call := range calls {
+					decodeTestArgs(call, serviceContext.Method())
+				}
+			}
+			codec.DecodeResponse(input, nil, context)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/context_test.go                                    *
+ *                                                        *
+ * hprose rpc context-aware invoking test for Go.         *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	gocontext "context"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type contextStub struct {
+	Sleep   func(gocontext.Context, int) (string, error)
+	SleepV  func(gocontext.Context, ...int) (string, error)
+	SleepCB func(gocontext.Context, func(string, error), int)
+	Hello   func(string) (string, error)
+	Notify  func(gocontext.Context, string) `oneway:"true"`
+}
+
+type contextService interface {
+	AddFunction(name string, function interface{}, options Options) Service
+}
+
+func addContextFunctions(service contextService, notified chan string) {
+	sleep := func(ms ...int) string {
+		time.Sleep(time.Duration(ms[0]) * time.Millisecond)
+		return "done"
+	}
+	service.AddFunction("sleep", sleep, Options{})
+	service.AddFunction("sleepV", sleep, Options{})
+	service.AddFunction("sleepCB", sleep, Options{})
+	service.AddFunction("hello", func(s string) string { return s }, Options{})
+	service.AddFunction("notify", func(s string) {
+		time.Sleep(20 * time.Millisecond)
+		notified <- s
+	}, Options{Oneway: true})
+}
+
+func testContext(t *testing.T, name string, client Client, notified chan string) {
+	var stub *contextStub
+	client.UseService(&stub)
+	s, err := stub.Sleep(gocontext.Background(), 10)
+	if s != "done" || err != nil {
+		t.Error(name, s, err)
+	}
+	ctx, cancel := gocontext.WithTimeout(
+		gocontext.Background(), 100*time.Millisecond)
+	start := time.Now()
+	s, err = stub.Sleep(ctx, 2000)
+	cancel()
+	if err != gocontext.DeadlineExceeded || time.Since(start) > time.Second {
+		t.Error(name, s, err, time.Since(start))
+	}
+	ctx, cancel = gocontext.WithCancel(gocontext.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	if s, err = stub.SleepV(ctx, 2000); err != gocontext.Canceled {
+		t.Error(name, s, err)
+	}
+	result := make(chan error, 1)
+	stub.SleepCB(gocontext.Background(), func(s string, err error) {
+		result <- err
+	}, 5)
+	if err := <-result; err != nil {
+		t.Error(name, err)
+	}
+	// the client still works after the cancellation
+	for i := 0; i < 3; i++ {
+		if s, err = stub.Hello("x"); s != "x" || err != nil {
+			t.Error(name, "after cancellation", s, err)
+		}
+	}
+	ctx, cancel = gocontext.WithCancel(gocontext.Background())
+	cancel()
+	if _, err = client.InvokeContext(ctx, "hello", nil, nil); err != gocontext.Canceled {
+		t.Error(name, err)
+	}
+	// the oneway request is still sent after the context is canceled
+	ctx, cancel = gocontext.WithCancel(gocontext.Background())
+	stub.Notify(ctx, name)
+	cancel()
+	select {
+	case s = <-notified:
+		if s != name {
+			t.Error(name, "notify", s)
+		}
+	case <-time.After(time.Second):
+		t.Error(name, "the oneway request is not sent")
+	}
+}
+
+func TestHTTPContext(t *testing.T) {
+	notified := make(chan string, 1)
+	service := NewHTTPService()
+	addContextFunctions(service, notified)
+	server := httptest.NewServer(service)
+	defer server.Close()
+	testContext(t, "http", NewHTTPClient(server.URL), notified)
+	testContext(t, "fasthttp", NewFastHTTPClient(server.URL), notified)
+}
+
+func TestTCPContext(t *testing.T) {
+	notified := make(chan string, 1)
+	server := NewTCPServer("tcp://127.0.0.1:0")
+	addContextFunctions(server, notified)
+	server.Handle()
+	defer server.Close()
+	client := NewTCPClient(server.URI())
+	defer client.Close()
+	testContext(t, "tcp", client, notified)
+}
+
+func TestWebSocketContext(t *testing.T) {
+	notified := make(chan string, 1)
+	service := NewWebSocketService()
+	addContextFunctions(service, notified)
+	server := httptest.NewServer(service)
+	defer server.Close()
+	client := NewWebSocketClient("ws" + server.URL[4:])
+	defer client.Close()
+	testContext(t, "websocket", client, notified)
+}
+
+func TestDetachedContext(t *testing.T) {
+	type key struct{}
+	parent, cancel := gocontext.WithTimeout(
+		gocontext.WithValue(gocontext.Background(), key{}, "value"), time.Second)
+	cancel()
+	ctx := detachedContext{parent}
+	if ctx.Err() != nil || ctx.Done() != nil {
+		t.Error(ctx.Err())
+	}
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("detached context has no deadline")
+	}
+	if ctx.Value(key{}) != "value" {
+		t.Error(ctx.Value(key{}))
+	}
+}
@@ -12,7 +12,7 @@
  *                                                        *
  * rpc error for Go.                                      *
  *                                                        *
- * LastModified: Oct 11, 2016                             *
+ * LastModified: Oct 18, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -27,11 +27,23 @@ import (
 
 // ErrTimeout represents a timeout error
 var ErrTimeout = errors.New("timeout")
+
+// ErrCircuitOpen represents all the service addresses are skipped by the
+// circuit breaker
+var ErrCircuitOpen = errors.New("circuit open")
+
+// ErrRateLimited represents the request is rejected by the rate limiter
+var ErrRateLimited = errors.New("rate limited")
 var errServerIsAlreadyStarted = errors.New("The server is already started")
 var errServerIsNotStarted = errors.New("The server is not started")
 var errClientIsAlreadyClosed = errors.New("The Client is already closed")
+var errConnIsClosed = errors.New("The connection is closed")
+var errEndpointUnhealthy = errors.New("The endpoint is unhealthy")
+var errNoIdleEndpoint = errors.New("No idle endpoint for the hedged request")
 var errURIListEmpty = errors.New("uriList must contain at least one uri")
 var errNotSupportMultpleProtocol = errors.New("Not support multiple protocol.")
+var errPushBrokerNotConnected = errors.New("The push broker is not connected")
+var errPushFrameTooLarge = errors.New("The push frame is too large")
 
 // PanicError represents a panic error
 type PanicError struct {
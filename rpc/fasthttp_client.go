@@ -12,7 +12,7 @@
  *                                                        *
  * hprose http client for Go.                             *
  *                                                        *
- * LastModified: Oct 11, 2016                             *
+ * LastModified: Oct 18, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -21,6 +21,7 @@ package rpc
 
 import (
 	"crypto/tls"
+	"time"
 
 	"github.com/valyala/fasthttp"
 )
@@ -42,6 +43,7 @@ func NewFastHTTPClient(uri ...string) (client *FastHTTPClient) {
 	client.initLimiter()
 	client.compression = false
 	client.keepAlive = true
+	client.setURIList = client.SetURIList
 	client.SetURIList(uri)
 	client.SendAndReceive = client.sendAndReceive
 	return
@@ -92,10 +94,10 @@ func (client *FastHTTPClient) sendAndReceive(
 	req := fasthttp.AcquireRequest()
 	client.Header.CopyTo(&req.Header)
 	req.Header.SetMethod("POST")
-	req.SetRequestURI(client.uri)
+	req.SetRequestURI(context.URI())
 	req.SetBody(data)
 	req.Header.SetContentLength(len(data))
-	req.Header.SetContentType("application/hprose")
+	req.Header.SetContentType(client.codec.ContentType())
 	if client.keepAlive {
 		req.Header.Set("Connection", "keep-alive")
 	} else {
@@ -105,11 +107,46 @@ func (client *FastHTTPClient) sendAndReceive(
 		req.Header.Set("Content-Encoding", "gzip")
 	}
 	resp := fasthttp.AcquireResponse()
-	err := client.Client.DoTimeout(req, resp, context.Timeout)
-	if err != nil {
-		data = nil
-	} else {
-		data = resp.Body()
+	// fasthttp can't abort the request in flight, the canceled request is
+	// abandoned and runs until it is done or timeout, so the deadline of
+	// the context also limits the timeout.
+	timeout := context.Timeout
+	deadline, bounded := context.Context().Deadline()
+	if bounded {
+		if d := time.Until(deadline); d < timeout {
+			timeout = d
+		} else {
+			bounded = false
+		}
+	}
+	done := context.Context().Done()
+	if done == nil {
+		return client.doTimeout(req, resp, timeout)
+	}
+	result := make(chan socketResponse, 1)
+	go func() {
+		data, err := client.doTimeout(req, resp, timeout)
+		result <- socketResponse{data, err}
+	}()
+	select {
+	case r := <-result:
+		if r.err == fasthttp.ErrTimeout && bounded {
+			<-done
+			return nil, context.Context().Err()
+		}
+		return r.data, r.err
+	case <-done:
+		return nil, context.Context().Err()
+	}
+}
+
+func (client *FastHTTPClient) doTimeout(
+	req *fasthttp.Request,
+	resp *fasthttp.Response,
+	timeout time.Duration) (data []byte, err error) {
+	err = client.Client.DoTimeout(req, resp, timeout)
+	if err == nil {
+		data = append([]byte(nil), resp.Body()...)
 	}
 	fasthttp.ReleaseRequest(req)
 	fasthttp.ReleaseResponse(resp)
@@ -12,7 +12,7 @@
  *                                                        *
  * hprose fasthttp service for Go.                        *
  *                                                        *
- * LastModified: Oct 9, 2016                              *
+ * LastModified: Oct 18, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -146,7 +146,7 @@ func (service *FastHTTPService) sendHeader(
 		return err
 	}
 	ctx := context.RequestCtx
-	ctx.Response.Header.Set("Content-Type", "text/plain")
+	ctx.Response.Header.Set("Content-Type", service.contentType())
 	if service.P3P {
 		ctx.Response.Header.Set("P3P",
 			`CP="CAO DSP COR CUR ADM DEV TAI PSA PSD IVAi IVDi `+
@@ -0,0 +1,170 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/functions.go                                       *
+ *                                                        *
+ * hprose client function list for Go.                    *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var errFunctionsNotSupported = errors.New(
+	"The codec does not support fetching the function list")
+
+// Functions returns the function list of the service, it is fetched on the
+// first call and cached, RefreshFunctions fetches it again.
+func (client *baseClient) Functions() ([]string, error) {
+	client.funcLocker.Lock()
+	defer client.funcLocker.Unlock()
+	if client.functions == nil {
+		if err := client.fetchFunctions(); err != nil {
+			return nil, err
+		}
+	}
+	return append([]string(nil), client.functions...), nil
+}
+
+// RefreshFunctions fetches the function list of the service and caches it
+func (client *baseClient) RefreshFunctions() ([]string, error) {
+	client.funcLocker.Lock()
+	defer client.funcLocker.Unlock()
+	if err := client.fetchFunctions(); err != nil {
+		return nil, err
+	}
+	return append([]string(nil), client.functions...), nil
+}
+
+func (client *baseClient) fetchFunctions() error {
+	codec, ok := client.codec.(FunctionsCodec)
+	if !ok {
+		return errFunctionsNotSupported
+	}
+	context := client.acquireContext()
+	defer client.releaseContext(context)
+	client.initClientContext(context, nil)
+	context.Idempotent = true
+	context.start = time.Now()
+	request, err := codec.EncodeFunctionsRequest(context)
+	if err != nil {
+		return err
+	}
+	response, err := client.sendRequest(request, context)
+	if err != nil {
+		return err
+	}
+	functions, err := codec.DecodeFunctions(response, context)
+	if err != nil {
+		return err
+	}
+	if functions == nil {
+		functions = []string{}
+	}
+	client.functions = functions
+	return nil
+}
+
+// UseServiceOptions is the options of UseServiceWithOptions.
+//
+// Namespace is the namespace of the remote service. When Validate is true,
+// every remote method of the remote service proxy object must be in the
+// function list of the service.
+type UseServiceOptions struct {
+	Namespace string
+	Validate  bool
+}
+
+// UseServiceWithOptions build a remote service proxy object with options,
+// it returns an error and remoteService is not changed if the validation
+// fails.
+func (client *baseClient) UseServiceWithOptions(
+	remoteService interface{}, options UseServiceOptions) error {
+	v := reflect.ValueOf(remoteService)
+	if v.Kind() != reflect.Ptr {
+		panic("UseService: remoteService argument must be a pointer")
+	}
+	if options.Validate {
+		if err := client.validateService(
+			v.Type().Elem(), options.Namespace); err != nil {
+			return err
+		}
+	}
+	buildRemoteService(client, nil, v, options.Namespace)
+	return nil
+}
+
+func (client *baseClient) validateService(t reflect.Type, ns string) error {
+	functions, err := client.Functions()
+	if err != nil {
+		return err
+	}
+	published := make(map[string]bool, len(functions))
+	for _, name := range functions {
+		if name == "*" {
+			return nil
+		}
+		published[strings.ToLower(name)] = true
+	}
+	var missing []string
+	for _, name := range remoteMethodNames(t, ns) {
+		if !published[strings.ToLower(name)] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("UseService: the remote methods are not found: %s",
+			strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// remoteMethodNames returns the remote method names of the remote service
+// proxy type t, it walks t like buildRemoteService.
+func remoteMethodNames(t reflect.Type, ns string) (names []string) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		switch ft.Kind() {
+		case reflect.Struct:
+			namespace := ns
+			if !sf.Anonymous {
+				if ns == "" {
+					namespace = sf.Name
+				} else {
+					namespace += "_" + sf.Name
+				}
+			}
+			names = append(names, remoteMethodNames(ft, namespace)...)
+		case reflect.Func:
+			names = append(names, getRemoteMethodName(sf, ns))
+		}
+	}
+	return
+}
@@ -0,0 +1,171 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/functions_test.go                                  *
+ *                                                        *
+ * hprose client function list test for Go.               *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestCodecFunctions(t *testing.T) {
+	for name, codec := range testCodecs {
+		functionsCodec, ok := codec.(FunctionsCodec)
+		if !ok {
+			t.Error(name, "is not a FunctionsCodec")
+			continue
+		}
+		context := new(ClientContext)
+		context.initBaseContext()
+		serviceContext := newTestServiceContext(func() {})
+		request, err := functionsCodec.EncodeFunctionsRequest(context)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		// the function list request has no calls
+		calls, err := codec.DecodeRequest(request, serviceContext)
+		if err != nil || len(calls) != 0 {
+			t.Error(name, calls, err)
+		}
+		response := codec.EncodeFunctions([]string{"hello", "sum"}, serviceContext)
+		if name == "hprose" {
+			response = codec.MergeResponse([][]byte{response}, serviceContext)
+		}
+		names, err := functionsCodec.DecodeFunctions(response, context)
+		if err != nil || !reflect.DeepEqual(names, []string{"hello", "sum"}) {
+			t.Error(name, names, err)
+		}
+		if _, err = functionsCodec.DecodeFunctions([]byte("\x00bad"), context); err == nil {
+			t.Error(name, "the malformed response is decoded")
+		}
+	}
+}
+
+type functionsSub struct {
+	Add func(a, b int) (int, error)
+}
+
+type functionsStub struct {
+	Hello func(string) (string, error)
+	Sub   *functionsSub
+}
+
+type missingStub struct {
+	Hello   func(string) (string, error)
+	Missing func() error
+	Other   func() error `name:"other_thing"`
+}
+
+func addListedFunctions(service contextService) {
+	service.AddFunction("hello", func(s string) string { return "Hello " + s }, Options{})
+	service.AddFunction("Sub_add", func(a, b int) int { return a + b }, Options{})
+}
+
+func testFunctions(t *testing.T, name string, client Client, service contextService) {
+	functions, err := client.Functions()
+	sort.Strings(functions)
+	if err != nil || strings.Join(functions, ",") != "#,Sub_add,hello" {
+		t.Fatal(name, functions, err)
+	}
+	var stub *functionsStub
+	if err = client.UseServiceWithOptions(&stub, UseServiceOptions{Validate: true}); err != nil {
+		t.Fatal(name, err)
+	}
+	if n, err := stub.Sub.Add(1, 2); n != 3 || err != nil {
+		t.Error(name, n, err)
+	}
+	var missing *missingStub
+	err = client.UseServiceWithOptions(&missing, UseServiceOptions{Validate: true})
+	if err == nil || missing != nil ||
+		!strings.Contains(err.Error(), "Missing, other_thing") {
+		t.Error(name, err)
+	}
+	service.AddFunction("missing", func() {}, Options{})
+	service.AddFunction("other_thing", func() {}, Options{})
+	// the function list is cached until it is refreshed
+	if err = client.UseServiceWithOptions(&missing, UseServiceOptions{Validate: true}); err == nil {
+		t.Error(name, "the function list is not cached")
+	}
+	if _, err = client.RefreshFunctions(); err != nil {
+		t.Error(name, err)
+	}
+	if err = client.UseServiceWithOptions(&missing, UseServiceOptions{Validate: true}); err != nil || missing == nil {
+		t.Error(name, err)
+	}
+}
+
+func TestFunctions(t *testing.T) {
+	for name, codec := range testCodecs {
+		server := NewTCPServer("tcp://127.0.0.1:0")
+		server.Codec = codec
+		addListedFunctions(server)
+		server.Handle()
+		client := NewTCPClient(server.URI())
+		client.SetCodec(codec)
+		testFunctions(t, "tcp "+name, client, server)
+		client.Close()
+		server.Close()
+		service := NewHTTPService()
+		service.Codec = codec
+		addListedFunctions(service)
+		httpServer := httptest.NewServer(service)
+		httpClient := NewHTTPClient(httpServer.URL)
+		httpClient.SetCodec(codec)
+		testFunctions(t, "http "+name, httpClient, service)
+		httpClient.Close()
+		httpServer.Close()
+	}
+}
+
+// plainCodec is a Codec which does not support the function list
+type plainCodec struct {
+	Codec
+}
+
+func TestFunctionsNotSupported(t *testing.T) {
+	client := NewTCPClient(testURIList...)
+	defer client.Close()
+	client.SetCodec(plainCodec{HproseCodec{}})
+	if _, err := client.Functions(); err != errFunctionsNotSupported {
+		t.Error(err)
+	}
+	var stub *functionsStub
+	if err := client.UseServiceWithOptions(&stub, UseServiceOptions{Validate: true}); err != errFunctionsNotSupported || stub != nil {
+		t.Error(err)
+	}
+}
+
+func TestFunctionsMissingMethod(t *testing.T) {
+	server := NewTCPServer("tcp://127.0.0.1:0")
+	server.AddMissingMethod(func(name string, args []reflect.Value, context Context) []reflect.Value {
+		return []reflect.Value{reflect.ValueOf(name)}
+	}, Options{})
+	server.Handle()
+	defer server.Close()
+	client := NewTCPClient(server.URI())
+	defer client.Close()
+	// every remote method is published by the missing method
+	var missing *missingStub
+	if err := client.UseServiceWithOptions(&missing, UseServiceOptions{Validate: true}); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,240 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/future.go                                          *
+ *                                                        *
+ * hprose client future for Go.                           *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	gocontext "context"
+	"errors"
+	"reflect"
+	"sync"
+)
+
+// Future is the result of an asynchronous invoking, it is done when the
+// invoking is finished.
+type Future struct {
+	done    chan struct{}
+	once    sync.Once
+	results []reflect.Value
+	err     error
+}
+
+var errNoFuture = errors.New("no future")
+
+func newFuture() *Future {
+	return &Future{done: make(chan struct{})}
+}
+
+func (future *Future) resolve(results []reflect.Value, err error) {
+	future.once.Do(func() {
+		future.results = results
+		future.err = err
+		close(future.done)
+	})
+}
+
+// Done returns a channel which is closed when the future is done
+func (future *Future) Done() <-chan struct{} {
+	return future.done
+}
+
+// Wait waits until the future is done and returns the results and the error
+func (future *Future) Wait() ([]reflect.Value, error) {
+	<-future.done
+	return future.results, future.err
+}
+
+// WaitContext waits until the future is done or ctx is done, it returns the
+// error of ctx if ctx is done first, and the invoking is not canceled.
+func (future *Future) WaitContext(
+	ctx gocontext.Context) ([]reflect.Value, error) {
+	select {
+	case <-future.done:
+		return future.results, future.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// settle calls fn with the results and the error of the future when it is
+// done, and resolves the returned future with the returns of fn.
+func (future *Future) settle(
+	fn func([]reflect.Value, error) ([]reflect.Value, error)) *Future {
+	next := newFuture()
+	go func() {
+		defer func() {
+			if e := recover(); e != nil {
+				next.resolve(nil, NewPanicError(e))
+			}
+		}()
+		next.resolve(fn(future.Wait()))
+	}()
+	return next
+}
+
+// Then returns a future which is resolved with the returns of onSuccess
+// called with the results of the future if the future succeeds, otherwise it
+// is resolved with the error of the future and onSuccess is not called.
+func (future *Future) Then(
+	onSuccess func(results []reflect.Value) ([]reflect.Value, error)) *Future {
+	return future.settle(func(
+		results []reflect.Value, err error) ([]reflect.Value, error) {
+		if err != nil {
+			return nil, err
+		}
+		return onSuccess(results)
+	})
+}
+
+// Catch returns a future which is resolved with the returns of onError
+// called with the error of the future if the future fails, otherwise it is
+// resolved with the results of the future and onError is not called.
+func (future *Future) Catch(
+	onError func(err error) ([]reflect.Value, error)) *Future {
+	return future.settle(func(
+		results []reflect.Value, err error) ([]reflect.Value, error) {
+		if err == nil {
+			return results, nil
+		}
+		return onError(err)
+	})
+}
+
+// waitUntil waits until the future or the result future is done, and reports
+// whether the future is done, so the waiting goroutines do not outlive the
+// result future.
+func (future *Future) waitUntil(result *Future) bool {
+	select {
+	case <-future.done:
+		return true
+	case <-result.done:
+		return false
+	}
+}
+
+// All returns a future which succeeds when all the futures succeed, or fails
+// with the error of the first failed future.
+//
+// The results of the returned future are the first results of the futures
+// in order (the zero Value if the future has no result), the whole results of
+// every future can be got by its Wait.
+func All(futures ...*Future) *Future {
+	all := newFuture()
+	var locker sync.Mutex
+	pending := len(futures)
+	resolve := func() {
+		results := make([]reflect.Value, len(futures))
+		for i, future := range futures {
+			if len(future.results) > 0 {
+				results[i] = future.results[0]
+			}
+		}
+		all.resolve(results, nil)
+	}
+	if pending == 0 {
+		resolve()
+		return all
+	}
+	for _, future := range futures {
+		go func(future *Future) {
+			if !future.waitUntil(all) {
+				return
+			}
+			if future.err != nil {
+				all.resolve(nil, future.err)
+				return
+			}
+			locker.Lock()
+			pending--
+			done := pending == 0
+			locker.Unlock()
+			if done {
+				resolve()
+			}
+		}(future)
+	}
+	return all
+}
+
+// Any returns a future which is resolved with the results of the first
+// succeeded future, or fails with the error of the last failed future if all
+// of them fail.
+func Any(futures ...*Future) *Future {
+	first := newFuture()
+	if len(futures) == 0 {
+		first.resolve(nil, errNoFuture)
+		return first
+	}
+	var locker sync.Mutex
+	failed := 0
+	for _, future := range futures {
+		go func(future *Future) {
+			if !future.waitUntil(first) {
+				return
+			}
+			if future.err == nil {
+				first.resolve(future.results, nil)
+				return
+			}
+			locker.Lock()
+			failed++
+			if failed == len(futures) {
+				first.resolve(nil, future.err)
+			}
+			locker.Unlock()
+		}(future)
+	}
+	return first
+}
+
+// Race returns a future which is resolved with the results and the error of
+// the first done future.
+func Race(futures ...*Future) *Future {
+	race := newFuture()
+	if len(futures) == 0 {
+		race.resolve(nil, errNoFuture)
+		return race
+	}
+	for _, future := range futures {
+		go func(future *Future) {
+			if future.waitUntil(race) {
+				race.resolve(future.results, future.err)
+			}
+		}(future)
+	}
+	return race
+}
+
+// InvokeAsync the remote method asynchronous and returns the future of it
+func (client *baseClient) InvokeAsync(
+	name string, args []reflect.Value, settings *InvokeSettings) *Future {
+	return client.InvokeAsyncContext(gocontext.Background(), name, args, settings)
+}
+
+// InvokeAsyncContext the remote method asynchronous with ctx and returns the
+// future of it
+func (client *baseClient) InvokeAsyncContext(
+	ctx gocontext.Context, name string,
+	args []reflect.Value, settings *InvokeSettings) *Future {
+	future := newFuture()
+	go func() {
+		future.resolve(client.InvokeContext(ctx, name, args, settings))
+	}()
+	return future
+}
@@ -0,0 +1,204 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/future_test.go                                     *
+ *                                                        *
+ * hprose client future test for Go.                      *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	gocontext "context"
+	"errors"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func resolvedFuture(value interface{}) *Future {
+	future := newFuture()
+	future.resolve([]reflect.Value{reflect.ValueOf(value)}, nil)
+	return future
+}
+
+func rejectedFuture(err error) *Future {
+	future := newFuture()
+	future.resolve(nil, err)
+	return future
+}
+
+func emptyFuture() *Future {
+	future := newFuture()
+	future.resolve(nil, nil)
+	return future
+}
+
+// delayedFuture is resolved with value, or rejected with value if it is an
+// error, after d.
+func delayedFuture(d time.Duration, value interface{}) *Future {
+	future := newFuture()
+	time.AfterFunc(d, func() {
+		if err, ok := value.(error); ok {
+			future.resolve(nil, err)
+		} else {
+			future.resolve([]reflect.Value{reflect.ValueOf(value)}, nil)
+		}
+	})
+	return future
+}
+
+func checkFuture(t *testing.T, future *Future, value interface{}, err error) {
+	t.Helper()
+	results, e := future.Wait()
+	if e != err {
+		t.Error(e, err)
+		return
+	}
+	if err == nil && (len(results) == 0 || results[0].Interface() != value) {
+		t.Error(results, value)
+	}
+}
+
+func TestFutureResolve(t *testing.T) {
+	future := newFuture()
+	select {
+	case <-future.Done():
+		t.Error("the future is done")
+	default:
+	}
+	future.resolve([]reflect.Value{reflect.ValueOf(1)}, nil)
+	// the future is only resolved once
+	future.resolve(nil, ErrTimeout)
+	checkFuture(t, future, 1, nil)
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := newFuture().WaitContext(ctx); err != gocontext.DeadlineExceeded {
+		t.Error(err)
+	}
+	checkFuture(t, future, 1, nil)
+}
+
+func TestFutureThenCatch(t *testing.T) {
+	fail := errors.New("fail")
+	addOne := func(results []reflect.Value) ([]reflect.Value, error) {
+		return []reflect.Value{reflect.ValueOf(results[0].Int() + 1)}, nil
+	}
+	recovered := func(err error) ([]reflect.Value, error) {
+		return []reflect.Value{reflect.ValueOf(int64(0))}, nil
+	}
+	checkFuture(t, resolvedFuture(int64(1)).Then(addOne).Then(addOne), int64(3), nil)
+	checkFuture(t, rejectedFuture(fail).Then(addOne), nil, fail)
+	checkFuture(t, rejectedFuture(fail).Catch(recovered).Then(addOne), int64(1), nil)
+	checkFuture(t, resolvedFuture(int64(5)).Catch(recovered), int64(5), nil)
+	checkFuture(t, rejectedFuture(fail).Catch(func(err error) ([]reflect.Value, error) {
+		return nil, ErrTimeout
+	}), nil, ErrTimeout)
+	_, err := resolvedFuture(1).Then(func(results []reflect.Value) ([]reflect.Value, error) {
+		panic("boom")
+	}).Wait()
+	if _, ok := err.(*PanicError); !ok {
+		t.Error(err)
+	}
+}
+
+func TestFutureAll(t *testing.T) {
+	fail := errors.New("fail")
+	results, err := All(
+		delayedFuture(20*time.Millisecond, 1),
+		resolvedFuture("two"),
+		delayedFuture(10*time.Millisecond, 3.0),
+		emptyFuture(),
+	).Wait()
+	if err != nil || len(results) != 4 || results[0].Interface() != 1 ||
+		results[1].Interface() != "two" || results[2].Interface() != 3.0 ||
+		results[3].IsValid() {
+		t.Error(results, err)
+	}
+	checkFuture(t, All(delayedFuture(10*time.Millisecond, fail), newFuture()), nil, fail)
+	if results, err = All().Wait(); err != nil || len(results) != 0 {
+		t.Error(results, err)
+	}
+}
+
+func TestFutureAny(t *testing.T) {
+	fail1, fail2 := errors.New("fail1"), errors.New("fail2")
+	checkFuture(t, Any(rejectedFuture(fail1), delayedFuture(10*time.Millisecond, 2), newFuture()), 2, nil)
+	checkFuture(t, Any(rejectedFuture(fail1), delayedFuture(10*time.Millisecond, fail2)), nil, fail2)
+	checkFuture(t, Any(), nil, errNoFuture)
+}
+
+func TestFutureRace(t *testing.T) {
+	fail := errors.New("fail")
+	checkFuture(t, Race(delayedFuture(time.Second, 1), delayedFuture(10*time.Millisecond, 2)), 2, nil)
+	checkFuture(t, Race(newFuture(), delayedFuture(10*time.Millisecond, fail)), nil, fail)
+	checkFuture(t, Race(), nil, errNoFuture)
+}
+
+// TestFutureNoLeak checks that the goroutines waiting for the futures exit
+// when the result future is done, even if the futures are never done.
+func TestFutureNoLeak(t *testing.T) {
+	n := runtime.NumGoroutine()
+	never := newFuture()
+	for i := 0; i < 100; i++ {
+		All(never, rejectedFuture(ErrTimeout)).Wait()
+		Any(never, resolvedFuture(i)).Wait()
+		Race(never, resolvedFuture(i)).Wait()
+	}
+	if !waitUntil(time.Second, func() bool { return runtime.NumGoroutine() <= n+5 }) {
+		t.Error("the goroutines are leaked", runtime.NumGoroutine()-n)
+	}
+}
+
+type futureStub struct {
+	Hello func(string) *Future
+	Sleep func(gocontext.Context, int) *Future
+	Fail  func() *Future
+}
+
+func TestFutureInvoke(t *testing.T) {
+	server := NewTCPServer("tcp://127.0.0.1:0")
+	server.AddFunction("hello", func(s string) string { return "Hello " + s }, Options{})
+	server.AddFunction("sleep", func(ms int) int {
+		time.Sleep(time.Duration(ms) * time.Millisecond)
+		return ms
+	}, Options{})
+	server.AddFunction("fail", func() error { return errors.New("fail") }, Options{})
+	server.Handle()
+	defer server.Close()
+	client := NewTCPClient(server.URI())
+	defer client.Close()
+	client.SetMaxPoolSize(4)
+	var stub *futureStub
+	client.UseService(&stub)
+	checkFuture(t, stub.Hello("world"), "Hello world", nil)
+	if _, err := stub.Fail().Wait(); err == nil || err.Error() != "fail" {
+		t.Error(err)
+	}
+	results, err := All(
+		stub.Sleep(gocontext.Background(), 20), stub.Hello("all")).Wait()
+	if err != nil || len(results) != 2 ||
+		results[0].Interface() != 20 || results[1].Interface() != "Hello all" {
+		t.Error(results, err)
+	}
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err = stub.Sleep(ctx, 500).Wait(); err != gocontext.DeadlineExceeded {
+		t.Error(err)
+	}
+	future := client.InvokeAsync("hello", []reflect.Value{reflect.ValueOf("async")},
+		&InvokeSettings{ResultTypes: []reflect.Type{reflect.TypeOf("")}})
+	checkFuture(t, future, "Hello async", nil)
+}
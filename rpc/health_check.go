@@ -0,0 +1,276 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/health_check.go                                    *
+ *                                                        *
+ * hprose client health check for Go.                     *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	gocontext "context"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// HealthChecker probes every service address of the client in background.
+//
+// Method is the remote method which is invoked without arguments as the
+// probe, the default is "#" which is published by every hprose service.
+// The probes are sent every Interval and timeout after Timeout. A service
+// address becomes unhealthy after UnhealthyThreshold failed probes in a row,
+// and becomes healthy again after HealthyThreshold successful probes in a row.
+//
+// The unhealthy service addresses are skipped when selecting the service
+// address and when failswitching, unless all of them are unhealthy.
+type HealthChecker struct {
+	Method             string
+	Interval           time.Duration
+	Timeout            time.Duration
+	UnhealthyThreshold int
+	HealthyThreshold   int
+}
+
+// NewHealthChecker is the constructor of HealthChecker
+func NewHealthChecker() *HealthChecker {
+	return &HealthChecker{
+		Method:             "#",
+		Interval:           10 * time.Second,
+		Timeout:            5 * time.Second,
+		UnhealthyThreshold: 2,
+		HealthyThreshold:   1,
+	}
+}
+
+// EndpointStatus is the status of a service address
+//
+// Latency is the exponentially weighted moving average of the latencies of
+// the successful requests and probes. LastError is the error of the last
+// failed request or probe, it is nil after a successful probe.
+type EndpointStatus struct {
+	URI       string
+	Healthy   bool
+	Latency   time.Duration
+	LastError error
+	LastCheck time.Time
+}
+
+const latencyWeight = 0.2
+
+type endpoint struct {
+	healthy   bool
+	latency   time.Duration
+	lastError error
+	lastCheck time.Time
+	failures  int
+	successes int
+}
+
+type endpointManager struct {
+	endpoints map[string]*endpoint
+	checker   *HealthChecker
+	stop      chan struct{}
+	locker    sync.RWMutex
+}
+
+func (em *endpointManager) getEndpoint(uri string) *endpoint {
+	if em.endpoints == nil {
+		em.endpoints = make(map[string]*endpoint)
+	}
+	e := em.endpoints[uri]
+	if e == nil {
+		e = &endpoint{healthy: true}
+		em.endpoints[uri] = e
+	}
+	return e
+}
+
+func (em *endpointManager) isHealthy(uri string) bool {
+	em.locker.RLock()
+	defer em.locker.RUnlock()
+	if e := em.endpoints[uri]; e != nil {
+		return e.healthy
+	}
+	return true
+}
+
+func (em *endpointManager) recordLatency(uri string, latency time.Duration) {
+	em.locker.Lock()
+	e := em.getEndpoint(uri)
+	if e.latency == 0 {
+		e.latency = latency
+	} else {
+		e.latency += time.Duration(latencyWeight * float64(latency-e.latency))
+	}
+	em.locker.Unlock()
+}
+
+func (em *endpointManager) recordError(uri string, err error) {
+	em.locker.Lock()
+	em.getEndpoint(uri).lastError = err
+	em.locker.Unlock()
+}
+
+// recordProbe counts the result of a probe sent to uri and returns the
+// health of uri, changed is true when the health is changed.
+func (em *endpointManager) recordProbe(
+	uri string, err error, checker *HealthChecker) (healthy bool, changed bool) {
+	em.locker.Lock()
+	defer em.locker.Unlock()
+	e := em.getEndpoint(uri)
+	e.lastCheck = time.Now()
+	if err == nil {
+		e.lastError = nil
+		e.failures = 0
+		e.successes++
+		if !e.healthy && e.successes >= checker.HealthyThreshold {
+			e.healthy = true
+			return true, true
+		}
+		return e.healthy, false
+	}
+	e.lastError = err
+	e.successes = 0
+	e.failures++
+	if e.healthy && e.failures >= checker.UnhealthyThreshold {
+		e.healthy = false
+		return false, true
+	}
+	return e.healthy, false
+}
+
+// HealthChecker returns the health checker of the client
+func (client *baseClient) HealthChecker() *HealthChecker {
+	client.endpointManager.locker.RLock()
+	defer client.endpointManager.locker.RUnlock()
+	return client.endpointManager.checker
+}
+
+// SetHealthChecker set the health checker of the client and starts probing
+// the service addresses in background, nil means no health checking (the
+// default). The probing is stopped when the client is closed.
+func (client *baseClient) SetHealthChecker(checker *HealthChecker) {
+	em := &client.endpointManager
+	em.locker.Lock()
+	defer em.locker.Unlock()
+	if em.stop != nil {
+		close(em.stop)
+		em.stop = nil
+	}
+	for _, e := range em.endpoints {
+		e.healthy = true
+		e.failures = 0
+		e.successes = 0
+	}
+	em.checker = checker
+	if checker != nil {
+		em.stop = make(chan struct{})
+		go client.healthCheck(checker, em.stop)
+	}
+}
+
+// Endpoints returns the status of all the service addresses of the client
+func (client *baseClient) Endpoints() []EndpointStatus {
+	uriList := client.URIList()
+	em := &client.endpointManager
+	em.locker.RLock()
+	defer em.locker.RUnlock()
+	endpoints := make([]EndpointStatus, len(uriList))
+	for i, uri := range uriList {
+		endpoints[i] = EndpointStatus{URI: uri, Healthy: true}
+		if e := em.endpoints[uri]; e != nil {
+			endpoints[i].Healthy = e.healthy
+			endpoints[i].Latency = e.latency
+			endpoints[i].LastError = e.lastError
+			endpoints[i].LastCheck = e.lastCheck
+		}
+	}
+	return endpoints
+}
+
+func (client *baseClient) healthCheck(
+	checker *HealthChecker, stop chan struct{}) {
+	interval := checker.Interval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		var wg sync.WaitGroup
+		for _, uri := range client.URIList() {
+			wg.Add(1)
+			go func(uri string) {
+				defer wg.Done()
+				client.probe(uri, checker, stop)
+			}(uri)
+		}
+		wg.Wait()
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (client *baseClient) probe(
+	uri string, checker *HealthChecker, stop chan struct{}) {
+	method := checker.Method
+	if method == "" {
+		method = "#"
+	}
+	ctx, cancel := gocontext.WithCancel(gocontext.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	context := client.acquireContext()
+	defer client.releaseContext(context)
+	client.initClientContext(context, &InvokeSettings{
+		Simple:      true,
+		Timeout:     checker.Timeout,
+		ResultTypes: []reflect.Type{interfaceType},
+	})
+	context.ctx = ctx
+	context.name = method
+	context.uri = uri
+	start := time.Now()
+	request, err := client.codec.EncodeRequest(method, nil, context)
+	var response []byte
+	if err == nil {
+		response, err = client.handlerManager.beforeFilterHandler(request, context)
+	}
+	if err == nil {
+		_, err = client.codec.DecodeResponse(response, nil, context)
+	}
+	if ctx.Err() != nil {
+		return
+	}
+	if err == nil {
+		client.endpointManager.recordLatency(uri, time.Since(start))
+	}
+	healthy, changed := client.endpointManager.recordProbe(uri, err, checker)
+	if changed {
+		if event, ok := client.event.(onHealthChangeEvent); ok {
+			event.OnHealthChange(uri, healthy)
+		}
+	}
+}
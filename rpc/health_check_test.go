@@ -0,0 +1,167 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/health_check_test.go                               *
+ *                                                        *
+ * hprose client health checking test for Go.             *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitUntil polls f until it returns true or timeout
+func waitUntil(timeout time.Duration, f func() bool) bool {
+	deadline := time.Now().Add(timeout)
+	for !f() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return true
+}
+
+func TestRecordProbe(t *testing.T) {
+	var em endpointManager
+	checker := NewHealthChecker()
+	checker.UnhealthyThreshold = 2
+	checker.HealthyThreshold = 2
+	uri := testURIList[0]
+	if healthy, changed := em.recordProbe(uri, ErrTimeout, checker); !healthy || changed {
+		t.Error(healthy, changed)
+	}
+	if healthy, changed := em.recordProbe(uri, ErrTimeout, checker); healthy || !changed {
+		t.Error(healthy, changed)
+	}
+	if em.isHealthy(uri) || em.endpoints[uri].lastError != ErrTimeout {
+		t.Error("the endpoint should be unhealthy")
+	}
+	if healthy, changed := em.recordProbe(uri, nil, checker); healthy || changed {
+		t.Error(healthy, changed)
+	}
+	if healthy, changed := em.recordProbe(uri, nil, checker); !healthy || !changed {
+		t.Error(healthy, changed)
+	}
+	if em.endpoints[uri].lastError != nil {
+		t.Error(em.endpoints[uri].lastError)
+	}
+	if !em.isHealthy(testURIList[1]) {
+		t.Error("the unknown endpoint is healthy")
+	}
+}
+
+func TestRecordLatency(t *testing.T) {
+	var em endpointManager
+	uri := testURIList[0]
+	em.recordLatency(uri, 100*time.Millisecond)
+	em.recordLatency(uri, 200*time.Millisecond)
+	if latency := em.endpoints[uri].latency; latency != 120*time.Millisecond {
+		t.Error(latency)
+	}
+}
+
+type healthEvent struct {
+	sync.Mutex
+	changes []string
+}
+
+func (e *healthEvent) OnHealthChange(uri string, healthy bool) {
+	e.Lock()
+	e.changes = append(e.changes, uri)
+	e.Unlock()
+}
+
+func (e *healthEvent) count() int {
+	e.Lock()
+	defer e.Unlock()
+	return len(e.changes)
+}
+
+func TestHealthChecker(t *testing.T) {
+	var down int32
+	servers := make([]*TCPServer, 2)
+	for i, name := range []string{"s1", "s2"} {
+		name := name
+		servers[i] = NewTCPServer("tcp://127.0.0.1:0")
+		servers[i].AddFunction("who", func() string { return name }, Options{})
+		servers[i].AddFunction("ping", func() error {
+			if name == "s2" && atomic.LoadInt32(&down) == 1 {
+				return errors.New("down")
+			}
+			return nil
+		}, Options{})
+		servers[i].Handle()
+		defer servers[i].Close()
+	}
+	client := NewTCPClient(servers[0].URI(), servers[1].URI())
+	defer client.Close()
+	event := new(healthEvent)
+	client.SetEvent(event)
+	client.SetRetry(0)
+	checker := NewHealthChecker()
+	checker.Interval = 20 * time.Millisecond
+	checker.Method = "ping"
+	checker.Timeout = time.Second
+	client.SetHealthChecker(checker)
+	if !waitUntil(time.Second, func() bool {
+		for _, e := range client.Endpoints() {
+			if e.LastCheck.IsZero() || e.Latency == 0 || !e.Healthy {
+				return false
+			}
+		}
+		return true
+	}) {
+		t.Fatal(client.Endpoints())
+	}
+	atomic.StoreInt32(&down, 1)
+	if !waitUntil(time.Second, func() bool { return event.count() == 1 }) {
+		t.Fatal(client.Endpoints())
+	}
+	for _, e := range client.Endpoints() {
+		if e.Healthy != (e.URI == servers[0].URI()) {
+			t.Error(e)
+		}
+	}
+	var stub *struct{ Who func() (string, error) }
+	client.UseService(&stub)
+	client.SetBalancer(NewRoundRobinBalancer())
+	for i := 0; i < 10; i++ {
+		if s, err := stub.Who(); s != "s1" || err != nil {
+			t.Error(s, err)
+		}
+	}
+	atomic.StoreInt32(&down, 0)
+	if !waitUntil(time.Second, func() bool { return event.count() == 2 }) {
+		t.Fatal(client.Endpoints())
+	}
+	counts := make(map[string]int)
+	for i := 0; i < 10; i++ {
+		s, _ := stub.Who()
+		counts[s]++
+	}
+	if counts["s1"] != 5 || counts["s2"] != 5 {
+		t.Error(counts)
+	}
+	client.SetHealthChecker(nil)
+	if client.HealthChecker() != nil {
+		t.Error("the health checker is not removed")
+	}
+}
@@ -0,0 +1,213 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/hedge.go                                           *
+ *                                                        *
+ * hprose client request hedging for Go.                  *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	gocontext "context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	latencySamples    = 128
+	minLatencySamples = 16
+)
+
+type latencyWindow struct {
+	samples []time.Duration
+	next    int
+}
+
+func (w *latencyWindow) add(latency time.Duration) {
+	if len(w.samples) < latencySamples {
+		w.samples = append(w.samples, latency)
+		return
+	}
+	w.samples[w.next] = latency
+	w.next = (w.next + 1) % latencySamples
+}
+
+func (w *latencyWindow) quantile(q float64) (time.Duration, bool) {
+	n := len(w.samples)
+	if n < minLatencySamples {
+		return 0, false
+	}
+	samples := make([]time.Duration, n)
+	copy(samples, w.samples)
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	i := int(math.Ceil(q*float64(n))) - 1
+	if i < 0 {
+		i = 0
+	} else if i >= n {
+		i = n - 1
+	}
+	return samples[i], true
+}
+
+type latencyManager struct {
+	windows map[string]*latencyWindow
+	locker  sync.Mutex
+}
+
+func (lm *latencyManager) record(name string, latency time.Duration) {
+	lm.locker.Lock()
+	if lm.windows == nil {
+		lm.windows = make(map[string]*latencyWindow)
+	}
+	w := lm.windows[name]
+	if w == nil {
+		w = new(latencyWindow)
+		lm.windows[name] = w
+	}
+	w.add(latency)
+	lm.locker.Unlock()
+}
+
+func (lm *latencyManager) quantile(
+	name string, q float64) (time.Duration, bool) {
+	lm.locker.Lock()
+	defer lm.locker.Unlock()
+	if w := lm.windows[name]; w != nil {
+		return w.quantile(q)
+	}
+	return 0, false
+}
+
+// hedgeURIs is the set of the service addresses which the requests of a
+// hedged invoking are being sent to, they are skipped when selecting the
+// service address for the other requests of the invoking.
+type hedgeURIs struct {
+	uris   map[string]bool
+	locker sync.Mutex
+}
+
+func (h *hedgeURIs) used(uri string) bool {
+	h.locker.Lock()
+	defer h.locker.Unlock()
+	return h.uris[uri]
+}
+
+func (h *hedgeURIs) use(uri string) {
+	h.locker.Lock()
+	h.uris[uri] = true
+	h.locker.Unlock()
+}
+
+func (h *hedgeURIs) release(uri string) {
+	h.locker.Lock()
+	delete(h.uris, uri)
+	h.locker.Unlock()
+}
+
+// hedgeDelay returns the delay of the hedged request, ok is false if the
+// request should not be hedged.
+//
+// The request is hedged only when it is idempotent, not oneway, and the
+// client has more than one service address. The delay is the HedgeQuantile
+// (for example, 0.95 for the 95th percentile) of the latencies of the recent
+// invokings of the method, or Hedge if HedgeQuantile is 0 or there are not
+// enough latencies.
+func (client *baseClient) hedgeDelay(
+	context *ClientContext) (delay time.Duration, ok bool) {
+	if !context.Idempotent || context.Oneway ||
+		(context.Hedge <= 0 && context.HedgeQuantile <= 0) ||
+		len(client.URIList()) < 2 {
+		return 0, false
+	}
+	if context.HedgeQuantile > 0 {
+		if delay, ok = client.latencyManager.quantile(
+			context.name, context.HedgeQuantile); ok {
+			return
+		}
+	}
+	return context.Hedge, context.Hedge > 0
+}
+
+func (client *baseClient) cloneContext(
+	context *ClientContext,
+	ctx gocontext.Context,
+	hedging *hedgeURIs) *ClientContext {
+	clone := client.acquireContext()
+	*clone = *context
+	clone.initBaseContext()
+	for key, value := range context.userData {
+		clone.userData[key] = value
+	}
+	clone.ctx = ctx
+	clone.hedging = hedging
+	return clone
+}
+
+type hedgeResult struct {
+	context  *ClientContext
+	response []byte
+	err      error
+}
+
+// hedgeSendRequest sends the request, and sends it again to another service
+// address if there is no response after delay. The first successful response
+// is returned and the other request is canceled.
+func (client *baseClient) hedgeSendRequest(
+	request []byte,
+	context *ClientContext,
+	delay time.Duration) (response []byte, err error) {
+	ctx, cancel := gocontext.WithCancel(context.Context())
+	defer cancel()
+	hedging := &hedgeURIs{uris: make(map[string]bool)}
+	results := make(chan hedgeResult, 2)
+	send := func(c *ClientContext) {
+		response, err := client.sendRequest(request, c)
+		results <- hedgeResult{c, response, err}
+	}
+	go send(client.cloneContext(context, ctx, hedging))
+	pending := 1
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	for pending > 0 {
+		select {
+		case <-timer.C:
+			hedge := client.cloneContext(context, ctx, hedging)
+			hedge.Retry = 0
+			hedge.Failswitch = false
+			pending++
+			go send(hedge)
+		case result := <-results:
+			pending--
+			response, err = result.response, result.err
+			if err == nil {
+				context.uri = result.context.uri
+				context.Retried = result.context.Retried
+				context.userData = result.context.userData
+			}
+			client.releaseContext(result.context)
+			if err == nil {
+				go func(pending int) {
+					for ; pending > 0; pending-- {
+						client.releaseContext((<-results).context)
+					}
+				}(pending)
+				return
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,231 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/hedge_test.go                                      *
+ *                                                        *
+ * hprose client hedged request test for Go.              *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLatencyQuantile(t *testing.T) {
+	w := new(latencyWindow)
+	for i := 1; i < minLatencySamples; i++ {
+		w.add(time.Duration(i) * time.Millisecond)
+	}
+	if _, ok := w.quantile(0.5); ok {
+		t.Error("the quantile of too few samples")
+	}
+	// 1ms ... 100ms out of order
+	w.samples = w.samples[:0]
+	for _, i := range rangeShuffle(100) {
+		w.add(time.Duration(i) * time.Millisecond)
+	}
+	tests := map[float64]time.Duration{
+		0:    time.Millisecond,
+		0.01: time.Millisecond,
+		0.5:  50 * time.Millisecond,
+		0.95: 95 * time.Millisecond,
+		0.99: 99 * time.Millisecond,
+		1:    100 * time.Millisecond,
+		2:    100 * time.Millisecond,
+	}
+	for q, want := range tests {
+		if d, ok := w.quantile(q); !ok || d != want {
+			t.Error(q, d, ok)
+		}
+	}
+}
+
+func rangeShuffle(n int) []int {
+	values := make([]int, n)
+	for i := range values {
+		values[i] = i + 1
+	}
+	// a fixed permutation, so the window is not filled in order
+	for i := range values {
+		j := (i*7 + 3) % n
+		values[i], values[j] = values[j], values[i]
+	}
+	return values
+}
+
+func TestLatencyWindowWrap(t *testing.T) {
+	w := new(latencyWindow)
+	for i := 0; i < latencySamples; i++ {
+		w.add(time.Second)
+	}
+	// the oldest samples are replaced
+	for i := 0; i < latencySamples/2+1; i++ {
+		w.add(time.Millisecond)
+	}
+	if len(w.samples) != latencySamples {
+		t.Error(len(w.samples))
+	}
+	if d, _ := w.quantile(0.5); d != time.Millisecond {
+		t.Error(d)
+	}
+	if d, _ := w.quantile(1); d != time.Second {
+		t.Error(d)
+	}
+}
+
+func TestLatencyManager(t *testing.T) {
+	var lm latencyManager
+	if _, ok := lm.quantile("a", 0.5); ok {
+		t.Error("the quantile of no samples")
+	}
+	for i := 0; i < minLatencySamples; i++ {
+		lm.record("a", time.Millisecond)
+		lm.record("b", time.Second)
+	}
+	if d, ok := lm.quantile("a", 0.9); !ok || d != time.Millisecond {
+		t.Error(d, ok)
+	}
+	if d, ok := lm.quantile("b", 0.9); !ok || d != time.Second {
+		t.Error(d, ok)
+	}
+}
+
+func TestGetHedgeValue(t *testing.T) {
+	type hedgeTags struct {
+		A func() `hedge:"20ms"`
+		B func() `hedge:"p95"`
+		C func() `hedge:"P99.9, 30ms"`
+		D func()
+	}
+	tests := map[string]struct {
+		delay    time.Duration
+		quantile float64
+	}{
+		"A": {20 * time.Millisecond, 0},
+		"B": {0, 0.95},
+		"C": {30 * time.Millisecond, 0.999},
+		"D": {0, 0},
+	}
+	st := reflect.TypeOf(hedgeTags{})
+	for name, want := range tests {
+		sf, _ := st.FieldByName(name)
+		delay, quantile := getHedgeValue(sf.Tag, "hedge")
+		if delay != want.delay || quantile < want.quantile-1e-9 || quantile > want.quantile+1e-9 {
+			t.Error(name, delay, quantile)
+		}
+	}
+}
+
+func TestHedgeDelay(t *testing.T) {
+	client := NewTCPClient(testURIList...)
+	defer client.Close()
+	context := new(ClientContext)
+	context.name = "test"
+	context.Idempotent = true
+	context.Hedge = 20 * time.Millisecond
+	if delay, ok := client.hedgeDelay(context); !ok || delay != context.Hedge {
+		t.Error(delay, ok)
+	}
+	context.HedgeQuantile = 0.5
+	// Hedge is used until there are enough latencies
+	if delay, ok := client.hedgeDelay(context); !ok || delay != context.Hedge {
+		t.Error(delay, ok)
+	}
+	for i := 0; i < minLatencySamples; i++ {
+		client.latencyManager.record("test", 5*time.Millisecond)
+	}
+	if delay, ok := client.hedgeDelay(context); !ok || delay != 5*time.Millisecond {
+		t.Error(delay, ok)
+	}
+	context.Oneway = true
+	if _, ok := client.hedgeDelay(context); ok {
+		t.Error("the oneway request is hedged")
+	}
+	context.Oneway = false
+	context.Idempotent = false
+	if _, ok := client.hedgeDelay(context); ok {
+		t.Error("the non-idempotent request is hedged")
+	}
+	context.Idempotent = true
+	client.SetURIList(testURIList[:1])
+	if _, ok := client.hedgeDelay(context); ok {
+		t.Error("the request is hedged to the same address")
+	}
+}
+
+type hedgeStub struct {
+	Who      func() (string, error) `idempotent:"true" hedge:"20ms"`
+	WhoOnce  func() (string, error) `name:"who" hedge:"20ms"`
+	WhoQuant func() (string, error) `name:"who" idempotent:"true" hedge:"p50,30ms"`
+}
+
+func newDelayedServer(name string, delay *int64, calls *int32) *TCPServer {
+	server := NewTCPServer("tcp://127.0.0.1:0")
+	server.AddFunction("who", func() string {
+		atomic.AddInt32(calls, 1)
+		time.Sleep(time.Duration(atomic.LoadInt64(delay)))
+		return name
+	}, Options{})
+	server.Handle()
+	return server
+}
+
+func TestHedge(t *testing.T) {
+	d1, d2 := int64(300*time.Millisecond), int64(0)
+	var c1, c2 int32
+	s1 := newDelayedServer("s1", &d1, &c1)
+	defer s1.Close()
+	s2 := newDelayedServer("s2", &d2, &c2)
+	defer s2.Close()
+	client := NewTCPClient(s1.URI(), s2.URI())
+	defer client.Close()
+	client.SetMaxPoolSize(4)
+	client.SetBalancer(NewRoundRobinBalancer())
+	var stub *hedgeStub
+	client.UseService(&stub)
+	for i := 0; i < 4; i++ {
+		start := time.Now()
+		s, err := stub.Who()
+		if elapsed := time.Since(start); s != "s2" || err != nil || elapsed > 200*time.Millisecond {
+			t.Error(i, s, err, elapsed)
+		}
+	}
+	// the requests to s1 are hedged to s2
+	if atomic.LoadInt32(&c1) == 0 || atomic.LoadInt32(&c2) != 4 {
+		t.Error(atomic.LoadInt32(&c1), atomic.LoadInt32(&c2))
+	}
+	// the non-idempotent request is not hedged
+	counts := make(map[string]int)
+	for i := 0; i < 2; i++ {
+		s, _ := stub.WhoOnce()
+		counts[s]++
+	}
+	if counts["s1"] != 1 || counts["s2"] != 1 {
+		t.Error(counts)
+	}
+	atomic.StoreInt64(&d1, int64(time.Millisecond))
+	atomic.StoreInt64(&d2, int64(time.Millisecond))
+	for i := 0; i < minLatencySamples; i++ {
+		if _, err := stub.WhoQuant(); err != nil {
+			t.Error(err)
+		}
+	}
+	if d, ok := client.latencyManager.quantile("who", 0.5); !ok || d > 100*time.Millisecond {
+		t.Error(d, ok)
+	}
+}
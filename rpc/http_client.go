@@ -12,7 +12,7 @@
  *                                                        *
  * hprose http client for Go.                             *
  *                                                        *
- * LastModified: Oct 11, 2016                             *
+ * LastModified: Oct 18, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -57,6 +57,7 @@ func NewHTTPClient(uri ...string) (client *HTTPClient) {
 	if DisableGlobalCookie {
 		client.Jar, _ = cookiejar.New(nil)
 	}
+	client.setURIList = client.SetURIList
 	client.SetURIList(uri)
 	client.SendAndReceive = client.sendAndReceive
 	return
@@ -127,7 +128,13 @@ func (client *HTTPClient) sendAndReceive(
 	client.cond.L.Lock()
 	client.limit()
 	client.cond.L.Unlock()
-	req, err := http.NewRequest("POST", client.uri, hio.NewByteReader(data))
+	defer func() {
+		client.cond.L.Lock()
+		client.unlimit()
+		client.cond.L.Unlock()
+	}()
+	req, err := http.NewRequestWithContext(
+		context.Context(), "POST", context.URI(), hio.NewByteReader(data))
 	if err != nil {
 		return nil, err
 	}
@@ -137,18 +144,15 @@ func (client *HTTPClient) sendAndReceive(
 		}
 	}
 	req.ContentLength = int64(len(data))
-	req.Header.Set("Content-Type", "application/hprose")
+	req.Header.Set("Content-Type", client.codec.ContentType())
 	client.Client.Timeout = context.Timeout
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	data, err = ioutil.ReadAll(resp.Body)
-	if err == nil {
-		err = resp.Body.Close()
+	if e := resp.Body.Close(); err == nil {
+		err = e
 	}
-	client.cond.L.Lock()
-	client.unlimit()
-	client.cond.L.Unlock()
 	return data, err
 }
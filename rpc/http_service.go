@@ -12,7 +12,7 @@
  *                                                        *
  * hprose http service for Go.                            *
  *                                                        *
- * LastModified: Oct 9, 2016                              *
+ * LastModified: Oct 18, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -151,7 +151,7 @@ func (service *HTTPService) sendHeader(context *HTTPContext) (err error) {
 		return err
 	}
 	header := context.Response.Header()
-	header.Set("Content-Type", "text/plain")
+	header.Set("Content-Type", service.contentType())
 	if service.P3P {
 		header.Set("P3P",
 			`CP="CAO DSP COR CUR ADM DEV TAI PSA PSD IVAi IVDi `+
@@ -0,0 +1,339 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/json_codec.go                                      *
+ *                                                        *
+ * hprose rpc json codec for Go.                          *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// JSONCodec is the JSON serialization codec.
+//
+// The request is a JSON object like:
+//
+//		{"method":"hello","params":["world"],"byref":false}
+//
+// The response is a JSON object like:
+//
+//		{"result":"Hello world","args":["world"]}
+//		{"error":"error message"}
+//		{"functions":["hello"]}
+//
+// A batch request or response is a JSON array of them. The request without
+// method asks for the function list.
+//
+// The values are serialized by encoding/json, so the View, UTC, DateOnly and
+// TimePrecision settings are not applied, use the json struct tags and the
+// json.Marshaler interface to control the output instead.
+type JSONCodec struct{}
+
+type jsonRequest struct {
+	Method string            `json:"method,omitempty"`
+	Params []json.RawMessage `json:"params,omitempty"`
+	ByRef  bool              `json:"byref,omitempty"`
+}
+
+type jsonResponse struct {
+	Result    json.RawMessage   `json:"result,omitempty"`
+	Args      []json.RawMessage `json:"args,omitempty"`
+	Error     *string           `json:"error,omitempty"`
+	Functions []string          `json:"functions,omitempty"`
+}
+
+func marshalJSONValues(values []reflect.Value) ([]json.RawMessage, error) {
+	raws := make([]json.RawMessage, len(values))
+	for i, v := range values {
+		data, err := json.Marshal(v.Interface())
+		if err != nil {
+			return nil, err
+		}
+		raws[i] = data
+	}
+	return raws, nil
+}
+
+// ContentType returns the MIME type of JSON
+func (JSONCodec) ContentType() string {
+	return "application/json"
+}
+
+// EncodeRequest encodes the remote call on the client
+func (JSONCodec) EncodeRequest(
+	name string,
+	args []reflect.Value,
+	context *ClientContext) ([]byte, error) {
+	params, err := marshalJSONValues(args)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonRequest{name, params, context.ByRef})
+}
+
+// DecodeResponse decodes the response on the client
+func (JSONCodec) DecodeResponse(
+	data []byte,
+	args []reflect.Value,
+	context *ClientContext) (results []reflect.Value, err error) {
+	if context.Oneway {
+		return
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if context.Mode == Raw || context.Mode == RawWithEndTag {
+		results = make([]reflect.Value, 1)
+		results[0] = reflect.ValueOf(data)
+		return
+	}
+	var response jsonResponse
+	if err = json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("Wrong Response: \r\n%s", data)
+	}
+	if response.Error != nil {
+		return nil, errors.New(*response.Error)
+	}
+	if context.Mode == Serialized {
+		results = make([]reflect.Value, 1)
+		results[0] = reflect.ValueOf([]byte(response.Result))
+	} else if results, err = unmarshalJSONResults(
+		response.Result, context.ResultTypes); err != nil {
+		return nil, err
+	}
+	n := len(response.Args)
+	if n > len(args) {
+		n = len(args)
+	}
+	for i := 0; i < n; i++ {
+		if err = json.Unmarshal(response.Args[i], args[i].Interface()); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+func unmarshalJSONResults(
+	data json.RawMessage,
+	resultTypes []reflect.Type) (results []reflect.Value, err error) {
+	n := len(resultTypes)
+	if n == 0 || data == nil {
+		return
+	}
+	results = make([]reflect.Value, n)
+	for i := 0; i < n; i++ {
+		results[i] = reflect.New(resultTypes[i])
+	}
+	if n == 1 {
+		err = json.Unmarshal(data, results[0].Interface())
+	} else {
+		var raws []json.RawMessage
+		if err = json.Unmarshal(data, &raws); err != nil {
+			return nil, err
+		}
+		for i := 0; i < n && i < len(raws); i++ {
+			if err = json.Unmarshal(raws[i], results[i].Interface()); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < n; i++ {
+		results[i] = results[i].Elem()
+	}
+	return
+}
+
+type jsonCall struct {
+	request *jsonRequest
+}
+
+func (call jsonCall) Name() string {
+	return call.request.Method
+}
+
+func (call jsonCall) ByRef() bool {
+	return call.request.ByRef
+}
+
+func (call jsonCall) Args(
+	method *Method,
+	newArgs func(count int) []reflect.Value) ([]reflect.Value, error) {
+	params := call.request.Params
+	count := len(params)
+	args := newArgs(count)
+	for i := 0; i < count; i++ {
+		p := reflect.New(args[i].Type())
+		if err := json.Unmarshal(params[i], p.Interface()); err != nil {
+			return nil, err
+		}
+		args[i].Set(p.Elem())
+	}
+	return args, nil
+}
+
+// DecodeRequest decodes the request on the service
+func (JSONCodec) DecodeRequest(
+	request []byte, context ServiceContext) ([]Call, error) {
+	data := bytes.TrimSpace(request)
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var requests []jsonRequest
+	if data[0] == '[' {
+		if err := json.Unmarshal(data, &requests); err != nil {
+			return nil, err
+		}
+	} else {
+		requests = make([]jsonRequest, 1)
+		if err := json.Unmarshal(data, &requests[0]); err != nil {
+			return nil, err
+		}
+		if requests[0].Method == "" {
+			return nil, nil
+		}
+	}
+	calls := make([]Call, len(requests))
+	for i := range requests {
+		calls[i] = jsonCall{&requests[i]}
+	}
+	return calls, nil
+}
+
+// EncodeResult encodes the result of one call on the service
+func (codec JSONCodec) EncodeResult(
+	args []reflect.Value,
+	results []reflect.Value,
+	context ServiceContext) []byte {
+	var response jsonResponse
+	var err error
+	switch context.Method().Mode {
+	case Raw, RawWithEndTag:
+		return results[0].Bytes()
+	case Serialized:
+		response.Result = results[0].Bytes()
+	default:
+		var result interface{}
+		switch len(results) {
+		case 0:
+		case 1:
+			result = results[0].Interface()
+		default:
+			a := make([]interface{}, len(results))
+			for i, v := range results {
+				a[i] = v.Interface()
+			}
+			result = a
+		}
+		if response.Result, err = json.Marshal(result); err != nil {
+			return codec.EncodeError(err.Error(), context)
+		}
+	}
+	if context.ByRef() {
+		if response.Args, err = marshalJSONValues(args); err != nil {
+			return codec.EncodeError(err.Error(), context)
+		}
+	}
+	data, err := json.Marshal(response)
+	if err != nil {
+		return codec.EncodeError(err.Error(), context)
+	}
+	return data
+}
+
+// EncodeError encodes the error of one call on the service
+func (JSONCodec) EncodeError(message string, context ServiceContext) []byte {
+	data, _ := json.Marshal(jsonResponse{Error: &message})
+	return data
+}
+
+// MergeResponse merges the results and errors of the calls to a response
+func (JSONCodec) MergeResponse(
+	responses [][]byte, context ServiceContext) []byte {
+	if len(responses) == 1 {
+		return responses[0]
+	}
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	buf.Write(bytes.Join(responses, []byte{','}))
+	buf.WriteByte(']')
+	return buf.Bytes()
+}
+
+// EncodeBatch merges the requests to one request on the client
+func (JSONCodec) EncodeBatch(
+	requests [][]byte, context *ClientContext) ([]byte, error) {
+	if len(requests) == 1 {
+		return requests[0], nil
+	}
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	buf.Write(bytes.Join(requests, []byte{','}))
+	buf.WriteByte(']')
+	return buf.Bytes(), nil
+}
+
+// SplitResponse splits the response of the batch request on the client
+func (JSONCodec) SplitResponse(
+	response []byte, count int, context *ClientContext) ([][]byte, error) {
+	data := bytes.TrimSpace(response)
+	if len(data) == 0 || data[0] != '[' {
+		return [][]byte{response}, nil
+	}
+	var raws []json.RawMessage
+	if err := json.Unmarshal(data, &raws); err != nil {
+		return nil, fmt.Errorf("Wrong Response: \r\n%s", response)
+	}
+	responses := make([][]byte, len(raws))
+	for i, raw := range raws {
+		responses[i] = raw
+	}
+	return responses, nil
+}
+
+// EncodeFunctionsRequest encodes the function list request on the client
+func (JSONCodec) EncodeFunctionsRequest(
+	context *ClientContext) ([]byte, error) {
+	return []byte("{}"), nil
+}
+
+// DecodeFunctions decodes the function list response on the client
+func (JSONCodec) DecodeFunctions(
+	response []byte, context *ClientContext) ([]string, error) {
+	var r jsonResponse
+	if err := json.Unmarshal(response, &r); err != nil {
+		return nil, fmt.Errorf("Wrong Response: \r\n%s", response)
+	}
+	if r.Error != nil {
+		return nil, errors.New(*r.Error)
+	}
+	return r.Functions, nil
+}
+
+// EncodeFunctions encodes the function list response on the service
+func (JSONCodec) EncodeFunctions(
+	names []string, context ServiceContext) []byte {
+	data, _ := json.Marshal(jsonResponse{Functions: names})
+	return data
+}
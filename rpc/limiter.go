@@ -12,14 +12,19 @@
  *                                                        *
  * hprose client requests limiter for Go.                 *
  *                                                        *
- * LastModified: Oct 2, 2016                              *
+ * LastModified: Oct 18, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
 
 package rpc
 
-import "sync"
+import (
+	gocontext "context"
+	"strings"
+	"sync"
+	"time"
+)
 
 type limiter struct {
 	cond                  sync.Cond
@@ -53,3 +58,259 @@ func (limiter *limiter) reset() {
 		limiter.cond.Signal()
 	}
 }
+
+// Limit is the rate limit and the concurrency limit of RateLimiter.
+//
+// Rate is the requests per second, and Burst is the max requests which can be
+// sent at once (at least 1), Rate 0 means no rate limit. MaxConcurrent is the
+// max requests in flight, 0 means no concurrency limit. When the limit is
+// reached, the request waits if Wait is true, otherwise it fails with
+// ErrRateLimited immediately.
+type Limit struct {
+	Rate          float64
+	Burst         int
+	MaxConcurrent int
+	Wait          bool
+}
+
+type limitState struct {
+	limit  Limit
+	tokens float64
+	last   time.Time
+	slots  chan struct{}
+	locker sync.Mutex
+}
+
+func newLimitState(limit Limit) *limitState {
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	state := &limitState{
+		limit:  limit,
+		tokens: float64(limit.Burst),
+		last:   time.Now(),
+	}
+	if limit.MaxConcurrent > 0 {
+		state.slots = make(chan struct{}, limit.MaxConcurrent)
+	}
+	return state
+}
+
+// reserve takes a token from the bucket, and returns the time to wait for it.
+func (state *limitState) reserve(deadline time.Time) (time.Duration, error) {
+	if state.limit.Rate <= 0 {
+		return 0, nil
+	}
+	state.locker.Lock()
+	defer state.locker.Unlock()
+	now := time.Now()
+	state.tokens += now.Sub(state.last).Seconds() * state.limit.Rate
+	if burst := float64(state.limit.Burst); state.tokens > burst {
+		state.tokens = burst
+	}
+	state.last = now
+	if state.tokens >= 1 {
+		state.tokens--
+		return 0, nil
+	}
+	wait := time.Duration(
+		(1 - state.tokens) / state.limit.Rate * float64(time.Second))
+	if !state.limit.Wait ||
+		(!deadline.IsZero() && now.Add(wait).After(deadline)) {
+		return 0, ErrRateLimited
+	}
+	state.tokens--
+	return wait, nil
+}
+
+func (state *limitState) refund() {
+	if state.limit.Rate > 0 {
+		state.locker.Lock()
+		state.tokens++
+		state.locker.Unlock()
+	}
+}
+
+func limitError(ctx gocontext.Context) error {
+	if ctx.Err() == gocontext.Canceled {
+		return ctx.Err()
+	}
+	return ErrRateLimited
+}
+
+func (state *limitState) acquire(
+	ctx gocontext.Context, deadline time.Time) error {
+	wait, err := state.reserve(deadline)
+	if err != nil {
+		return err
+	}
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			state.refund()
+			return limitError(ctx)
+		}
+	}
+	if state.slots == nil {
+		return nil
+	}
+	select {
+	case state.slots <- struct{}{}:
+		return nil
+	default:
+	}
+	if state.limit.Wait {
+		select {
+		case state.slots <- struct{}{}:
+			return nil
+		case <-ctx.Done():
+			state.refund()
+			return limitError(ctx)
+		case <-timeout:
+		}
+	}
+	state.refund()
+	return ErrRateLimited
+}
+
+func (state *limitState) release() {
+	if state.slots != nil {
+		<-state.slots
+	}
+}
+
+// RateLimiter limits the requests of the client per method and per service
+// address.
+//
+// The limits are applied to every request sent by the client, including the
+// retried and the hedged requests. The request which waits longer than its
+// Timeout or the deadline of its context fails with ErrRateLimited. The
+// requests failed with ErrRateLimited are not retried, and are not counted
+// as failures by the circuit breaker and the health checker.
+type RateLimiter struct {
+	methods   map[string]Limit
+	endpoints map[string]Limit
+	states    map[string]*limitState
+	locker    sync.Mutex
+}
+
+// NewRateLimiter is the constructor of RateLimiter
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{
+		methods:   make(map[string]Limit),
+		endpoints: make(map[string]Limit),
+		states:    make(map[string]*limitState),
+	}
+}
+
+// SetMethodLimit sets the limit of the method name, the limit with an empty
+// name is the default limit of every method which has no limit of its own.
+func (limiter *RateLimiter) SetMethodLimit(name string, limit Limit) {
+	limiter.locker.Lock()
+	limiter.methods[strings.ToLower(name)] = limit
+	limiter.resetStates("m:")
+	limiter.locker.Unlock()
+}
+
+// RemoveMethodLimit removes the limit of the method name
+func (limiter *RateLimiter) RemoveMethodLimit(name string) {
+	limiter.locker.Lock()
+	delete(limiter.methods, strings.ToLower(name))
+	limiter.resetStates("m:")
+	limiter.locker.Unlock()
+}
+
+// SetEndpointLimit sets the limit of the service address uri, the limit with
+// an empty uri is the default limit of every service address which has no
+// limit of its own.
+func (limiter *RateLimiter) SetEndpointLimit(uri string, limit Limit) {
+	limiter.locker.Lock()
+	limiter.endpoints[uri] = limit
+	limiter.resetStates("e:")
+	limiter.locker.Unlock()
+}
+
+// RemoveEndpointLimit removes the limit of the service address uri
+func (limiter *RateLimiter) RemoveEndpointLimit(uri string) {
+	limiter.locker.Lock()
+	delete(limiter.endpoints, uri)
+	limiter.resetStates("e:")
+	limiter.locker.Unlock()
+}
+
+// resetStates removes the states with prefix, the requests in flight release
+// the states which they acquired.
+func (limiter *RateLimiter) resetStates(prefix string) {
+	for key := range limiter.states {
+		if strings.HasPrefix(key, prefix) {
+			delete(limiter.states, key)
+		}
+	}
+}
+
+func (limiter *RateLimiter) state(
+	limits map[string]Limit, prefix string, key string) *limitState {
+	limiter.locker.Lock()
+	defer limiter.locker.Unlock()
+	if state, ok := limiter.states[prefix+key]; ok {
+		return state
+	}
+	limit, ok := limits[key]
+	if !ok {
+		limit, ok = limits[""]
+	}
+	var state *limitState
+	if ok {
+		state = newLimitState(limit)
+	}
+	limiter.states[prefix+key] = state
+	return state
+}
+
+// acquire waits for the limits of the method and the service address of the
+// request, release must be called when the request is done.
+func (limiter *RateLimiter) acquire(
+	context *ClientContext) (release func(), err error) {
+	ctx := context.Context()
+	deadline, _ := ctx.Deadline()
+	if context.Timeout > 0 {
+		timeout := time.Now().Add(context.Timeout)
+		if deadline.IsZero() || timeout.Before(deadline) {
+			deadline = timeout
+		}
+	}
+	method := limiter.state(
+		limiter.methods, "m:", strings.ToLower(context.name))
+	endpoint := limiter.state(limiter.endpoints, "e:", context.uri)
+	if method != nil {
+		if err = method.acquire(ctx, deadline); err != nil {
+			return nil, err
+		}
+	}
+	if endpoint != nil {
+		if err = endpoint.acquire(ctx, deadline); err != nil {
+			if method != nil {
+				method.release()
+				method.refund()
+			}
+			return nil, err
+		}
+	}
+	return func() {
+		if method != nil {
+			method.release()
+		}
+		if endpoint != nil {
+			endpoint.release()
+		}
+	}, nil
+}
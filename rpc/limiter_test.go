@@ -0,0 +1,267 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/limiter_test.go                                    *
+ *                                                        *
+ * hprose client rate limiter test for Go.                *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	gocontext "context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketBurst(t *testing.T) {
+	state := newLimitState(Limit{Rate: 10, Burst: 3})
+	for i := 0; i < 3; i++ {
+		if wait, err := state.reserve(time.Time{}); wait != 0 || err != nil {
+			t.Error(i, wait, err)
+		}
+	}
+	if _, err := state.reserve(time.Time{}); err != ErrRateLimited {
+		t.Error(err)
+	}
+	// 200ms later, 2 tokens are added
+	state.last = state.last.Add(-200 * time.Millisecond)
+	for i := 0; i < 2; i++ {
+		if wait, err := state.reserve(time.Time{}); wait != 0 || err != nil {
+			t.Error(i, wait, err)
+		}
+	}
+	if _, err := state.reserve(time.Time{}); err != ErrRateLimited {
+		t.Error(err)
+	}
+	// the tokens are never more than Burst
+	state.last = state.last.Add(-time.Hour)
+	for i := 0; i < 3; i++ {
+		state.reserve(time.Time{})
+	}
+	if _, err := state.reserve(time.Time{}); err != ErrRateLimited {
+		t.Error(err)
+	}
+	state.refund()
+	if wait, err := state.reserve(time.Time{}); wait != 0 || err != nil {
+		t.Error("the token is not refunded", wait, err)
+	}
+}
+
+func TestTokenBucketWait(t *testing.T) {
+	state := newLimitState(Limit{Rate: 10, Wait: true})
+	if state.limit.Burst != 1 {
+		t.Error(state.limit.Burst)
+	}
+	state.reserve(time.Time{})
+	// the waiting requests reserve the future tokens in order
+	for i := 1; i <= 2; i++ {
+		wait, err := state.reserve(time.Time{})
+		want := time.Duration(i) * 100 * time.Millisecond
+		if err != nil || wait > want || wait < want-10*time.Millisecond {
+			t.Error(i, wait, err)
+		}
+	}
+	tokens := state.tokens
+	if _, err := state.reserve(time.Now().Add(100 * time.Millisecond)); err != ErrRateLimited {
+		t.Error("the wait exceeds the deadline", err)
+	}
+	if state.tokens < tokens-0.1 {
+		t.Error("the token is taken by the failed reservation", tokens, state.tokens)
+	}
+	unlimited := newLimitState(Limit{})
+	for i := 0; i < 100; i++ {
+		if wait, err := unlimited.reserve(time.Time{}); wait != 0 || err != nil {
+			t.Error(wait, err)
+		}
+	}
+}
+
+func TestLimitStateAcquire(t *testing.T) {
+	state := newLimitState(Limit{Rate: 20, Wait: true})
+	ctx := gocontext.Background()
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := state.acquire(ctx, time.Time{}); err != nil {
+			t.Error(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Error("the requests are not delayed", elapsed)
+	}
+	canceled, cancel := gocontext.WithCancel(ctx)
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if err := state.acquire(canceled, time.Time{}); err != gocontext.Canceled {
+		t.Error(err)
+	}
+	// the token of the canceled request is refunded
+	time.Sleep(50 * time.Millisecond)
+	if wait, err := state.reserve(time.Time{}); wait > 10*time.Millisecond || err != nil {
+		t.Error(wait, err)
+	}
+}
+
+func TestLimitStateConcurrency(t *testing.T) {
+	ctx := gocontext.Background()
+	state := newLimitState(Limit{MaxConcurrent: 1})
+	if err := state.acquire(ctx, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := state.acquire(ctx, time.Time{}); err != ErrRateLimited {
+		t.Error(err)
+	}
+	state.release()
+	if err := state.acquire(ctx, time.Time{}); err != nil {
+		t.Error(err)
+	}
+	state = newLimitState(Limit{MaxConcurrent: 1, Wait: true})
+	state.acquire(ctx, time.Time{})
+	start := time.Now()
+	if err := state.acquire(ctx, time.Now().Add(20*time.Millisecond)); err != ErrRateLimited {
+		t.Error(err)
+	}
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond {
+		t.Error("the request does not wait", elapsed)
+	}
+	time.AfterFunc(10*time.Millisecond, state.release)
+	if err := state.acquire(ctx, time.Time{}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRateLimiterState(t *testing.T) {
+	limiter := NewRateLimiter()
+	if limiter.state(limiter.methods, "m:", "a") != nil {
+		t.Error("no limit")
+	}
+	limiter.SetMethodLimit("A", Limit{Rate: 1})
+	limiter.SetMethodLimit("", Limit{Rate: 2})
+	a := limiter.state(limiter.methods, "m:", "a")
+	b := limiter.state(limiter.methods, "m:", "b")
+	if a == nil || a.limit.Rate != 1 || b == nil || b.limit.Rate != 2 {
+		t.Fatal(a, b)
+	}
+	if limiter.state(limiter.methods, "m:", "a") != a {
+		t.Error("the state is not kept")
+	}
+	limiter.RemoveMethodLimit("a")
+	if a = limiter.state(limiter.methods, "m:", "a"); a == nil || a.limit.Rate != 2 {
+		t.Error(a)
+	}
+	limiter.SetEndpointLimit(testURIList[0], Limit{Rate: 3})
+	if e := limiter.state(limiter.endpoints, "e:", testURIList[0]); e == nil || e.limit.Rate != 3 {
+		t.Error(e)
+	}
+	if e := limiter.state(limiter.endpoints, "e:", testURIList[1]); e != nil {
+		t.Error(e)
+	}
+}
+
+type limitStub struct {
+	Slow func(gocontext.Context) (int, error)
+	Fast func(gocontext.Context) (int, error) `idempotent:"true"`
+}
+
+func TestRateLimiter(t *testing.T) {
+	var active, maxActive int32
+	server := NewTCPServer("tcp://127.0.0.1:0")
+	server.AddFunction("slow", func() int {
+		n := atomic.AddInt32(&active, 1)
+		for {
+			m := atomic.LoadInt32(&maxActive)
+			if n <= m || atomic.CompareAndSwapInt32(&maxActive, m, n) {
+				break
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+		atomic.AddInt32(&active, -1)
+		return 1
+	}, Options{})
+	server.AddFunction("fast", func() int { return 2 }, Options{})
+	server.Handle()
+	defer server.Close()
+	client := NewTCPClient(server.URI())
+	defer client.Close()
+	client.SetMaxPoolSize(8)
+	breaker := NewCircuitBreaker()
+	client.SetCircuitBreaker(breaker)
+	limiter := NewRateLimiter()
+	limiter.SetMethodLimit("SLOW", Limit{MaxConcurrent: 2, Wait: true})
+	client.SetRateLimiter(limiter)
+	var stub *limitStub
+	client.UseService(&stub)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if n, err := stub.Slow(gocontext.Background()); n != 1 || err != nil {
+				t.Error(n, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(&maxActive); n != 2 {
+		t.Error("max active", n)
+	}
+	limiter.SetMethodLimit("fast", Limit{Rate: 10, Burst: 2})
+	succeeded, limited := 0, 0
+	for i := 0; i < 5; i++ {
+		switch _, err := stub.Fast(gocontext.Background()); err {
+		case nil:
+			succeeded++
+		case ErrRateLimited:
+			limited++
+		default:
+			t.Error(err)
+		}
+	}
+	if succeeded != 2 || limited != 3 {
+		t.Error(succeeded, limited)
+	}
+	// the limited requests are not counted as failures
+	if state := breaker.State(server.URI()); state != CircuitClosed {
+		t.Error(state)
+	}
+	limiter.SetMethodLimit("fast", Limit{Rate: 20, Wait: true})
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if _, err := stub.Fast(gocontext.Background()); err != nil {
+			t.Error(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 180*time.Millisecond {
+		t.Error("the requests are not delayed", elapsed)
+	}
+	// the request fails at once if the wait exceeds the deadline
+	limiter.SetMethodLimit("fast", Limit{Rate: 1, Wait: true})
+	stub.Fast(gocontext.Background())
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), 100*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	if _, err := stub.Fast(ctx); err != ErrRateLimited {
+		t.Error(err)
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Error("the request waits", elapsed)
+	}
+	limiter.RemoveMethodLimit("fast")
+	limiter.SetEndpointLimit("", Limit{Rate: 1})
+	stub.Fast(gocontext.Background())
+	if _, err := stub.Fast(gocontext.Background()); err != ErrRateLimited {
+		t.Error(err)
+	}
+}
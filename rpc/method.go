@@ -12,7 +12,7 @@
  *                                                        *
  * hprose method manager for Go.                          *
  *                                                        *
- * LastModified: Oct 20, 2016                             *
+ * LastModified: Oct 18, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -23,6 +23,7 @@ import (
 	"reflect"
 	"strings"
 	"sync"
+	"time"
 )
 
 // Options is the options of the published service method
@@ -32,6 +33,10 @@ type Options struct {
 	Oneway         bool
 	NameSpace      string
 	JSONCompatible bool
+	UTC            bool
+	DateOnly       bool
+	TimePrecision  time.Duration
+	Location       *time.Location
 }
 
 // Method is the published service method
@@ -0,0 +1,683 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/msgpack.go                                         *
+ *                                                        *
+ * minimal MessagePack serialization for Go.              *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var errMsgpackExtension = errors.New("unsupported msgpack extension type")
+var errMsgpackTooDeep = errors.New("msgpack: exceeded max depth")
+
+// msgpackMaxDepth is the max nesting depth of the arrays and maps, so the
+// input can't overflow the stack.
+const msgpackMaxDepth = 10000
+
+type msgpackWriter struct {
+	buf           []byte
+	timePrecision time.Duration
+}
+
+func (w *msgpackWriter) writeNil() {
+	w.buf = append(w.buf, 0xc0)
+}
+
+func (w *msgpackWriter) writeBool(b bool) {
+	if b {
+		w.buf = append(w.buf, 0xc3)
+	} else {
+		w.buf = append(w.buf, 0xc2)
+	}
+}
+
+func (w *msgpackWriter) write8(tag byte, n uint8) {
+	w.buf = append(w.buf, tag, n)
+}
+
+func (w *msgpackWriter) write16(tag byte, n uint16) {
+	w.buf = append(w.buf, tag, byte(n>>8), byte(n))
+}
+
+func (w *msgpackWriter) write32(tag byte, n uint32) {
+	w.buf = append(w.buf, tag, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(w.buf[len(w.buf)-4:], n)
+}
+
+func (w *msgpackWriter) write64(tag byte, n uint64) {
+	w.buf = append(w.buf, tag, 0, 0, 0, 0, 0, 0, 0, 0)
+	binary.BigEndian.PutUint64(w.buf[len(w.buf)-8:], n)
+}
+
+func (w *msgpackWriter) writeUint(u uint64) {
+	switch {
+	case u < 128:
+		w.buf = append(w.buf, byte(u))
+	case u <= math.MaxUint8:
+		w.write8(0xcc, uint8(u))
+	case u <= math.MaxUint16:
+		w.write16(0xcd, uint16(u))
+	case u <= math.MaxUint32:
+		w.write32(0xce, uint32(u))
+	default:
+		w.write64(0xcf, u)
+	}
+}
+
+func (w *msgpackWriter) writeInt(i int64) {
+	switch {
+	case i >= 0:
+		w.writeUint(uint64(i))
+	case i >= -32:
+		w.buf = append(w.buf, byte(i))
+	case i >= math.MinInt8:
+		w.write8(0xd0, uint8(i))
+	case i >= math.MinInt16:
+		w.write16(0xd1, uint16(i))
+	case i >= math.MinInt32:
+		w.write32(0xd2, uint32(i))
+	default:
+		w.write64(0xd3, uint64(i))
+	}
+}
+
+func (w *msgpackWriter) writeHeader(n int, fix byte, tag16 byte, tag32 byte) {
+	switch {
+	case n < 16:
+		w.buf = append(w.buf, fix|byte(n))
+	case n <= math.MaxUint16:
+		w.write16(tag16, uint16(n))
+	default:
+		w.write32(tag32, uint32(n))
+	}
+}
+
+func (w *msgpackWriter) writeString(s string) {
+	n := len(s)
+	switch {
+	case n < 32:
+		w.buf = append(w.buf, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		w.write8(0xd9, uint8(n))
+	case n <= math.MaxUint16:
+		w.write16(0xda, uint16(n))
+	default:
+		w.write32(0xdb, uint32(n))
+	}
+	w.buf = append(w.buf, s...)
+}
+
+func (w *msgpackWriter) writeBytes(b []byte) {
+	n := len(b)
+	switch {
+	case n <= math.MaxUint8:
+		w.write8(0xc4, uint8(n))
+	case n <= math.MaxUint16:
+		w.write16(0xc5, uint16(n))
+	default:
+		w.write32(0xc6, uint32(n))
+	}
+	w.buf = append(w.buf, b...)
+}
+
+func (w *msgpackWriter) writeTime(t time.Time) {
+	if w.timePrecision > 0 {
+		t = t.Truncate(w.timePrecision)
+	}
+	w.buf = append(w.buf, 0xc7, 12, 0xff)
+	w.buf = append(w.buf, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0)
+	b := w.buf[len(w.buf)-12:]
+	binary.BigEndian.PutUint32(b, uint32(t.Nanosecond()))
+	binary.BigEndian.PutUint64(b[4:], uint64(t.Unix()))
+}
+
+func msgpackFieldName(f reflect.StructField) string {
+	if name := f.Tag.Get("msgpack"); name != "" {
+		return name
+	}
+	if name := strings.Split(f.Tag.Get("json"), ",")[0]; name != "" {
+		return name
+	}
+	return f.Name
+}
+
+func (w *msgpackWriter) writeValue(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Invalid:
+		w.writeNil()
+	case reflect.Bool:
+		w.writeBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		w.writeInt(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		w.writeUint(v.Uint())
+	case reflect.Float32:
+		w.write32(0xca, math.Float32bits(float32(v.Float())))
+	case reflect.Float64:
+		w.write64(0xcb, math.Float64bits(v.Float()))
+	case reflect.String:
+		w.writeString(v.String())
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			w.writeNil()
+			return nil
+		}
+		return w.writeValue(v.Elem())
+	case reflect.Slice:
+		if v.IsNil() {
+			w.writeNil()
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			w.writeBytes(v.Bytes())
+			return nil
+		}
+		fallthrough
+	case reflect.Array:
+		n := v.Len()
+		w.writeHeader(n, 0x90, 0xdc, 0xdd)
+		for i := 0; i < n; i++ {
+			if err := w.writeValue(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			w.writeNil()
+			return nil
+		}
+		w.writeHeader(v.Len(), 0x80, 0xde, 0xdf)
+		for _, key := range v.MapKeys() {
+			if err := w.writeValue(key); err != nil {
+				return err
+			}
+			if err := w.writeValue(v.MapIndex(key)); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		if v.Type() == timeType {
+			w.writeTime(v.Interface().(time.Time))
+			return nil
+		}
+		return w.writeStruct(v)
+	default:
+		return fmt.Errorf("msgpack: unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+func (w *msgpackWriter) writeValues(values []reflect.Value) error {
+	w.writeHeader(len(values), 0x90, 0xdc, 0xdd)
+	for _, v := range values {
+		if err := w.writeValue(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *msgpackWriter) writeStruct(v reflect.Value) error {
+	t := v.Type()
+	fields := make([]int, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath == "" && f.Tag.Get("msgpack") != "-" {
+			fields = append(fields, i)
+		}
+	}
+	w.writeHeader(len(fields), 0x80, 0xde, 0xdf)
+	for _, i := range fields {
+		w.writeString(msgpackFieldName(t.Field(i)))
+		if err := w.writeValue(v.Field(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type msgpackReader struct {
+	buf   []byte
+	off   int
+	depth int
+}
+
+func (r *msgpackReader) next(n int) ([]byte, error) {
+	if n < 0 || r.off+n > len(r.buf) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := r.buf[r.off : r.off+n]
+	r.off += n
+	return b, nil
+}
+
+func (r *msgpackReader) readUint(size int) (uint64, error) {
+	b, err := r.next(size)
+	if err != nil {
+		return 0, err
+	}
+	switch size {
+	case 1:
+		return uint64(b[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(b)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(b)), nil
+	}
+	return binary.BigEndian.Uint64(b), nil
+}
+
+func (r *msgpackReader) readInt(size int) (int64, error) {
+	u, err := r.readUint(size)
+	switch size {
+	case 1:
+		return int64(int8(u)), err
+	case 2:
+		return int64(int16(u)), err
+	case 4:
+		return int64(int32(u)), err
+	}
+	return int64(u), err
+}
+
+// checkLength checks that there are at least n values of the given minimum
+// size left, so the length from the input can't cause a huge allocation.
+func (r *msgpackReader) checkLength(n int, size int) error {
+	if n < 0 || n > (len(r.buf)-r.off)/size {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+func (r *msgpackReader) enter() error {
+	if r.depth >= msgpackMaxDepth {
+		return errMsgpackTooDeep
+	}
+	r.depth++
+	return nil
+}
+
+func (r *msgpackReader) leave() {
+	r.depth--
+}
+
+func (r *msgpackReader) readArray(n int) (interface{}, error) {
+	if err := r.checkLength(n, 1); err != nil {
+		return nil, err
+	}
+	if err := r.enter(); err != nil {
+		return nil, err
+	}
+	defer r.leave()
+	a := make([]interface{}, n)
+	for i := 0; i < n; i++ {
+		v, err := r.readValue()
+		if err != nil {
+			return nil, err
+		}
+		a[i] = v
+	}
+	return a, nil
+}
+
+func (r *msgpackReader) readMap(n int) (interface{}, error) {
+	if err := r.checkLength(n, 2); err != nil {
+		return nil, err
+	}
+	if err := r.enter(); err != nil {
+		return nil, err
+	}
+	defer r.leave()
+	m := make(map[interface{}]interface{}, n)
+	for i := 0; i < n; i++ {
+		k, err := r.readValue()
+		if err != nil {
+			return nil, err
+		}
+		switch k.(type) {
+		case []byte, []interface{}, map[interface{}]interface{}:
+			return nil, fmt.Errorf("msgpack: unhashable map key type %T", k)
+		}
+		v, err := r.readValue()
+		if err != nil {
+			return nil, err
+		}
+		m[k] = v
+	}
+	return m, nil
+}
+
+func (r *msgpackReader) readExt(n int) (interface{}, error) {
+	b, err := r.next(n + 1)
+	if err != nil {
+		return nil, err
+	}
+	if int8(b[0]) != -1 {
+		return nil, errMsgpackExtension
+	}
+	b = b[1:]
+	switch n {
+	case 4:
+		return time.Unix(int64(binary.BigEndian.Uint32(b)), 0), nil
+	case 8:
+		u := binary.BigEndian.Uint64(b)
+		return time.Unix(int64(u&0x3ffffffff), int64(u>>34)), nil
+	case 12:
+		nsec := binary.BigEndian.Uint32(b)
+		return time.Unix(int64(binary.BigEndian.Uint64(b[4:])), int64(nsec)), nil
+	}
+	return nil, errMsgpackExtension
+}
+
+// readValue reads a value, the map is unserialized as
+// map[interface{}]interface{}, the array is unserialized as []interface{}.
+func (r *msgpackReader) readValue() (interface{}, error) {
+	b, err := r.next(1)
+	if err != nil {
+		return nil, err
+	}
+	tag := b[0]
+	switch {
+	case tag <= 0x7f:
+		return int64(tag), nil
+	case tag >= 0xe0:
+		return int64(int8(tag)), nil
+	case tag >= 0x80 && tag <= 0x8f:
+		return r.readMap(int(tag & 0x0f))
+	case tag >= 0x90 && tag <= 0x9f:
+		return r.readArray(int(tag & 0x0f))
+	case tag >= 0xa0 && tag <= 0xbf:
+		s, err := r.next(int(tag & 0x1f))
+		return string(s), err
+	}
+	switch tag {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := r.readUint(1 << (tag - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		b, err := r.next(int(n))
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), b...), nil
+	case 0xc7, 0xc8, 0xc9:
+		n, err := r.readUint(1 << (tag - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return r.readExt(int(n))
+	case 0xca:
+		u, err := r.readUint(4)
+		return float64(math.Float32frombits(uint32(u))), err
+	case 0xcb:
+		u, err := r.readUint(8)
+		return math.Float64frombits(u), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		return r.readUint(1 << (tag - 0xcc))
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		return r.readInt(1 << (tag - 0xd0))
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return r.readExt(1 << (tag - 0xd4))
+	case 0xd9, 0xda, 0xdb:
+		n, err := r.readUint(1 << (tag - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		s, err := r.next(int(n))
+		return string(s), err
+	case 0xdc, 0xdd:
+		n, err := r.readUint(2 << (tag - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return r.readArray(int(n))
+	case 0xde, 0xdf:
+		n, err := r.readUint(2 << (tag - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return r.readMap(int(n))
+	}
+	return nil, fmt.Errorf("msgpack: unexpected tag 0x%x", tag)
+}
+
+// readRaw reads the raw bytes of the next value
+func (r *msgpackReader) readRaw() ([]byte, error) {
+	start := r.off
+	if _, err := r.readValue(); err != nil {
+		return nil, err
+	}
+	return r.buf[start:r.off], nil
+}
+
+func msgpackUnmarshal(data []byte, v reflect.Value, jsonCompatible bool) error {
+	r := &msgpackReader{buf: data}
+	x, err := r.readValue()
+	if err != nil {
+		return err
+	}
+	return msgpackAssign(v, x, jsonCompatible)
+}
+
+func msgpackGeneric(x interface{}, jsonCompatible bool) interface{} {
+	switch x := x.(type) {
+	case []interface{}:
+		for i, e := range x {
+			x[i] = msgpackGeneric(e, jsonCompatible)
+		}
+	case map[interface{}]interface{}:
+		if jsonCompatible {
+			m := make(map[string]interface{}, len(x))
+			for k, e := range x {
+				m[fmt.Sprint(k)] = msgpackGeneric(e, jsonCompatible)
+			}
+			return m
+		}
+		for k, e := range x {
+			x[k] = msgpackGeneric(e, jsonCompatible)
+		}
+	}
+	return x
+}
+
+func msgpackCastError(x interface{}, t reflect.Type) error {
+	return fmt.Errorf("msgpack: can't convert %T to %s", x, t)
+}
+
+// msgpackAssign sets the value x which returned by readValue to v
+func msgpackAssign(v reflect.Value, x interface{}, jsonCompatible bool) error {
+	t := v.Type()
+	if x == nil {
+		v.Set(reflect.Zero(t))
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Interface:
+		xv := reflect.ValueOf(msgpackGeneric(x, jsonCompatible))
+		if !xv.Type().AssignableTo(t) {
+			return msgpackCastError(x, t)
+		}
+		v.Set(xv)
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(t.Elem()))
+		}
+		return msgpackAssign(v.Elem(), x, jsonCompatible)
+	case reflect.Bool:
+		b, ok := x.(bool)
+		if !ok {
+			return msgpackCastError(x, t)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch x := x.(type) {
+		case int64:
+			v.SetInt(x)
+		case uint64:
+			v.SetInt(int64(x))
+		case float64:
+			v.SetInt(int64(x))
+		default:
+			return msgpackCastError(x, t)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		switch x := x.(type) {
+		case int64:
+			v.SetUint(uint64(x))
+		case uint64:
+			v.SetUint(x)
+		case float64:
+			v.SetUint(uint64(x))
+		default:
+			return msgpackCastError(x, t)
+		}
+	case reflect.Float32, reflect.Float64:
+		switch x := x.(type) {
+		case int64:
+			v.SetFloat(float64(x))
+		case uint64:
+			v.SetFloat(float64(x))
+		case float64:
+			v.SetFloat(x)
+		default:
+			return msgpackCastError(x, t)
+		}
+	case reflect.String:
+		switch x := x.(type) {
+		case string:
+			v.SetString(x)
+		case []byte:
+			v.SetString(string(x))
+		default:
+			return msgpackCastError(x, t)
+		}
+	case reflect.Slice, reflect.Array:
+		return msgpackAssignList(v, x, jsonCompatible)
+	case reflect.Map:
+		m, ok := x.(map[interface{}]interface{})
+		if !ok {
+			return msgpackCastError(x, t)
+		}
+		mv := reflect.MakeMap(t)
+		for k, e := range m {
+			key := reflect.New(t.Key()).Elem()
+			if err := msgpackAssign(key, k, jsonCompatible); err != nil {
+				return err
+			}
+			value := reflect.New(t.Elem()).Elem()
+			if err := msgpackAssign(value, e, jsonCompatible); err != nil {
+				return err
+			}
+			mv.SetMapIndex(key, value)
+		}
+		v.Set(mv)
+	case reflect.Struct:
+		return msgpackAssignStruct(v, x, jsonCompatible)
+	default:
+		return msgpackCastError(x, t)
+	}
+	return nil
+}
+
+func msgpackAssignList(v reflect.Value, x interface{}, jsonCompatible bool) error {
+	t := v.Type()
+	var a []interface{}
+	switch x := x.(type) {
+	case []byte:
+		if t.Elem().Kind() == reflect.Uint8 && t.Kind() == reflect.Slice {
+			v.SetBytes(x)
+			return nil
+		}
+		if t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8 {
+			return msgpackCastError(x, t)
+		}
+		a = make([]interface{}, len(x))
+		for i, b := range x {
+			a[i] = uint64(b)
+		}
+	case string:
+		if t.Elem().Kind() != reflect.Uint8 {
+			return msgpackCastError(x, t)
+		}
+		return msgpackAssignList(v, []byte(x), jsonCompatible)
+	case []interface{}:
+		a = x
+	default:
+		return msgpackCastError(x, t)
+	}
+	n := len(a)
+	if t.Kind() == reflect.Slice {
+		v.Set(reflect.MakeSlice(t, n, n))
+	} else if n > v.Len() {
+		n = v.Len()
+	}
+	for i := 0; i < n; i++ {
+		if err := msgpackAssign(v.Index(i), a[i], jsonCompatible); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func msgpackAssignStruct(v reflect.Value, x interface{}, jsonCompatible bool) error {
+	t := v.Type()
+	if tm, ok := x.(time.Time); ok {
+		if t != timeType {
+			return msgpackCastError(x, t)
+		}
+		v.Set(reflect.ValueOf(tm))
+		return nil
+	}
+	m, ok := x.(map[interface{}]interface{})
+	if !ok {
+		return msgpackCastError(x, t)
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" || f.Tag.Get("msgpack") == "-" {
+			continue
+		}
+		name := msgpackFieldName(f)
+		for k, e := range m {
+			if s, ok := k.(string); ok && strings.EqualFold(s, name) {
+				if err := msgpackAssign(v.Field(i), e, jsonCompatible); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,410 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/msgpack_codec.go                                   *
+ *                                                        *
+ * hprose rpc MessagePack codec for Go.                   *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// MsgPackCodec is the MessagePack serialization codec.
+//
+// The request and response have the same structure as JSONCodec, they are
+// MessagePack maps with the same keys, and the batch is a MessagePack array.
+// time.Time is serialized as the MessagePack timestamp extension, it is
+// truncated by the TimePrecision setting. The timestamp has no time zone,
+// so the UTC setting makes no difference, and the View and DateOnly settings
+// are not applied, the exported fields of a struct are always serialized.
+type MsgPackCodec struct{}
+
+// ContentType returns the MIME type of MessagePack
+func (MsgPackCodec) ContentType() string {
+	return "application/msgpack"
+}
+
+// EncodeRequest encodes the remote call on the client
+func (MsgPackCodec) EncodeRequest(
+	name string,
+	args []reflect.Value,
+	context *ClientContext) ([]byte, error) {
+	w := &msgpackWriter{timePrecision: context.TimePrecision}
+	n := 2
+	if context.ByRef {
+		n++
+	}
+	w.writeHeader(n, 0x80, 0xde, 0xdf)
+	w.writeString("method")
+	w.writeString(name)
+	w.writeString("params")
+	if err := w.writeValues(args); err != nil {
+		return nil, err
+	}
+	if context.ByRef {
+		w.writeString("byref")
+		w.writeBool(true)
+	}
+	return w.buf, nil
+}
+
+func (r *msgpackReader) readMapHeader() (int, error) {
+	b, err := r.next(1)
+	if err != nil {
+		return 0, err
+	}
+	switch tag := b[0]; {
+	case tag >= 0x80 && tag <= 0x8f:
+		return int(tag & 0x0f), nil
+	case tag == 0xde:
+		n, err := r.readUint(2)
+		return int(n), err
+	case tag == 0xdf:
+		n, err := r.readUint(4)
+		return int(n), err
+	}
+	return 0, errors.New("msgpack: map expected")
+}
+
+func (r *msgpackReader) readArrayHeader() (int, error) {
+	b, err := r.next(1)
+	if err != nil {
+		return 0, err
+	}
+	switch tag := b[0]; {
+	case tag >= 0x90 && tag <= 0x9f:
+		return int(tag & 0x0f), nil
+	case tag == 0xdc:
+		n, err := r.readUint(2)
+		return int(n), err
+	case tag == 0xdd:
+		n, err := r.readUint(4)
+		return int(n), err
+	}
+	return 0, errors.New("msgpack: array expected")
+}
+
+// EncodeBatch merges the requests to one request on the client
+func (MsgPackCodec) EncodeBatch(
+	requests [][]byte, context *ClientContext) ([]byte, error) {
+	n := len(requests)
+	if n == 1 {
+		return requests[0], nil
+	}
+	w := new(msgpackWriter)
+	w.writeHeader(n, 0x90, 0xdc, 0xdd)
+	for _, request := range requests {
+		w.buf = append(w.buf, request...)
+	}
+	return w.buf, nil
+}
+
+// SplitResponse splits the response of the batch request on the client
+func (MsgPackCodec) SplitResponse(
+	response []byte, count int, context *ClientContext) ([][]byte, error) {
+	r := &msgpackReader{buf: response}
+	n, err := r.readArrayHeader()
+	if err != nil {
+		return [][]byte{response}, nil
+	}
+	if err = r.checkLength(n, 1); err != nil {
+		return nil, err
+	}
+	responses := make([][]byte, n)
+	for i := 0; i < n; i++ {
+		if responses[i], err = r.readRaw(); err != nil {
+			return nil, err
+		}
+	}
+	return responses, nil
+}
+
+// DecodeResponse decodes the response on the client
+func (MsgPackCodec) DecodeResponse(
+	data []byte,
+	args []reflect.Value,
+	context *ClientContext) (results []reflect.Value, err error) {
+	if context.Oneway {
+		return
+	}
+	if len(data) == 0 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if context.Mode == Raw || context.Mode == RawWithEndTag {
+		results = make([]reflect.Value, 1)
+		results[0] = reflect.ValueOf(data)
+		return
+	}
+	r := &msgpackReader{buf: data}
+	n, err := r.readMapHeader()
+	if err != nil {
+		return nil, fmt.Errorf("Wrong Response: \r\n%s", data)
+	}
+	var result []byte
+	var byRefArgs interface{}
+	for i := 0; i < n; i++ {
+		var key interface{}
+		if key, err = r.readValue(); err != nil {
+			return nil, err
+		}
+		switch key {
+		case "result":
+			result, err = r.readRaw()
+		case "args":
+			byRefArgs, err = r.readValue()
+		case "error":
+			var message interface{}
+			if message, err = r.readValue(); err == nil {
+				return nil, errors.New(fmt.Sprint(message))
+			}
+		default:
+			_, err = r.readValue()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if context.Mode == Serialized {
+		results = make([]reflect.Value, 1)
+		results[0] = reflect.ValueOf(result)
+	} else if results, err = unmarshalMsgPackResults(result, context); err != nil {
+		return nil, err
+	}
+	if a, ok := byRefArgs.([]interface{}); ok {
+		for i := 0; i < len(a) && i < len(args); i++ {
+			err = msgpackAssign(args[i], a[i], context.JSONCompatible)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return
+}
+
+func unmarshalMsgPackResults(
+	data []byte,
+	context *ClientContext) (results []reflect.Value, err error) {
+	n := len(context.ResultTypes)
+	if n == 0 || data == nil {
+		return
+	}
+	results = make([]reflect.Value, n)
+	for i := 0; i < n; i++ {
+		results[i] = reflect.New(context.ResultTypes[i]).Elem()
+	}
+	if n == 1 {
+		err = msgpackUnmarshal(data, results[0], context.JSONCompatible)
+		return
+	}
+	r := &msgpackReader{buf: data}
+	x, err := r.readValue()
+	if err != nil {
+		return nil, err
+	}
+	a, ok := x.([]interface{})
+	if !ok {
+		return nil, msgpackCastError(x, reflect.TypeOf(a))
+	}
+	for i := 0; i < n && i < len(a); i++ {
+		err = msgpackAssign(results[i], a[i], context.JSONCompatible)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+type msgpackCall struct {
+	name   string
+	params []interface{}
+	byRef  bool
+}
+
+func (call *msgpackCall) Name() string {
+	return call.name
+}
+
+func (call *msgpackCall) ByRef() bool {
+	return call.byRef
+}
+
+func (call *msgpackCall) Args(
+	method *Method,
+	newArgs func(count int) []reflect.Value) ([]reflect.Value, error) {
+	jsonCompatible := method != nil && method.JSONCompatible
+	count := len(call.params)
+	args := newArgs(count)
+	for i := 0; i < count; i++ {
+		if err := msgpackAssign(args[i], call.params[i], jsonCompatible); err != nil {
+			return nil, err
+		}
+	}
+	return args, nil
+}
+
+func newMsgPackCall(x interface{}) (*msgpackCall, error) {
+	m, ok := x.(map[interface{}]interface{})
+	if !ok {
+		return nil, errors.New("msgpack: map expected")
+	}
+	call := new(msgpackCall)
+	call.name, _ = m["method"].(string)
+	call.params, _ = m["params"].([]interface{})
+	call.byRef, _ = m["byref"].(bool)
+	return call, nil
+}
+
+// DecodeRequest decodes the request on the service
+func (MsgPackCodec) DecodeRequest(
+	request []byte, context ServiceContext) ([]Call, error) {
+	if len(request) == 0 {
+		return nil, nil
+	}
+	r := &msgpackReader{buf: request}
+	x, err := r.readValue()
+	if err != nil {
+		return nil, err
+	}
+	if a, ok := x.([]interface{}); ok {
+		calls := make([]Call, len(a))
+		for i, e := range a {
+			if calls[i], err = newMsgPackCall(e); err != nil {
+				return nil, err
+			}
+		}
+		return calls, nil
+	}
+	call, err := newMsgPackCall(x)
+	if err != nil {
+		return nil, err
+	}
+	if call.name == "" {
+		return nil, nil
+	}
+	return []Call{call}, nil
+}
+
+// EncodeResult encodes the result of one call on the service
+func (codec MsgPackCodec) EncodeResult(
+	args []reflect.Value,
+	results []reflect.Value,
+	context ServiceContext) []byte {
+	mode := context.Method().Mode
+	if mode == Raw || mode == RawWithEndTag {
+		return results[0].Bytes()
+	}
+	w := &msgpackWriter{timePrecision: context.Method().TimePrecision}
+	n := 1
+	if context.ByRef() {
+		n++
+	}
+	w.writeHeader(n, 0x80, 0xde, 0xdf)
+	w.writeString("result")
+	var err error
+	switch {
+	case mode == Serialized:
+		w.buf = append(w.buf, results[0].Bytes()...)
+	case len(results) == 0:
+		w.writeNil()
+	case len(results) == 1:
+		err = w.writeValue(results[0])
+	default:
+		err = w.writeValues(results)
+	}
+	if err == nil && context.ByRef() {
+		w.writeString("args")
+		err = w.writeValues(args)
+	}
+	if err != nil {
+		return codec.EncodeError(err.Error(), context)
+	}
+	return w.buf
+}
+
+// EncodeError encodes the error of one call on the service
+func (MsgPackCodec) EncodeError(message string, context ServiceContext) []byte {
+	w := new(msgpackWriter)
+	w.writeHeader(1, 0x80, 0xde, 0xdf)
+	w.writeString("error")
+	w.writeString(message)
+	return w.buf
+}
+
+// MergeResponse merges the results and errors of the calls to a response
+func (MsgPackCodec) MergeResponse(
+	responses [][]byte, context ServiceContext) []byte {
+	n := len(responses)
+	if n == 1 {
+		return responses[0]
+	}
+	w := new(msgpackWriter)
+	w.writeHeader(n, 0x90, 0xdc, 0xdd)
+	for _, response := range responses {
+		w.buf = append(w.buf, response...)
+	}
+	return w.buf
+}
+
+// EncodeFunctionsRequest encodes the function list request on the client
+func (MsgPackCodec) EncodeFunctionsRequest(
+	context *ClientContext) ([]byte, error) {
+	w := new(msgpackWriter)
+	w.writeHeader(0, 0x80, 0xde, 0xdf)
+	return w.buf, nil
+}
+
+// DecodeFunctions decodes the function list response on the client
+func (MsgPackCodec) DecodeFunctions(
+	response []byte, context *ClientContext) (names []string, err error) {
+	r := &msgpackReader{buf: response}
+	n, err := r.readMapHeader()
+	if err != nil {
+		return nil, fmt.Errorf("Wrong Response: \r\n%s", response)
+	}
+	for i := 0; i < n; i++ {
+		var key, value interface{}
+		if key, err = r.readValue(); err != nil {
+			return nil, err
+		}
+		if value, err = r.readValue(); err != nil {
+			return nil, err
+		}
+		switch key {
+		case "functions":
+			list, _ := value.([]interface{})
+			for _, name := range list {
+				names = append(names, fmt.Sprint(name))
+			}
+		case "error":
+			return nil, errors.New(fmt.Sprint(value))
+		}
+	}
+	return names, nil
+}
+
+// EncodeFunctions encodes the function list response on the service
+func (MsgPackCodec) EncodeFunctions(
+	names []string, context ServiceContext) []byte {
+	w := new(msgpackWriter)
+	w.writeHeader(1, 0x80, 0xde, 0xdf)
+	w.writeString("functions")
+	w.writeValue(reflect.ValueOf(names))
+	return w.buf
+}
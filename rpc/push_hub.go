@@ -0,0 +1,369 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/push_hub.go                                        *
+ *                                                        *
+ * hprose tcp push hub and push broker for Go.            *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"io"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	hio "github.com/hprose/hprose-golang/io"
+)
+
+func encodePushMessage(message *PushMessage) []byte {
+	writer := hio.NewWriter(false)
+	writer.Serialize(message.Node)
+	writer.Serialize(message.Topic)
+	writer.Serialize(message.IDs)
+	writer.Serialize(message.Result)
+	return writer.Bytes()
+}
+
+func decodePushMessage(data []byte) (message *PushMessage, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			message, err = nil, NewPanicError(e)
+		}
+	}()
+	reader := acquireReader(data)
+	defer releaseReader(reader)
+	message = new(PushMessage)
+	reader.Unserialize(&message.Node)
+	reader.Unserialize(&message.Topic)
+	reader.Unserialize(&message.IDs)
+	reader.Unserialize(&message.Result)
+	return message, nil
+}
+
+// DefaultMaxPushFrameSize is the default max size of the encoded messages sent
+// between PushHub and TCPPushBroker.
+const DefaultMaxPushFrameSize = 16 << 20
+
+func maxPushFrameSize(size int) int {
+	if size <= 0 {
+		return DefaultMaxPushFrameSize
+	}
+	return size
+}
+
+// recvPushFrame returns errPushFrameTooLarge if the frame is larger than
+// maxSize, so a broken or malicious peer can't make it allocate any size.
+func recvPushFrame(reader io.Reader, maxSize int) (data []byte, err error) {
+	var header [4]byte
+	if _, err = io.ReadFull(reader, header[:]); err != nil {
+		return
+	}
+	size := toUint32(header[:])
+	if uint64(size) > uint64(maxSize) {
+		return nil, errPushFrameTooLarge
+	}
+	data = make([]byte, size)
+	_, err = io.ReadFull(reader, data)
+	return
+}
+
+// PushHub is a tcp hub which fans out the messages of TCPPushBroker, every
+// message received from a node is sent to all the other nodes. The node which
+// doesn't receive the messages in time is disconnected.
+//
+// MaxFrameSize is the max size of a message (DefaultMaxPushFrameSize if zero),
+// the node which sends a larger message is disconnected.
+type PushHub struct {
+	MaxFrameSize int
+	uri          string
+	listener     net.Listener
+	conns        map[net.Conn]chan []byte
+	locker       sync.Mutex
+}
+
+// NewPushHub is the constructor of PushHub, uri is the listen address, for
+// example "tcp://127.0.0.1:4321".
+func NewPushHub(uri string) *PushHub {
+	if uri == "" {
+		uri = "tcp://127.0.0.1:0"
+	}
+	return &PushHub{uri: uri, conns: make(map[net.Conn]chan []byte)}
+}
+
+// URI return the real address of this hub
+func (hub *PushHub) URI() string {
+	hub.locker.Lock()
+	defer hub.locker.Unlock()
+	if hub.listener == nil {
+		panic(errServerIsNotStarted)
+	}
+	u, err := url.Parse(hub.uri)
+	if err != nil {
+		panic(err)
+	}
+	return u.Scheme + "://" + hub.listener.Addr().String()
+}
+
+// Handle starts the hub in background
+func (hub *PushHub) Handle() error {
+	u, err := url.Parse(hub.uri)
+	if err != nil {
+		return err
+	}
+	listener, err := net.Listen(u.Scheme, u.Host)
+	if err != nil {
+		return err
+	}
+	hub.locker.Lock()
+	if hub.listener != nil {
+		hub.locker.Unlock()
+		listener.Close()
+		return errServerIsAlreadyStarted
+	}
+	hub.listener = listener
+	hub.locker.Unlock()
+	go hub.Serve(listener)
+	return nil
+}
+
+// Serve the nodes on the listener
+func (hub *PushHub) Serve(listener net.Listener) error {
+	var tempDelay time.Duration
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if tempDelay = nextTempDelay(err, nil, tempDelay); tempDelay > 0 {
+				continue
+			}
+			return err
+		}
+		tempDelay = 0
+		go hub.serveConn(conn)
+	}
+}
+
+func (hub *PushHub) serveConn(conn net.Conn) {
+	send := make(chan []byte, 1024)
+	hub.locker.Lock()
+	hub.conns[conn] = send
+	hub.locker.Unlock()
+	defer hub.remove(conn)
+	go func() {
+		for data := range send {
+			if err := clientSendData(conn, data); err != nil {
+				conn.Close()
+				return
+			}
+		}
+	}()
+	for {
+		data, err := recvPushFrame(conn, maxPushFrameSize(hub.MaxFrameSize))
+		if err != nil {
+			return
+		}
+		hub.locker.Lock()
+		for c, s := range hub.conns {
+			if c == conn {
+				continue
+			}
+			select {
+			case s <- data:
+			default:
+				c.Close()
+			}
+		}
+		hub.locker.Unlock()
+	}
+}
+
+func (hub *PushHub) remove(conn net.Conn) {
+	conn.Close()
+	hub.locker.Lock()
+	if send, ok := hub.conns[conn]; ok {
+		delete(hub.conns, conn)
+		close(send)
+	}
+	hub.locker.Unlock()
+}
+
+// Close the hub and all the connections
+func (hub *PushHub) Close() {
+	hub.locker.Lock()
+	listener := hub.listener
+	hub.listener = nil
+	for conn := range hub.conns {
+		conn.Close()
+	}
+	hub.locker.Unlock()
+	if listener != nil {
+		listener.Close()
+	}
+}
+
+// TCPPushBroker is a PushBroker which connects to a PushHub, the messages are
+// published to the handlers of this broker and the brokers of the other nodes
+// through the hub. It reconnects to the hub after ReconnectDelay when the
+// connection is broken, and the messages published before it reconnects are
+// not sent to the other nodes.
+//
+// MaxFrameSize is the max size of a message (DefaultMaxPushFrameSize if zero),
+// the larger messages are not sent to the other nodes, and the connection is
+// broken if a larger message is received.
+//
+// WriteTimeout is the max time of sending a message to the hub (no limit if
+// zero), the connection is broken if the message is not sent in time.
+type TCPPushBroker struct {
+	ReconnectDelay time.Duration
+	MaxFrameSize   int
+	WriteTimeout   time.Duration
+	uri            string
+	handlers       pushHandlers
+	conn           net.Conn
+	started        bool
+	closed         chan struct{}
+	locker         sync.Mutex
+	writeLocker    sync.Mutex
+}
+
+// NewTCPPushBroker is the constructor of TCPPushBroker, uri is the address of
+// the PushHub.
+func NewTCPPushBroker(uri string) *TCPPushBroker {
+	return &TCPPushBroker{
+		ReconnectDelay: time.Second,
+		WriteTimeout:   10 * time.Second,
+		uri:            uri,
+		closed:         make(chan struct{}),
+	}
+}
+
+// Publish the message to all the handlers of the nodes
+func (broker *TCPPushBroker) Publish(message *PushMessage) error {
+	broker.handlers.dispatch(message)
+	broker.locker.Lock()
+	conn := broker.conn
+	broker.locker.Unlock()
+	if conn == nil {
+		return errPushBrokerNotConnected
+	}
+	data := encodePushMessage(message)
+	if len(data) > maxPushFrameSize(broker.MaxFrameSize) {
+		return errPushFrameTooLarge
+	}
+	broker.writeLocker.Lock()
+	defer broker.writeLocker.Unlock()
+	if broker.WriteTimeout > 0 {
+		conn.SetWriteDeadline(time.Now().Add(broker.WriteTimeout))
+	}
+	if err := clientSendData(conn, data); err != nil {
+		// the frame may be sent partly, so the connection is closed, and the
+		// run loop reconnects to the hub.
+		broker.drop(conn)
+		return err
+	}
+	return nil
+}
+
+// Subscribe the published messages, the broker connects to the hub on the
+// first subscription.
+func (broker *TCPPushBroker) Subscribe(
+	handler func(message *PushMessage)) (func(), error) {
+	unsubscribe := broker.handlers.add(handler)
+	broker.locker.Lock()
+	started := broker.started
+	broker.started = true
+	broker.locker.Unlock()
+	if !started {
+		conn, _ := broker.dial()
+		go broker.run(conn)
+	}
+	return unsubscribe, nil
+}
+
+// Close the broker
+func (broker *TCPPushBroker) Close() {
+	broker.locker.Lock()
+	defer broker.locker.Unlock()
+	select {
+	case <-broker.closed:
+		return
+	default:
+	}
+	close(broker.closed)
+	if broker.conn != nil {
+		broker.conn.Close()
+		broker.conn = nil
+	}
+}
+
+func (broker *TCPPushBroker) dial() (net.Conn, error) {
+	u, err := url.Parse(broker.uri)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.Dial(u.Scheme, u.Host)
+	if err != nil {
+		return nil, err
+	}
+	broker.locker.Lock()
+	defer broker.locker.Unlock()
+	select {
+	case <-broker.closed:
+		conn.Close()
+		return nil, errClientIsAlreadyClosed
+	default:
+	}
+	broker.conn = conn
+	return conn, nil
+}
+
+func (broker *TCPPushBroker) drop(conn net.Conn) {
+	broker.locker.Lock()
+	if broker.conn == conn {
+		broker.conn = nil
+	}
+	broker.locker.Unlock()
+	conn.Close()
+}
+
+func (broker *TCPPushBroker) run(conn net.Conn) {
+	for {
+		if conn != nil {
+			broker.receive(conn)
+			broker.drop(conn)
+		}
+		timer := time.NewTimer(broker.ReconnectDelay)
+		select {
+		case <-broker.closed:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		conn, _ = broker.dial()
+	}
+}
+
+func (broker *TCPPushBroker) receive(conn net.Conn) {
+	for {
+		data, err := recvPushFrame(conn, maxPushFrameSize(broker.MaxFrameSize))
+		if err != nil {
+			return
+		}
+		if message, err := decodePushMessage(data); err == nil {
+			broker.handlers.dispatch(message)
+		}
+	}
+}
@@ -0,0 +1,333 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/push_hub_test.go                                   *
+ *                                                        *
+ * hprose tcp push hub and push broker test for Go.       *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPushMessageCodec(t *testing.T) {
+	message := &PushMessage{
+		Node:   "node",
+		Topic:  "news",
+		IDs:    []string{"a", "b"},
+		Result: "hello",
+	}
+	decoded, err := decodePushMessage(encodePushMessage(message))
+	if err != nil || !reflect.DeepEqual(decoded, message) {
+		t.Error(decoded, err)
+	}
+	message.IDs = nil
+	decoded, err = decodePushMessage(encodePushMessage(message))
+	if err != nil || decoded.IDs != nil {
+		t.Error(decoded, err)
+	}
+	if _, err = decodePushMessage([]byte("\x00bad")); err == nil {
+		t.Error("the malformed message is decoded")
+	}
+}
+
+func TestRecvPushFrame(t *testing.T) {
+	var buf bytes.Buffer
+	clientSendData(&buf, []byte("hello"))
+	data, err := recvPushFrame(&buf, 5)
+	if err != nil || string(data) != "hello" {
+		t.Error(string(data), err)
+	}
+	clientSendData(&buf, []byte("hello"))
+	if _, err = recvPushFrame(&buf, 4); err != errPushFrameTooLarge {
+		t.Error(err)
+	}
+	// the frame size is checked before the frame is allocated
+	if _, err = recvPushFrame(bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff}),
+		DefaultMaxPushFrameSize); err != errPushFrameTooLarge {
+		t.Error(err)
+	}
+	if _, err = recvPushFrame(bytes.NewReader([]byte{0, 0, 0, 9, 1}),
+		DefaultMaxPushFrameSize); err != io.ErrUnexpectedEOF {
+		t.Error(err)
+	}
+}
+
+func hubConns(hub *PushHub) int {
+	hub.locker.Lock()
+	defer hub.locker.Unlock()
+	return len(hub.conns)
+}
+
+func dialHub(t *testing.T, hub *PushHub) net.Conn {
+	u, _ := url.Parse(hub.URI())
+	conn, err := net.Dial("tcp", u.Host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func TestPushHub(t *testing.T) {
+	hub := NewPushHub("")
+	hub.MaxFrameSize = 1024
+	if err := hub.Handle(); err != nil {
+		t.Fatal(err)
+	}
+	defer hub.Close()
+	if err := hub.Handle(); err != errServerIsAlreadyStarted {
+		t.Error(err)
+	}
+	c1, c2, c3 := dialHub(t, hub), dialHub(t, hub), dialHub(t, hub)
+	defer c1.Close()
+	defer c2.Close()
+	waitUntil(time.Second, func() bool { return hubConns(hub) == 3 })
+	// the frame is sent to all the other nodes
+	clientSendData(c1, []byte("hello"))
+	for _, conn := range []net.Conn{c2, c3} {
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		if data, err := recvPushFrame(conn, 1024); err != nil || string(data) != "hello" {
+			t.Error(string(data), err)
+		}
+	}
+	c1.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	if data, err := recvPushFrame(c1, 1024); err == nil {
+		t.Error("the frame is sent back", string(data))
+	}
+	// the node which sends a too large frame is disconnected
+	c3.Write([]byte{0xff, 0xff, 0xff, 0xff})
+	c3.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := c3.Read(make([]byte, 1)); err != io.EOF {
+		t.Error("the node is not disconnected", err)
+	}
+	if !waitUntil(time.Second, func() bool { return hubConns(hub) == 2 }) {
+		t.Error(hubConns(hub))
+	}
+	clientSendData(c2, []byte("world"))
+	c1.SetReadDeadline(time.Now().Add(time.Second))
+	if data, err := recvPushFrame(c1, 1024); err != nil || string(data) != "world" {
+		t.Error(string(data), err)
+	}
+	hub.Close()
+	c1.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := c1.Read(make([]byte, 1)); err != io.EOF {
+		t.Error("the connection is not closed", err)
+	}
+}
+
+func TestTCPPushBrokerFrameTooLarge(t *testing.T) {
+	hub := NewPushHub("")
+	hub.Handle()
+	defer hub.Close()
+	broker := NewTCPPushBroker(hub.URI())
+	broker.MaxFrameSize = 64
+	defer broker.Close()
+	received := make(chan string, 1)
+	broker.Subscribe(func(message *PushMessage) {
+		received <- message.Result.(string)
+	})
+	// the large message is dispatched to the local handlers only
+	large := strings.Repeat("x", 100)
+	if err := broker.Publish(&PushMessage{Topic: "news", Result: large}); err != errPushFrameTooLarge {
+		t.Error(err)
+	}
+	if s := <-received; s != large {
+		t.Error(s)
+	}
+	if err := broker.Publish(&PushMessage{Topic: "news", Result: "small"}); err != nil {
+		t.Error(err)
+	}
+	<-received
+}
+
+func TestTCPPushBrokerWriteTimeout(t *testing.T) {
+	// the hub accepts the connections, but never reads from them
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	accepted := make(chan net.Conn, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- conn
+		}
+	}()
+	broker := NewTCPPushBroker("tcp://" + listener.Addr().String())
+	broker.ReconnectDelay = 20 * time.Millisecond
+	broker.WriteTimeout = 50 * time.Millisecond
+	defer broker.Close()
+	broker.Subscribe(func(*PushMessage) {})
+	conn := <-accepted
+	defer conn.Close()
+	large := strings.Repeat("x", 1<<20)
+	start := time.Now()
+	for {
+		err = broker.Publish(&PushMessage{Topic: "news", Result: large})
+		if err != nil || time.Since(start) > 5*time.Second {
+			break
+		}
+	}
+	if e, ok := err.(net.Error); !ok || !e.Timeout() {
+		t.Fatal("the write does not time out", err)
+	}
+	// the broken connection is dropped, and the broker reconnects
+	select {
+	case conn := <-accepted:
+		conn.Close()
+	case <-time.After(time.Second):
+		t.Fatal("the broker does not reconnect")
+	}
+}
+
+func TestTCPPushBrokerReconnect(t *testing.T) {
+	hub := NewPushHub("")
+	hub.Handle()
+	uri := hub.URI()
+	b1, b2 := NewTCPPushBroker(uri), NewTCPPushBroker(uri)
+	defer b1.Close()
+	defer b2.Close()
+	received := make(chan string, 10)
+	for _, broker := range []*TCPPushBroker{b1, b2} {
+		broker.ReconnectDelay = 20 * time.Millisecond
+		broker.Subscribe(func(message *PushMessage) {
+			if message.Node == "b1" {
+				received <- message.Result.(string)
+			}
+		})
+	}
+	waitUntil(time.Second, func() bool { return hubConns(hub) == 2 })
+	hub.Close()
+	if !waitUntil(time.Second, func() bool {
+		return b1.Publish(&PushMessage{Node: "b1", Result: "lost"}) == errPushBrokerNotConnected
+	}) {
+		t.Fatal("the broker is connected after the hub is closed")
+	}
+	for len(received) > 0 {
+		<-received
+	}
+	hub = NewPushHub(uri)
+	if err := hub.Handle(); err != nil {
+		t.Fatal(err)
+	}
+	defer hub.Close()
+	if !waitUntil(3*time.Second, func() bool { return hubConns(hub) == 2 }) {
+		t.Fatal("the brokers do not reconnect")
+	}
+	if err := b1.Publish(&PushMessage{Node: "b1", Result: "hello"}); err != nil {
+		t.Fatal(err)
+	}
+	// the message is received by b1 itself and by b2 through the hub
+	for i := 0; i < 2; i++ {
+		select {
+		case s := <-received:
+			if s != "hello" {
+				t.Error(s)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("the message is not received")
+		}
+	}
+}
+
+func testPushBrokers(t *testing.T, brokers ...PushBroker) {
+	servers := make([]*TCPServer, len(brokers))
+	received := make([]chan string, len(brokers))
+	for i, broker := range brokers {
+		server := NewTCPServer("tcp://127.0.0.1:0")
+		server.Publish("news", 0, 0)
+		if err := server.SetPushBroker(broker); err != nil {
+			t.Fatal(err)
+		}
+		server.Handle()
+		defer server.Close()
+		client := NewTCPClient(server.URI())
+		defer client.Close()
+		id := "c" + string(rune('0'+i))
+		ch := make(chan string, 10)
+		client.Subscribe("news", id, nil, func(s string) { ch <- s })
+		waitUntil(time.Second, func() bool { return server.Exist("news", id) })
+		servers[i], received[i] = server, ch
+	}
+	expect := func(i int, want string) {
+		t.Helper()
+		select {
+		case s := <-received[i]:
+			if s != want {
+				t.Error(i, s, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal(i, want, "is not received")
+		}
+	}
+	servers[0].Broadcast("news", "all", nil)
+	expect(0, "all")
+	expect(1, "all")
+	servers[1].Push("news", "to c0", "c0")
+	expect(0, "to c0")
+	// the callback only reports the clients on this node
+	sent := make(chan bool, 1)
+	servers[0].Unicast("news", "c1", "to c1", func(ok bool) { sent <- ok })
+	expect(1, "to c1")
+	if <-sent {
+		t.Error("the remote client is reported")
+	}
+	servers[1].Push("news", "push")
+	expect(0, "push")
+	expect(1, "push")
+	select {
+	case s := <-received[0]:
+		t.Error("the message is received again", s)
+	case s := <-received[1]:
+		t.Error("the message is received again", s)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestMemoryPushBroker(t *testing.T) {
+	broker := NewMemoryPushBroker()
+	testPushBrokers(t, broker, broker)
+}
+
+func TestTCPPushBroker(t *testing.T) {
+	hub := NewPushHub("")
+	if err := hub.Handle(); err != nil {
+		t.Fatal(err)
+	}
+	defer hub.Close()
+	b1 := NewTCPPushBroker(hub.URI())
+	b2 := NewTCPPushBroker(hub.URI())
+	defer b1.Close()
+	defer b2.Close()
+	// the brokers connect to the hub on the first subscription
+	for _, broker := range []*TCPPushBroker{b1, b2} {
+		unsubscribe, _ := broker.Subscribe(func(*PushMessage) {})
+		defer unsubscribe()
+	}
+	waitUntil(time.Second, func() bool { return hubConns(hub) == 2 })
+	testPushBrokers(t, b1, b2)
+}
@@ -0,0 +1,337 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/push_test.go                                       *
+ *                                                        *
+ * hprose push subscription test for Go.                  *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type subscribeEvents struct {
+	events chan string
+	errors chan error
+}
+
+func newSubscribeEvents() *subscribeEvents {
+	return &subscribeEvents{
+		events: make(chan string, 100),
+		errors: make(chan error, 100),
+	}
+}
+
+func (e *subscribeEvents) OnSubscribeConnected(topic string, id string) {
+	e.events <- "connected " + topic + " " + id
+}
+
+func (e *subscribeEvents) OnSubscribeReconnecting(topic string, id string, err error) {
+	e.events <- "reconnecting " + topic + " " + id
+}
+
+func (e *subscribeEvents) OnSubscribeStopped(topic string, id string) {
+	e.events <- "stopped " + topic + " " + id
+}
+
+func (e *subscribeEvents) OnError(name string, err error) {
+	e.errors <- err
+}
+
+func (e *subscribeEvents) expect(t *testing.T, want ...string) {
+	t.Helper()
+	for _, event := range want {
+		select {
+		case got := <-e.events:
+			if got != event {
+				t.Fatalf("got event %q, want %q", got, event)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("event %q is not fired", event)
+		}
+	}
+}
+
+func expectMessages(t *testing.T, got chan int, want ...int) {
+	t.Helper()
+	for _, n := range want {
+		select {
+		case m := <-got:
+			if m != n {
+				t.Fatalf("got message %d, want %d", m, n)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("message %d is not received", n)
+		}
+	}
+	select {
+	case m := <-got:
+		t.Fatalf("unexpected message %d", m)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// dropFilter fails the next response which has messages, so that the
+// subscription reconnects after the messages are sent.
+type dropFilter struct {
+	drop int32
+	fail int32
+}
+
+var errDropped = errors.New("the response is dropped")
+
+func (f *dropFilter) handler(
+	request []byte, context Context, next NextFilterHandler) ([]byte, error) {
+	if atomic.LoadInt32(&f.fail) != 0 {
+		return nil, errDropped
+	}
+	response, err := next(request, context)
+	if err == nil && bytes.Contains(response, []byte(pushMessageMessages)) &&
+		atomic.CompareAndSwapInt32(&f.drop, 1, 0) {
+		return nil, errDropped
+	}
+	return response, err
+}
+
+func newSubscribeClient(uri string, events *subscribeEvents, delay time.Duration) (*TCPClient, *dropFilter) {
+	client := NewTCPClient(uri)
+	client.SetRetry(0)
+	client.SetEvent(events)
+	client.SetSubscribeBackoff(&ExponentialBackoff{
+		InitialInterval: delay,
+		Multiplier:      1,
+		Retryable:       func(err error) bool { return true },
+	})
+	filter := new(dropFilter)
+	client.AddAfterFilterHandler(filter.handler)
+	return client, filter
+}
+
+func TestSubscribeResume(t *testing.T) {
+	server := NewTCPServer("tcp://127.0.0.1:0")
+	server.Publish("news", 0, 0)
+	server.Handle()
+	defer server.Close()
+	events := newSubscribeEvents()
+	client, filter := newSubscribeClient(server.URI(), events, 200*time.Millisecond)
+	defer client.Close()
+	got := make(chan int, 10)
+	if err := client.Subscribe("news", "a", nil, func(n int) { got <- n }); err != nil {
+		t.Fatal(err)
+	}
+	waitUntil(time.Second, func() bool { return server.Exist("news", "a") })
+	server.Push("news", 1, "a")
+	expectMessages(t, got, 1)
+	events.expect(t, "connected news a")
+	// the response of 2 is lost, and 3 is pushed while reconnecting
+	atomic.StoreInt32(&filter.drop, 1)
+	server.Push("news", 2, "a")
+	events.expect(t, "reconnecting news a")
+	server.Push("news", 3, "a")
+	events.expect(t, "connected news a")
+	expectMessages(t, got, 2, 3)
+	if n := server.QueueDepth("news", "a"); n > 2 {
+		t.Error("the received messages are not acknowledged", n)
+	}
+	client.Unsubscribe("news", "a")
+	events.expect(t, "stopped news a")
+	if client.IsSubscribed("news") {
+		t.Error("news is subscribed")
+	}
+}
+
+// connTracker closes the accepted connections of a server to simulate that the
+// server is down, the server does not close them when it is closed.
+type connTracker struct {
+	sync.Mutex
+	conns []net.Conn
+}
+
+func (c *connTracker) OnAccept(context *SocketContext) {
+	c.Lock()
+	c.conns = append(c.conns, context.Conn)
+	c.Unlock()
+}
+
+func (c *connTracker) closeAll() {
+	c.Lock()
+	for _, conn := range c.conns {
+		conn.Close()
+	}
+	c.conns = nil
+	c.Unlock()
+}
+
+func newNewsServer(uri string) (*TCPServer, *connTracker) {
+	server := NewTCPServer(uri)
+	tracker := new(connTracker)
+	server.Event = tracker
+	server.Publish("news", 0, 0)
+	server.Handle()
+	return server, tracker
+}
+
+func TestSubscribeReconnect(t *testing.T) {
+	server, tracker := newNewsServer("tcp://127.0.0.1:0")
+	uri := server.URI()
+	events := newSubscribeEvents()
+	client, _ := newSubscribeClient(uri, events, 50*time.Millisecond)
+	defer client.Close()
+	got := make(chan int, 10)
+	client.Subscribe("news", "a", nil, func(n int) { got <- n })
+	waitUntil(time.Second, func() bool { return server.Exist("news", "a") })
+	server.Push("news", 1, "a")
+	expectMessages(t, got, 1)
+	events.expect(t, "connected news a")
+	server.Close()
+	tracker.closeAll()
+	events.expect(t, "reconnecting news a")
+	// the reconnecting event is fired once until the subscription connects
+	time.Sleep(200 * time.Millisecond)
+	select {
+	case event := <-events.events:
+		t.Fatal(event)
+	default:
+	}
+	server, tracker = newNewsServer(uri)
+	defer server.Close()
+	if !waitUntil(3*time.Second, func() bool { return server.Exist("news", "a") }) {
+		t.Fatal("the subscription does not reconnect")
+	}
+	server.Push("news", 2, "a")
+	expectMessages(t, got, 2)
+	events.expect(t, "connected news a")
+	client.Close()
+	events.expect(t, "stopped news a")
+}
+
+type stopBackoff struct{}
+
+func (stopBackoff) Backoff(attempt int, elapsed time.Duration, err error,
+	context *ClientContext) (time.Duration, bool) {
+	return 0, false
+}
+
+func TestSubscribeStopped(t *testing.T) {
+	server := NewTCPServer("tcp://127.0.0.1:0")
+	server.Publish("news", 0, 0)
+	server.Handle()
+	defer server.Close()
+	events := newSubscribeEvents()
+	client, filter := newSubscribeClient(server.URI(), events, 0)
+	defer client.Close()
+	client.SetSubscribeBackoff(stopBackoff{})
+	atomic.StoreInt32(&filter.fail, 1)
+	got := make(chan int, 10)
+	client.Subscribe("news", "a", nil, func(n int) { got <- n })
+	events.expect(t, "stopped news a")
+	if err := <-events.errors; err != errDropped {
+		t.Error(err)
+	}
+	// the stopped subscription is removed
+	if client.IsSubscribed("news") || len(client.SubscribedList()) != 0 {
+		t.Error("the stopped subscription is not removed")
+	}
+	atomic.StoreInt32(&filter.fail, 0)
+	if err := client.Subscribe("news", "a", nil, func(n int) { got <- n }); err != nil {
+		t.Fatal(err)
+	}
+	if !client.IsSubscribed("news") {
+		t.Error("news is not subscribed")
+	}
+	waitUntil(time.Second, func() bool { return server.Exist("news", "a") })
+	server.Push("news", 1, "a")
+	expectMessages(t, got, 1)
+	events.expect(t, "connected news a")
+}
+
+func TestDedupWindow(t *testing.T) {
+	var window dedupWindow
+	if !window.add("1") || window.add("1") {
+		t.Error("the id is not remembered")
+	}
+	for i := 2; i <= pushDedupWindow; i++ {
+		window.add(strconv.Itoa(i))
+	}
+	if window.add("1") {
+		t.Error("the id is forgotten before the window is full")
+	}
+	// the oldest id is forgotten when the window is full
+	window.add(strconv.Itoa(pushDedupWindow + 1))
+	if !window.add("1") {
+		t.Error("the oldest id is not forgotten")
+	}
+	if len(window.seen) != pushDedupWindow {
+		t.Error(len(window.seen))
+	}
+}
+
+// TestSubscribeDedup checks that the message which is sent again after the
+// acknowledgement times out is acknowledged, but not received again.
+func TestSubscribeDedup(t *testing.T) {
+	server := NewTCPServer("tcp://127.0.0.1:0")
+	server.PublishWithOptions("news", PublishOptions{
+		Delivery:   DeliveryAtLeastOnce,
+		AckTimeout: 200 * time.Millisecond,
+	})
+	server.Handle()
+	defer server.Close()
+	client := NewTCPClient(server.URI())
+	defer client.Close()
+	// the first acknowledgement is lost
+	var lost int32 = 1
+	client.AddInvokeHandler(func(name string, args []reflect.Value,
+		context Context, next NextInvokeHandler) ([]reflect.Value, error) {
+		if len(args) == 3 && args[2].String() != "" &&
+			atomic.CompareAndSwapInt32(&lost, 1, 0) {
+			args = []reflect.Value{args[0], args[1], reflect.ValueOf("")}
+		}
+		return next(name, args, context)
+	})
+	got := make(chan int, 10)
+	client.Subscribe("news", "a", nil, func(n int) { got <- n })
+	waitUntil(time.Second, func() bool { return server.Exist("news", "a") })
+	acked := make(chan bool, 1)
+	start := time.Now()
+	server.Unicast("news", "a", 1, func(ok bool) { acked <- ok })
+	expectMessages(t, got, 1)
+	select {
+	case ok := <-acked:
+		if !ok {
+			t.Error("the message is not acknowledged")
+		}
+		if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+			t.Error("the message is acknowledged before it is sent again", elapsed)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("the message is not acknowledged")
+	}
+	expectMessages(t, got)
+	if atomic.LoadInt32(&lost) != 0 {
+		t.Error("the acknowledgement is not lost")
+	}
+	if n := server.QueueDepth("news", "a"); n != 0 {
+		t.Error(n)
+	}
+}
@@ -0,0 +1,307 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/registrar.go                                       *
+ *                                                        *
+ * hprose service registrar for Go.                       *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// Registration is the information of the service which is registered
+type Registration struct {
+	URI      string
+	Methods  []string
+	Metadata map[string]string
+}
+
+// Registrar registers the service to a service registry.
+//
+// Register is called when the service is started, and is called again every
+// RegisterInterval of the service to refresh the registration, so it should
+// be idempotent. Deregister is called when the service is closed.
+type Registrar interface {
+	Register(registration Registration) error
+	Deregister(registration Registration) error
+}
+
+// Register the service with uri by the Registrar of the service, and refresh
+// the registration every RegisterInterval until Deregister is called.
+//
+// TCPServer and UnixServer call it in Handle with their real URI, the other
+// services should call it with the address which they are served on. If the
+// host of uri is unspecified (such as 0.0.0.0 or ::), it is replaced with an
+// address of the network interfaces, and an error is returned if there is no
+// such address.
+func (service *baseService) Register(uri string) (err error) {
+	if service.Registrar == nil {
+		return nil
+	}
+	if uri, err = routableURI(uri); err != nil {
+		return err
+	}
+	service.Deregister()
+	registration := Registration{
+		URI:      uri,
+		Methods:  service.publishedMethods(),
+		Metadata: service.Metadata,
+	}
+	if err := service.Registrar.Register(registration); err != nil {
+		return err
+	}
+	stop := make(chan struct{})
+	service.registerLock.Lock()
+	service.registration = &registration
+	service.registerStop = stop
+	service.registerLock.Unlock()
+	if service.RegisterInterval > 0 {
+		go service.refreshRegistration(
+			service.Registrar, registration, service.RegisterInterval, stop)
+	}
+	return nil
+}
+
+// Deregister the service by the Registrar of the service
+func (service *baseService) Deregister() error {
+	service.registerLock.Lock()
+	registration := service.registration
+	if service.registerStop != nil {
+		close(service.registerStop)
+	}
+	service.registration = nil
+	service.registerStop = nil
+	service.registerLock.Unlock()
+	if registration == nil || service.Registrar == nil {
+		return nil
+	}
+	return service.Registrar.Deregister(*registration)
+}
+
+var interfaceAddrs = net.InterfaceAddrs
+
+var errNoRoutableAddress = errors.New("No routable address is found to register")
+
+// routableURI replaces the unspecified host of uri with a global unicast
+// address of the network interfaces, the IPv4 address is preferred unless the
+// host is 0.0.0.0, which only accepts the IPv4 address.
+func routableURI(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	if u.Host == "" {
+		return uri, nil
+	}
+	host := u.Hostname()
+	ip := net.ParseIP(host)
+	if host != "" && (ip == nil || !ip.IsUnspecified()) {
+		return uri, nil
+	}
+	addrs, err := interfaceAddrs()
+	if err != nil {
+		return "", err
+	}
+	ipv4Only := ip != nil && ip.To4() != nil
+	var ipv6 net.IP
+	for _, addr := range addrs {
+		ipnet, ok := addr.(*net.IPNet)
+		if !ok || !ipnet.IP.IsGlobalUnicast() {
+			continue
+		}
+		if ipnet.IP.To4() != nil {
+			u.Host = joinHostPort(ipnet.IP.String(), u.Port())
+			return u.String(), nil
+		}
+		if ipv6 == nil && !ipv4Only {
+			ipv6 = ipnet.IP
+		}
+	}
+	if ipv6 == nil {
+		return "", errNoRoutableAddress
+	}
+	u.Host = joinHostPort(ipv6.String(), u.Port())
+	return u.String(), nil
+}
+
+func (service *baseService) publishedMethods() []string {
+	service.mmLocker.Lock()
+	defer service.mmLocker.Unlock()
+	methods := make([]string, 0, len(service.MethodNames))
+	for _, name := range service.MethodNames {
+		if name != "#" && name != "*" {
+			methods = append(methods, name)
+		}
+	}
+	return methods
+}
+
+func (service *baseService) refreshRegistration(
+	registrar Registrar,
+	registration Registration,
+	interval time.Duration,
+	stop chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			service.registerLock.Lock()
+			select {
+			case <-stop:
+				service.registerLock.Unlock()
+				return
+			default:
+			}
+			err := registrar.Register(registration)
+			service.registerLock.Unlock()
+			if err != nil {
+				if event, ok := service.Event.(registerErrorEvent); ok {
+					event.OnRegisterError(registration.URI, err)
+				}
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// FileRegistrar registers the services to the file at Path, which can be
+// read by FileResolver.
+//
+// The file is a JSON array of the registrations, a registration expires after
+// TTL unless it is registered again, 0 means never expires. The file can be
+// shared by the services of different processes, it is locked by the file at
+// Path + ".lock" when it is updated.
+type FileRegistrar struct {
+	Path   string
+	TTL    time.Duration
+	locker sync.Mutex
+}
+
+type fileRegistration struct {
+	URI      string            `json:"uri"`
+	Methods  []string          `json:"methods,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Expires  int64             `json:"expires,omitempty"`
+}
+
+func (r *fileRegistration) expired(now time.Time) bool {
+	return r.Expires != 0 && r.Expires <= now.Unix()
+}
+
+// NewFileRegistrar is the constructor of FileRegistrar
+func NewFileRegistrar(path string) *FileRegistrar {
+	return &FileRegistrar{
+		Path: path,
+		TTL:  30 * time.Second,
+	}
+}
+
+// Register the registration to the file
+func (r *FileRegistrar) Register(registration Registration) error {
+	return r.update(registration.URI, &fileRegistration{
+		URI:      registration.URI,
+		Methods:  registration.Methods,
+		Metadata: registration.Metadata,
+	})
+}
+
+// Deregister removes the registration from the file
+func (r *FileRegistrar) Deregister(registration Registration) error {
+	return r.update(registration.URI, nil)
+}
+
+var errRegistryIsLocked = errors.New("The registry file is locked")
+
+func (r *FileRegistrar) lock() error {
+	lockPath := r.Path + ".lock"
+	for i := 0; i < 500; i++ {
+		file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			return file.Close()
+		}
+		if !os.IsExist(err) {
+			return err
+		}
+		if info, err := os.Stat(lockPath); err == nil &&
+			time.Since(info.ModTime()) > 10*time.Second {
+			os.Remove(lockPath)
+			continue
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return errRegistryIsLocked
+}
+
+// update replaces the registration of uri with registration, and removes
+// the expired registrations.
+func (r *FileRegistrar) update(
+	uri string, registration *fileRegistration) error {
+	r.locker.Lock()
+	defer r.locker.Unlock()
+	if err := r.lock(); err != nil {
+		return err
+	}
+	defer os.Remove(r.Path + ".lock")
+	var registrations []*fileRegistration
+	data, err := ioutil.ReadFile(r.Path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if data = bytes.TrimSpace(data); len(data) > 0 {
+		if err = json.Unmarshal(data, &registrations); err != nil {
+			return err
+		}
+	}
+	now := time.Now()
+	n := 0
+	for _, reg := range registrations {
+		if reg != nil && reg.URI != uri && !reg.expired(now) {
+			registrations[n] = reg
+			n++
+		}
+	}
+	registrations = registrations[:n]
+	if registration != nil {
+		if r.TTL > 0 {
+			// rounded up, so the registration never expires before TTL
+			expires := now.Add(r.TTL)
+			registration.Expires = expires.Unix()
+			if expires.Nanosecond() > 0 {
+				registration.Expires++
+			}
+		}
+		registrations = append(registrations, registration)
+	}
+	if data, err = json.MarshalIndent(registrations, "", "  "); err != nil {
+		return err
+	}
+	tmpPath := r.Path + ".tmp"
+	if err = ioutil.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, r.Path)
+}
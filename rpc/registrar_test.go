@@ -0,0 +1,229 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/registrar_test.go                                  *
+ *                                                        *
+ * hprose service registrar test for Go.                  *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func stubInterfaceAddrs(t *testing.T, cidrs ...string) {
+	var addrs []net.Addr
+	for _, cidr := range cidrs {
+		ip, ipnet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ipnet.IP = ip
+		addrs = append(addrs, ipnet)
+	}
+	interfaceAddrs = func() ([]net.Addr, error) { return addrs, nil }
+	t.Cleanup(func() { interfaceAddrs = net.InterfaceAddrs })
+}
+
+func TestRoutableURI(t *testing.T) {
+	stubInterfaceAddrs(t, "127.0.0.1/8", "::1/128", "fe80::1/64",
+		"2001:db8::5/64", "192.168.1.5/24")
+	tests := map[string]string{
+		"tcp://0.0.0.0:8412":            "tcp://192.168.1.5:8412",
+		"tcp://[::]:8412":               "tcp://192.168.1.5:8412",
+		"http://:8080/rpc":              "http://192.168.1.5:8080/rpc",
+		"tcp://127.0.0.1:8412":          "tcp://127.0.0.1:8412",
+		"tcp://example.com:8412":        "tcp://example.com:8412",
+		"unix:/tmp/hprose.sock":         "unix:/tmp/hprose.sock",
+		"ws://0.0.0.0:8080/ws?weight=2": "ws://192.168.1.5:8080/ws?weight=2",
+	}
+	for uri, want := range tests {
+		if got, err := routableURI(uri); got != want || err != nil {
+			t.Error(uri, got, err)
+		}
+	}
+	stubInterfaceAddrs(t, "127.0.0.1/8", "2001:db8::5/64")
+	if uri, err := routableURI("tcp://[::]:8412"); uri != "tcp://[2001:db8::5]:8412" || err != nil {
+		t.Error(uri, err)
+	}
+	if uri, err := routableURI("tcp://0.0.0.0:8412"); err != errNoRoutableAddress {
+		t.Error(uri, err)
+	}
+	stubInterfaceAddrs(t, "127.0.0.1/8")
+	if uri, err := routableURI("tcp://[::]:8412"); err != errNoRoutableAddress {
+		t.Error(uri, err)
+	}
+}
+
+func readRegistrations(t *testing.T, path string) (registrations []fileRegistration) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = json.Unmarshal(data, &registrations); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestFileRegistrar(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hprose")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "services.json")
+	registrar := NewFileRegistrar(path)
+	registrar.TTL = time.Second
+	var servers []*TCPServer
+	var locker sync.Mutex
+	var wg sync.WaitGroup
+	// the file is updated by the services concurrently
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			server := NewTCPServer("tcp://127.0.0.1:0")
+			server.AddFunction("who", func() string { return "s" }, Options{})
+			server.Registrar = registrar
+			server.Metadata = map[string]string{"zone": "a"}
+			server.RegisterInterval = 100 * time.Millisecond
+			if err := server.Handle(); err != nil {
+				t.Error(err)
+				return
+			}
+			locker.Lock()
+			servers = append(servers, server)
+			locker.Unlock()
+		}()
+	}
+	wg.Wait()
+	if len(servers) != 3 {
+		t.FailNow()
+	}
+	registrations := readRegistrations(t, path)
+	if len(registrations) != 3 {
+		t.Fatal(registrations)
+	}
+	for _, registration := range registrations {
+		if len(registration.Methods) != 1 || registration.Methods[0] != "who" ||
+			registration.Metadata["zone"] != "a" || registration.Expires == 0 {
+			t.Error(registration)
+		}
+	}
+	client := NewTCPClient("tcp://127.0.0.1:1")
+	defer client.Close()
+	resolver := NewFileResolver(path)
+	resolver.Interval = 20 * time.Millisecond
+	client.SetResolver(resolver)
+	if uriList := client.URIList(); len(uriList) != 3 {
+		t.Error(uriList)
+	}
+	// the registrations are refreshed before they expire
+	time.Sleep(1500 * time.Millisecond)
+	if uriList := client.URIList(); len(uriList) != 3 {
+		t.Error(uriList)
+	}
+	servers[0].Close()
+	if !waitUntil(time.Second, func() bool { return len(client.URIList()) == 2 }) {
+		t.Error(client.URIList())
+	}
+	var stub *whoStub
+	client.UseService(&stub)
+	if s, err := stub.Who(); s != "s" || err != nil {
+		t.Error(s, err)
+	}
+	servers[1].Close()
+	servers[2].Close()
+	if registrations := readRegistrations(t, path); len(registrations) != 0 {
+		t.Error(registrations)
+	}
+}
+
+type failedRegistrar struct {
+	sync.Mutex
+	calls int
+}
+
+func (r *failedRegistrar) Register(registration Registration) error {
+	r.Lock()
+	defer r.Unlock()
+	r.calls++
+	if r.calls > 1 {
+		return errors.New("the registry is down")
+	}
+	return nil
+}
+
+func (r *failedRegistrar) Deregister(registration Registration) error {
+	return nil
+}
+
+type registerErrorCounter struct {
+	sync.Mutex
+	uris []string
+}
+
+func (e *registerErrorCounter) OnRegisterError(uri string, err error) {
+	e.Lock()
+	e.uris = append(e.uris, uri)
+	e.Unlock()
+}
+
+func (e *registerErrorCounter) count() int {
+	e.Lock()
+	defer e.Unlock()
+	return len(e.uris)
+}
+
+func TestRegisterUnspecifiedAddress(t *testing.T) {
+	stubInterfaceAddrs(t, "127.0.0.1/8")
+	registrar := new(failedRegistrar)
+	event := new(registerErrorCounter)
+	server := NewTCPServer("tcp://0.0.0.0:0")
+	server.Registrar = registrar
+	server.Event = event
+	if err := server.Handle(); err != errNoRoutableAddress {
+		t.Error(err)
+	}
+	if server.listener != nil {
+		t.Error("the server is not closed")
+	}
+	stubInterfaceAddrs(t, "127.0.0.1/8", "10.1.2.3/8")
+	server.RegisterInterval = 10 * time.Millisecond
+	if err := server.Handle(); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	if !waitUntil(time.Second, func() bool { return event.count() > 0 }) {
+		t.Fatal("the register error is not reported")
+	}
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(server.URI(), "tcp://"))
+	event.Lock()
+	uri := event.uris[0]
+	event.Unlock()
+	if uri != "tcp://10.1.2.3:"+port {
+		t.Error(uri)
+	}
+}
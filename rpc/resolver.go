@@ -0,0 +1,310 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/resolver.go                                        *
+ *                                                        *
+ * hprose client service discovery for Go.                *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"bytes"
+	gocontext "context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Resolver resolves the service addresses of the client.
+//
+// Start is called when the resolver is set to the client, the resolver should
+// call update with the service addresses whenever they are changed until Stop
+// is called. If the resolving fails, update should be called with the error,
+// the client keeps the current service addresses and reports the error by
+// the OnError event with an empty name.
+type Resolver interface {
+	Start(update func(uriList []string, err error))
+	Stop()
+}
+
+// Resolver returns the resolver of the client
+func (client *baseClient) Resolver() Resolver {
+	client.resolverLocker.Lock()
+	defer client.resolverLocker.Unlock()
+	return client.resolver
+}
+
+// SetResolver set the resolver of the client and starts it, nil means no
+// resolver (the default). The old resolver is stopped, and the resolver is
+// stopped when the client is closed.
+//
+// The service addresses from the resolver are set by SetURIList, the requests
+// in flight are not interrupted, and the pooled connections are only closed
+// for the service addresses which are removed.
+func (client *baseClient) SetResolver(resolver Resolver) {
+	client.resolverLocker.Lock()
+	defer client.resolverLocker.Unlock()
+	if client.resolver != nil {
+		client.resolver.Stop()
+	}
+	client.resolver = resolver
+	if resolver != nil {
+		resolver.Start(client.resolve)
+	}
+}
+
+func (client *baseClient) resolve(uriList []string, err error) {
+	defer client.fireErrorEvent("", err)
+	if err == nil && !sameURIList(uriList, client.URIList()) {
+		client.setURIList(uriList)
+	}
+}
+
+func sameURIList(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	count := make(map[string]int, len(a))
+	for _, uri := range a {
+		count[uri]++
+	}
+	for _, uri := range b {
+		if count[uri] == 0 {
+			return false
+		}
+		count[uri]--
+	}
+	return true
+}
+
+type staticResolver struct {
+	uriList []string
+}
+
+// NewStaticResolver returns a Resolver which resolves the service addresses
+// to uriList.
+func NewStaticResolver(uriList ...string) Resolver {
+	return &staticResolver{uriList}
+}
+
+func (r *staticResolver) Start(update func(uriList []string, err error)) {
+	update(r.uriList, nil)
+}
+
+func (r *staticResolver) Stop() {}
+
+type poller struct {
+	stop   chan struct{}
+	locker sync.Mutex
+}
+
+// start calls lookup immediately and then every interval, and calls update
+// with the result until Stop is called.
+func (p *poller) start(
+	interval time.Duration,
+	lookup func() ([]string, error),
+	update func(uriList []string, err error)) {
+	stop := make(chan struct{})
+	p.locker.Lock()
+	if p.stop != nil {
+		close(p.stop)
+	}
+	p.stop = stop
+	p.locker.Unlock()
+	update(lookup())
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				update(lookup())
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop the resolver
+func (p *poller) Stop() {
+	p.locker.Lock()
+	if p.stop != nil {
+		close(p.stop)
+		p.stop = nil
+	}
+	p.locker.Unlock()
+}
+
+// FileResolver resolves the service addresses from the file at Path, the file
+// is read every Interval.
+//
+// The file is a JSON array of the service addresses or the registrations
+// written by FileRegistrar (the expired ones are ignored), or one service
+// address per line, the empty lines and the lines starting with # are ignored.
+type FileResolver struct {
+	Path     string
+	Interval time.Duration
+	poller
+}
+
+// NewFileResolver is the constructor of FileResolver
+func NewFileResolver(path string) *FileResolver {
+	return &FileResolver{
+		Path:     path,
+		Interval: 5 * time.Second,
+	}
+}
+
+// Start the resolver
+func (r *FileResolver) Start(update func(uriList []string, err error)) {
+	r.start(r.Interval, r.lookup, update)
+}
+
+func (r *FileResolver) lookup() ([]string, error) {
+	data, err := ioutil.ReadFile(r.Path)
+	if err != nil {
+		return nil, err
+	}
+	return parseURIList(data)
+}
+
+func parseURIList(data []byte) (uriList []string, err error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var items []json.RawMessage
+		if err = json.Unmarshal(data, &items); err != nil {
+			return nil, err
+		}
+		now := time.Now()
+		for _, item := range items {
+			var registration fileRegistration
+			if len(item) > 0 && item[0] == '"' {
+				err = json.Unmarshal(item, &registration.URI)
+			} else {
+				err = json.Unmarshal(item, &registration)
+			}
+			if err != nil {
+				return nil, err
+			}
+			if registration.URI != "" && !registration.expired(now) {
+				uriList = append(uriList, registration.URI)
+			}
+		}
+		return
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && line[0] != '#' {
+			uriList = append(uriList, line)
+		}
+	}
+	return
+}
+
+// DNSResolver resolves the service addresses by the DNS lookups of the host
+// of URI, the lookups are done every Interval and timeout after Timeout.
+//
+// When SRV is false, the host is looked up for the A/AAAA records, the
+// service addresses are URI with the host replaced by the addresses. When SRV
+// is true, the host is looked up for the SRV records (for example,
+// _hprose._tcp.example.com), the service addresses are URI with the host and
+// port replaced by the targets and ports.
+//
+// Resolver is used for the lookups, net.DefaultResolver is used if it is nil.
+// It can be set to a net.Resolver with a custom Dial to use a local DNS
+// server.
+type DNSResolver struct {
+	URI      string
+	SRV      bool
+	Interval time.Duration
+	Timeout  time.Duration
+	Resolver *net.Resolver
+	poller
+}
+
+// NewDNSResolver is the constructor of DNSResolver
+func NewDNSResolver(uri string) *DNSResolver {
+	return &DNSResolver{
+		URI:      uri,
+		Interval: 30 * time.Second,
+		Timeout:  5 * time.Second,
+	}
+}
+
+// Start the resolver
+func (r *DNSResolver) Start(update func(uriList []string, err error)) {
+	r.start(r.Interval, r.lookup, update)
+}
+
+func joinHostPort(host, port string) string {
+	if port != "" {
+		return net.JoinHostPort(host, port)
+	}
+	if strings.Contains(host, ":") {
+		return "[" + host + "]"
+	}
+	return host
+}
+
+func (r *DNSResolver) lookup() ([]string, error) {
+	u, err := url.Parse(r.URI)
+	if err != nil {
+		return nil, err
+	}
+	resolver := r.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	ctx := gocontext.Background()
+	if r.Timeout > 0 {
+		var cancel gocontext.CancelFunc
+		ctx, cancel = gocontext.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+	var hosts []string
+	if r.SRV {
+		_, addrs, err := resolver.LookupSRV(ctx, "", "", u.Hostname())
+		if err != nil {
+			return nil, err
+		}
+		for _, addr := range addrs {
+			hosts = append(hosts, joinHostPort(
+				strings.TrimSuffix(addr.Target, "."),
+				strconv.Itoa(int(addr.Port))))
+		}
+	} else {
+		addrs, err := resolver.LookupHost(ctx, u.Hostname())
+		if err != nil {
+			return nil, err
+		}
+		for _, addr := range addrs {
+			hosts = append(hosts, joinHostPort(addr, u.Port()))
+		}
+	}
+	sort.Strings(hosts)
+	uriList := make([]string, len(hosts))
+	for i, host := range hosts {
+		u.Host = host
+		uriList[i] = u.String()
+	}
+	return uriList, nil
+}
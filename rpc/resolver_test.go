@@ -0,0 +1,406 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/resolver_test.go                                   *
+ *                                                        *
+ * hprose client service discovery test for Go.           *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	gocontext "context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type whoStub struct {
+	Who func() (string, error)
+}
+
+type connCounter struct {
+	accepted int32
+	closed   int32
+}
+
+func (c *connCounter) OnAccept(context *SocketContext) {
+	atomic.AddInt32(&c.accepted, 1)
+}
+
+func (c *connCounter) OnClose(context *SocketContext) {
+	atomic.AddInt32(&c.closed, 1)
+}
+
+func newWhoServer(name string) (*TCPServer, *connCounter) {
+	server := NewTCPServer("tcp://127.0.0.1:0")
+	server.AddFunction("who", func() string { return name }, Options{})
+	counter := new(connCounter)
+	server.Event = counter
+	server.Handle()
+	return server, counter
+}
+
+type errorCounter struct {
+	errors int32
+}
+
+func (e *errorCounter) OnError(name string, err error) {
+	if name == "" && err != nil {
+		atomic.AddInt32(&e.errors, 1)
+	}
+}
+
+// testResolver calls update when the test wants
+type testResolver struct {
+	update func(uriList []string, err error)
+}
+
+func (r *testResolver) Start(update func(uriList []string, err error)) {
+	r.update = update
+}
+
+func (r *testResolver) Stop() {
+	r.update = nil
+}
+
+func TestParseURIList(t *testing.T) {
+	expired := time.Now().Add(-time.Minute).Unix()
+	expires := time.Now().Add(time.Minute).Unix()
+	tests := map[string][]string{
+		"":                                      nil,
+		"# comment\n\ntcp://a:1\n tcp://b:1 \n": {"tcp://a:1", "tcp://b:1"},
+		`["tcp://a:1", "tcp://b:1"]`:            {"tcp://a:1", "tcp://b:1"},
+		fmt.Sprintf(`[{"uri":"tcp://a:1","expires":%d},{"uri":"tcp://b:1","expires":%d},{"uri":"tcp://c:1"},{}]`,
+			expires, expired): {"tcp://a:1", "tcp://c:1"},
+	}
+	for data, want := range tests {
+		uriList, err := parseURIList([]byte(data))
+		if err != nil || !reflect.DeepEqual(uriList, want) {
+			t.Error(data, uriList, err)
+		}
+	}
+	if _, err := parseURIList([]byte(`["tcp://a:1",`)); err == nil {
+		t.Error("the malformed JSON is accepted")
+	}
+}
+
+func TestSameURIList(t *testing.T) {
+	if !sameURIList(testURIList, []string{testURIList[2], testURIList[0], testURIList[1]}) {
+		t.Error("the order is compared")
+	}
+	if sameURIList(testURIList, testURIList[:2]) ||
+		sameURIList([]string{"a", "a"}, []string{"a", "b"}) {
+		t.Error("the different lists are the same")
+	}
+}
+
+func TestStaticResolver(t *testing.T) {
+	server, _ := newWhoServer("s1")
+	defer server.Close()
+	client := NewTCPClient("tcp://127.0.0.1:1")
+	defer client.Close()
+	client.SetResolver(NewStaticResolver(server.URI()))
+	if uriList := client.URIList(); len(uriList) != 1 || uriList[0] != server.URI() {
+		t.Fatal(uriList)
+	}
+	var stub *whoStub
+	client.UseService(&stub)
+	if s, err := stub.Who(); s != "s1" || err != nil {
+		t.Error(s, err)
+	}
+}
+
+func TestFileResolver(t *testing.T) {
+	s1, _ := newWhoServer("s1")
+	defer s1.Close()
+	s2, _ := newWhoServer("s2")
+	defer s2.Close()
+	dir, err := ioutil.TempDir("", "hprose")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "services.txt")
+	write := func(data string) {
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("# services\n" + s1.URI() + "\n\n")
+	client := NewTCPClient(s2.URI())
+	defer client.Close()
+	client.SetMaxPoolSize(4)
+	event := new(errorCounter)
+	client.SetEvent(event)
+	resolver := NewFileResolver(path)
+	resolver.Interval = 10 * time.Millisecond
+	client.SetResolver(resolver)
+	if uriList := client.URIList(); !sameURIList(uriList, []string{s1.URI()}) {
+		t.Fatal(uriList)
+	}
+	var stub *whoStub
+	client.UseService(&stub)
+	// the requests in flight are not interrupted by the updates
+	var stop int32
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for atomic.LoadInt32(&stop) == 0 {
+				if _, err := stub.Who(); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	both := []string{s1.URI(), s2.URI()}
+	write(`["` + s1.URI() + `", "` + s2.URI() + `"]`)
+	if !waitUntil(time.Second, func() bool { return sameURIList(client.URIList(), both) }) {
+		t.Error(client.URIList())
+	}
+	write(s2.URI())
+	if !waitUntil(time.Second, func() bool { return sameURIList(client.URIList(), both[1:]) }) {
+		t.Error(client.URIList())
+	}
+	// the invalid address is reported and the current addresses are kept
+	write("bogus://x")
+	if !waitUntil(time.Second, func() bool { return atomic.LoadInt32(&event.errors) > 0 }) {
+		t.Error("the error is not reported")
+	}
+	atomic.StoreInt32(&stop, 1)
+	wg.Wait()
+	if uriList := client.URIList(); !sameURIList(uriList, both[1:]) {
+		t.Error(uriList)
+	}
+	for i := 0; i < 5; i++ {
+		if s, err := stub.Who(); s != "s2" || err != nil {
+			t.Error(s, err)
+		}
+	}
+	client.SetResolver(nil)
+	if resolver.stop != nil {
+		t.Error("the resolver is not stopped")
+	}
+}
+
+// TestResolverKeepsConnections checks that the pooled connections to the
+// service addresses which are still resolved are not closed by the updates.
+func TestResolverKeepsConnections(t *testing.T) {
+	s1, c1 := newWhoServer("s1")
+	defer s1.Close()
+	s2, c2 := newWhoServer("s2")
+	defer s2.Close()
+	client := NewTCPClient(s1.URI())
+	defer client.Close()
+	client.SetBalancer(NewRoundRobinBalancer())
+	resolver := new(testResolver)
+	client.SetResolver(resolver)
+	resolver.update([]string{s1.URI(), s2.URI()}, nil)
+	var stub *whoStub
+	client.UseService(&stub)
+	counts := make(map[string]int)
+	for i := 0; i < 4; i++ {
+		s, _ := stub.Who()
+		counts[s]++
+	}
+	if counts["s1"] != 2 || counts["s2"] != 2 {
+		t.Fatal(counts)
+	}
+	resolver.update([]string{s1.URI()}, nil)
+	if !waitUntil(time.Second, func() bool { return atomic.LoadInt32(&c2.closed) == 1 }) {
+		t.Error("the connection to the removed address is not closed")
+	}
+	for i := 0; i < 4; i++ {
+		if s, err := stub.Who(); s != "s1" || err != nil {
+			t.Error(s, err)
+		}
+	}
+	resolver.update([]string{s1.URI(), "tcp://127.0.0.1:1"}, nil)
+	resolver.update([]string{s1.URI()}, nil)
+	if s, err := stub.Who(); s != "s1" || err != nil {
+		t.Error(s, err)
+	}
+	if accepted := atomic.LoadInt32(&c1.accepted); accepted != 1 {
+		t.Error("the connection to s1 is reopened", accepted)
+	}
+	if closed := atomic.LoadInt32(&c1.closed); closed != 0 {
+		t.Error("the connection to s1 is closed", closed)
+	}
+}
+
+// dnsServer answers the A queries of svc.test. and the SRV queries of
+// _hprose._tcp.svc.test., the other names are not found.
+func dnsServer(t *testing.T) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if response := dnsResponse(buf[:n]); response != nil {
+				conn.WriteTo(response, addr)
+			}
+		}
+	}()
+	t.Cleanup(func() { conn.Close() })
+	return conn.LocalAddr().String()
+}
+
+func dnsName(name string) (data []byte) {
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		data = append(data, byte(len(label)))
+		data = append(data, label...)
+	}
+	return append(data, 0)
+}
+
+func dnsResponse(query []byte) []byte {
+	const typeA, typeSRV = 1, 33
+	if len(query) < 12 {
+		return nil
+	}
+	var labels []string
+	i := 12
+	for i < len(query) && query[i] != 0 {
+		n := int(query[i])
+		if i+1+n > len(query) {
+			return nil
+		}
+		labels = append(labels, strings.ToLower(string(query[i+1:i+1+n])))
+		i += 1 + n
+	}
+	i += 5
+	if i > len(query) {
+		return nil
+	}
+	name := strings.Join(labels, ".")
+	qtype := binary.BigEndian.Uint16(query[i-4:])
+	var answers [][]byte
+	rcode := 0
+	switch {
+	case name == "svc.test" && qtype == typeA:
+		answers = [][]byte{{127, 0, 0, 2}, {127, 0, 0, 1}}
+	case name == "_hprose._tcp.svc.test" && qtype == typeSRV:
+		for j, port := range []uint16{4002, 4001} {
+			rdata := make([]byte, 6)
+			binary.BigEndian.PutUint16(rdata, 1)
+			binary.BigEndian.PutUint16(rdata[4:], port)
+			answers = append(answers, append(rdata, dnsName(fmt.Sprintf("h%d.test.", 2-j))...))
+		}
+	case name != "svc.test" && name != "_hprose._tcp.svc.test":
+		rcode = 3
+	}
+	response := append([]byte(nil), query[:i]...)
+	binary.BigEndian.PutUint16(response[2:], uint16(0x8180|rcode))
+	binary.BigEndian.PutUint16(response[4:], 1)
+	binary.BigEndian.PutUint16(response[6:], uint16(len(answers)))
+	binary.BigEndian.PutUint32(response[8:], 0)
+	for _, rdata := range answers {
+		// the name is a pointer to the question
+		header := []byte{0xc0, 12, 0, 0, 0, 1, 0, 0, 0, 1, 0, 0}
+		binary.BigEndian.PutUint16(header[2:], qtype)
+		binary.BigEndian.PutUint16(header[10:], uint16(len(rdata)))
+		response = append(append(response, header...), rdata...)
+	}
+	return response
+}
+
+func lookupOnce(r *DNSResolver) (uriList []string, err error) {
+	r.Start(func(l []string, e error) {
+		uriList, err = l, e
+	})
+	r.Stop()
+	return
+}
+
+func TestDNSResolver(t *testing.T) {
+	addr := dnsServer(t)
+	var dialer net.Dialer
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx gocontext.Context, network, address string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "udp", addr)
+		},
+	}
+	r := NewDNSResolver("tcp://svc.test:4321/path")
+	r.Resolver = resolver
+	uriList, err := lookupOnce(r)
+	want := []string{"tcp://127.0.0.1:4321/path", "tcp://127.0.0.2:4321/path"}
+	if err != nil || !reflect.DeepEqual(uriList, want) {
+		t.Error(uriList, err)
+	}
+	r = NewDNSResolver("tcp://_hprose._tcp.svc.test")
+	r.SRV = true
+	r.Resolver = resolver
+	uriList, err = lookupOnce(r)
+	want = []string{"tcp://h1.test:4001", "tcp://h2.test:4002"}
+	if err != nil || !reflect.DeepEqual(uriList, want) {
+		t.Error(uriList, err)
+	}
+	r = NewDNSResolver("tcp://missing.test:4321")
+	r.Resolver = resolver
+	if uriList, err = lookupOnce(r); err == nil {
+		t.Error(uriList)
+	}
+}
+
+func TestDNSResolverUpdate(t *testing.T) {
+	addr := dnsServer(t)
+	var dialer net.Dialer
+	var down int32
+	client := NewTCPClient("tcp://127.0.0.1:1")
+	defer client.Close()
+	event := new(errorCounter)
+	client.SetEvent(event)
+	r := NewDNSResolver("tcp://svc.test:4321")
+	r.Interval = 10 * time.Millisecond
+	r.Resolver = &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx gocontext.Context, network, address string) (net.Conn, error) {
+			if atomic.LoadInt32(&down) == 1 {
+				return nil, errors.New("the DNS server is down")
+			}
+			return dialer.DialContext(ctx, "udp", addr)
+		},
+	}
+	client.SetResolver(r)
+	want := []string{"tcp://127.0.0.1:4321", "tcp://127.0.0.2:4321"}
+	if uriList := client.URIList(); !sameURIList(uriList, want) {
+		t.Error(uriList)
+	}
+	atomic.StoreInt32(&down, 1)
+	if !waitUntil(time.Second, func() bool { return atomic.LoadInt32(&event.errors) > 0 }) {
+		t.Error("the error is not reported")
+	}
+	if uriList := client.URIList(); !sameURIList(uriList, want) {
+		t.Error(uriList)
+	}
+}
@@ -0,0 +1,172 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/retry_policy.go                                    *
+ *                                                        *
+ * hprose client retry policy for Go.                     *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	gocontext "context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RetryPolicy decides whether and when a failed request is retried.
+//
+// Backoff is called with the retry attempt (starting from 1), the elapsed time
+// since the first request was sent and the send error. It returns the delay
+// before the retry, ok is false if the request should not be retried.
+//
+// The policy is only used for the idempotent requests, and the retry count is
+// still limited by the Retry setting.
+type RetryPolicy interface {
+	Backoff(attempt int, elapsed time.Duration, err error,
+		context *ClientContext) (delay time.Duration, ok bool)
+}
+
+// LegacyRetryPolicy is the default retry policy, it retries all errors after
+// attempt * 500ms (minus 500ms for every other service address when the
+// request is failswitch), and the delay is capped at 5s.
+type LegacyRetryPolicy struct{}
+
+// Backoff implements the RetryPolicy Backoff method
+func (LegacyRetryPolicy) Backoff(
+	attempt int, elapsed time.Duration, err error,
+	context *ClientContext) (time.Duration, bool) {
+	interval := attempt * 500
+	if context.Failswitch && context.Client != nil {
+		interval -= (len(context.Client.URIList()) - 1) * 500
+	}
+	if interval > 5000 {
+		interval = 5000
+	}
+	if interval < 0 {
+		interval = 0
+	}
+	return time.Duration(interval) * time.Millisecond, true
+}
+
+// ExponentialBackoff is a retry policy with exponential backoff and jitter
+//
+// The delay of the attempt n is InitialInterval * Multiplier^(n-1), capped at
+// MaxInterval, and randomized by ±Jitter (clamped to [0, 1]) of itself. The
+// request is not retried if the delay will exceed MaxElapsedTime (0 means no
+// limit) since the first request was sent, or if Retryable (IsRetryable if
+// nil) returns false.
+type ExponentialBackoff struct {
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	Multiplier      float64
+	Jitter          float64
+	MaxElapsedTime  time.Duration
+	Retryable       func(err error) bool
+}
+
+// NewExponentialBackoff is the constructor of ExponentialBackoff
+func NewExponentialBackoff() *ExponentialBackoff {
+	return &ExponentialBackoff{
+		InitialInterval: 100 * time.Millisecond,
+		MaxInterval:     10 * time.Second,
+		Multiplier:      2,
+		Jitter:          0.2,
+		MaxElapsedTime:  time.Minute,
+	}
+}
+
+// Backoff implements the RetryPolicy Backoff method
+func (policy *ExponentialBackoff) Backoff(
+	attempt int, elapsed time.Duration, err error,
+	context *ClientContext) (time.Duration, bool) {
+	retryable := policy.Retryable
+	if retryable == nil {
+		retryable = IsRetryable
+	}
+	if !retryable(err) {
+		return 0, false
+	}
+	multiplier := policy.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	delay := 0.0
+	if policy.InitialInterval > 0 {
+		delay = float64(policy.InitialInterval) *
+			math.Pow(multiplier, float64(attempt-1))
+	}
+	if policy.MaxInterval > 0 && delay > float64(policy.MaxInterval) {
+		delay = float64(policy.MaxInterval)
+	}
+	jitter := math.Min(math.Max(policy.Jitter, 0), 1)
+	if jitter > 0 {
+		delay += delay * jitter * (2*rand.Float64() - 1)
+	}
+	// the delay may overflow time.Duration when MaxInterval is 0
+	d := time.Duration(math.MaxInt64)
+	if delay < float64(math.MaxInt64) {
+		d = time.Duration(delay)
+	}
+	if policy.MaxElapsedTime > 0 && d > policy.MaxElapsedTime-elapsed {
+		return 0, false
+	}
+	return d, true
+}
+
+// IsRetryable reports whether err is a transient error, that is the timeout,
+// the network error, the broken connection or ErrCircuitOpen.
+func IsRetryable(err error) bool {
+	if err == nil ||
+		errors.Is(err, gocontext.Canceled) ||
+		errors.Is(err, gocontext.DeadlineExceeded) {
+		return false
+	}
+	if err == ErrTimeout || err == ErrCircuitOpen ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+var retryPolicies = map[string]RetryPolicy{
+	"legacy":      LegacyRetryPolicy{},
+	"exponential": NewExponentialBackoff(),
+}
+var retryPoliciesLocker sync.RWMutex
+
+// RegisterRetryPolicy registers the retry policy with name, so it can be used
+// by the retrypolicy tag of the remote service proxy object.
+func RegisterRetryPolicy(name string, policy RetryPolicy) {
+	retryPoliciesLocker.Lock()
+	retryPolicies[strings.ToLower(name)] = policy
+	retryPoliciesLocker.Unlock()
+}
+
+func getRetryPolicy(name string) RetryPolicy {
+	retryPoliciesLocker.RLock()
+	defer retryPoliciesLocker.RUnlock()
+	policy, ok := retryPolicies[strings.ToLower(name)]
+	if !ok {
+		panic(errors.New("unknown retry policy: " + name))
+	}
+	return policy
+}
@@ -0,0 +1,187 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/retry_policy_test.go                               *
+ *                                                        *
+ * hprose client retry policy test for Go.                *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	gocontext "context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	policy := NewExponentialBackoff()
+	policy.Jitter = 0
+	policy.MaxInterval = time.Second
+	policy.MaxElapsedTime = 0
+	context := new(ClientContext)
+	want := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	}
+	for i, d := range want {
+		delay, ok := policy.Backoff(i+1, 0, ErrTimeout, context)
+		if !ok || delay != d {
+			t.Error(i+1, delay, ok)
+		}
+	}
+	policy.Multiplier = 0.5
+	if delay, _ := policy.Backoff(3, 0, ErrTimeout, context); delay != policy.InitialInterval {
+		t.Error("the multiplier less than 1 is used", delay)
+	}
+}
+
+func TestExponentialBackoffJitter(t *testing.T) {
+	policy := NewExponentialBackoff()
+	policy.Jitter = 0.5
+	context := new(ClientContext)
+	min, max := time.Hour, time.Duration(0)
+	for i := 0; i < 1000; i++ {
+		delay, ok := policy.Backoff(2, 0, ErrTimeout, context)
+		if !ok {
+			t.Fatal("not retried")
+		}
+		if delay < min {
+			min = delay
+		}
+		if delay > max {
+			max = delay
+		}
+	}
+	if min < 100*time.Millisecond || max > 300*time.Millisecond {
+		t.Error(min, max)
+	}
+	if max-min < 100*time.Millisecond {
+		t.Error("the delay is not randomized", min, max)
+	}
+}
+
+func TestExponentialBackoffLimits(t *testing.T) {
+	policy := NewExponentialBackoff()
+	policy.Jitter = 0
+	policy.MaxElapsedTime = time.Second
+	context := new(ClientContext)
+	if _, ok := policy.Backoff(1, 800*time.Millisecond, ErrTimeout, context); !ok {
+		t.Error("retry within MaxElapsedTime")
+	}
+	if _, ok := policy.Backoff(2, 900*time.Millisecond, ErrTimeout, context); ok {
+		t.Error("retry after MaxElapsedTime")
+	}
+	if _, ok := policy.Backoff(1, 0, errors.New("remote"), context); ok {
+		t.Error("the error is not retryable")
+	}
+	policy.Retryable = func(err error) bool { return true }
+	if _, ok := policy.Backoff(1, 0, errors.New("remote"), context); !ok {
+		t.Error("Retryable is not used")
+	}
+}
+
+func TestExponentialBackoffOverflow(t *testing.T) {
+	policy := NewExponentialBackoff()
+	policy.MaxInterval = 0
+	policy.MaxElapsedTime = 0
+	context := new(ClientContext)
+	// the delay overflows time.Duration without MaxInterval
+	for _, attempt := range []int{100, 10000} {
+		if d, ok := policy.Backoff(attempt, 0, ErrTimeout, context); !ok || d <= 0 {
+			t.Error(attempt, d, ok)
+		}
+	}
+	policy.MaxElapsedTime = time.Minute
+	if d, ok := policy.Backoff(10000, time.Second, ErrTimeout, context); ok {
+		t.Error("retry after MaxElapsedTime", d)
+	}
+	// the jitter greater than 1 can't make the delay negative
+	policy = NewExponentialBackoff()
+	policy.Jitter = 10
+	for i := 0; i < 1000; i++ {
+		if d, _ := policy.Backoff(1, 0, ErrTimeout, context); d < 0 || d > 200*time.Millisecond {
+			t.Fatal(d)
+		}
+	}
+	policy.Jitter = -1
+	if d, _ := policy.Backoff(1, 0, ErrTimeout, context); d != 100*time.Millisecond {
+		t.Error(d)
+	}
+}
+
+func TestLegacyRetryPolicy(t *testing.T) {
+	context := new(ClientContext)
+	for attempt, want := range map[int]time.Duration{
+		1:  500 * time.Millisecond,
+		4:  2 * time.Second,
+		20: 5 * time.Second,
+	} {
+		delay, ok := LegacyRetryPolicy{}.Backoff(attempt, 0, nil, context)
+		if !ok || delay != want {
+			t.Error(attempt, delay, ok)
+		}
+	}
+	context.Failswitch = true
+	context.Client = NewTCPClient(testURIList...)
+	defer context.Client.Close()
+	if delay, _ := (LegacyRetryPolicy{}).Backoff(2, 0, nil, context); delay != 0 {
+		t.Error(delay)
+	}
+	if delay, _ := (LegacyRetryPolicy{}).Backoff(4, 0, nil, context); delay != time.Second {
+		t.Error(delay)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := map[error]bool{
+		nil:                        false,
+		gocontext.Canceled:         false,
+		gocontext.DeadlineExceeded: false,
+		ErrTimeout:                 true,
+		ErrCircuitOpen:             true,
+		io.EOF:                     true,
+		io.ErrUnexpectedEOF:        true,
+		&net.OpError{Op: "dial", Err: errors.New("refused")}: true,
+		fmt.Errorf("wrapped: %w", io.EOF):                    true,
+		errors.New("remote error"):                           false,
+	}
+	for err, want := range tests {
+		if IsRetryable(err) != want {
+			t.Error(err, want)
+		}
+	}
+}
+
+func TestRegisterRetryPolicy(t *testing.T) {
+	policy := NewExponentialBackoff()
+	RegisterRetryPolicy("TestPolicy", policy)
+	if getRetryPolicy("testpolicy") != policy {
+		t.Error("the registered policy is not found")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("unknown retry policy")
+		}
+	}()
+	getRetryPolicy("unknown")
+}
@@ -12,7 +12,7 @@
  *                                                        *
  * hprose service for Go.                                 *
  *                                                        *
- * LastModified: Sep 23, 2016                             *
+ * LastModified: Oct 18, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -42,5 +42,10 @@ type Service interface {
 	AddBeforeFilterHandler(handler ...FilterHandler) Service
 	AddAfterFilterHandler(handler ...FilterHandler) Service
 	Publish(topic string, timeout time.Duration, heartbeat time.Duration) Service
+	PublishWithOptions(topic string, options PublishOptions) Service
+	PushBroker() PushBroker
+	SetPushBroker(broker PushBroker) error
+	Register(uri string) error
+	Deregister() error
 	Clients
 }
@@ -12,7 +12,7 @@
  *                                                        *
  * hprose service context for Go.                         *
  *                                                        *
- * LastModified: Oct 6, 2016                              *
+ * LastModified: Oct 18, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -26,6 +26,8 @@ type ServiceContext interface {
 	Method() *Method
 	IsMissingMethod() bool
 	ByRef() bool
+	View() string
+	SetView(view string)
 	setMethod(method *Method)
 	setIsMissingMethod(value bool)
 	setByRef(value bool)
@@ -37,6 +39,7 @@ type serviceContext struct {
 	service         Service
 	isMissingMethod bool
 	byRef           bool
+	view            string
 }
 
 func (context *serviceContext) initServiceContext(service Service) {
@@ -45,6 +48,7 @@ func (context *serviceContext) initServiceContext(service Service) {
 	context.method = nil
 	context.isMissingMethod = false
 	context.byRef = false
+	context.view = ""
 }
 
 func (context *serviceContext) Method() *Method {
@@ -63,6 +67,14 @@ func (context *serviceContext) ByRef() bool {
 	return context.byRef
 }
 
+func (context *serviceContext) View() string {
+	return context.view
+}
+
+func (context *serviceContext) SetView(view string) {
+	context.view = view
+}
+
 func (context *serviceContext) setMethod(method *Method) {
 	context.method = method
 }
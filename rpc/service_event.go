@@ -12,7 +12,7 @@
  *                                                        *
  * hprose service event for Go.                           *
  *                                                        *
- * LastModified: Sep 11, 2016                             *
+ * LastModified: Oct 18, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -55,3 +55,15 @@ type subscribeEvent interface {
 type unsubscribeEvent interface {
 	OnUnsubscribe(topic string, id string, service Service)
 }
+
+type deliveryFailedEvent interface {
+	OnDeliveryFailed(topic string, id string, result interface{})
+}
+
+type pushBrokerErrorEvent interface {
+	OnPushBrokerError(topic string, err error)
+}
+
+type registerErrorEvent interface {
+	OnRegisterError(uri string, err error)
+}
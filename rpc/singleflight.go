@@ -0,0 +1,120 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/singleflight.go                                    *
+ *                                                        *
+ * hprose client request coalescing for Go.               *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	gocontext "context"
+	"reflect"
+	"sync"
+)
+
+type flightKey struct {
+	client  *baseClient
+	request string
+}
+
+type flightCall struct {
+	done     chan struct{}
+	cancel   gocontext.CancelFunc
+	waiters  int
+	response []byte
+	err      error
+}
+
+// SingleFlight coalesces the identical idempotent invokings in flight.
+//
+// The invokings are identical when they are invoked by the same client with
+// the same encoded request (the method name and the serialized arguments).
+// Only one request is sent for them, and the response is decoded for every
+// invoking with its own settings. The request is sent with the settings of
+// the first invoking, and it is only canceled when all the invokings are
+// canceled. The invokings which are not idempotent, oneway or byref are not
+// coalesced.
+//
+// Usage:
+//
+//	client.AddInvokeHandler(rpc.NewSingleFlight().Handler)
+type SingleFlight struct {
+	calls  map[flightKey]*flightCall
+	locker sync.Mutex
+}
+
+// NewSingleFlight is the constructor of SingleFlight
+func NewSingleFlight() *SingleFlight {
+	return &SingleFlight{calls: make(map[flightKey]*flightCall)}
+}
+
+// Handler is the invoke handler of SingleFlight
+func (sf *SingleFlight) Handler(
+	name string,
+	args []reflect.Value,
+	context Context,
+	next NextInvokeHandler) (results []reflect.Value, err error) {
+	if context, ok := context.(*ClientContext); ok {
+		context.flight = sf
+	}
+	return next(name, args, context)
+}
+
+func (sf *SingleFlight) remove(key flightKey, call *flightCall) {
+	if sf.calls[key] == call {
+		delete(sf.calls, key)
+	}
+}
+
+// send sends the request or waits for the identical request in flight
+func (sf *SingleFlight) send(
+	client *baseClient,
+	request []byte,
+	context *ClientContext) ([]byte, error) {
+	key := flightKey{client, string(request)}
+	sf.locker.Lock()
+	call := sf.calls[key]
+	if call == nil {
+		ctx, cancel := gocontext.WithCancel(gocontext.Background())
+		call = &flightCall{done: make(chan struct{}), cancel: cancel}
+		sf.calls[key] = call
+		shared := client.cloneContext(context, ctx, nil)
+		go func() {
+			call.response, call.err = client.send(request, shared)
+			client.releaseContext(shared)
+			sf.locker.Lock()
+			sf.remove(key, call)
+			sf.locker.Unlock()
+			close(call.done)
+			cancel()
+		}()
+	}
+	call.waiters++
+	sf.locker.Unlock()
+	select {
+	case <-call.done:
+		return call.response, call.err
+	case <-context.Context().Done():
+		sf.locker.Lock()
+		call.waiters--
+		if call.waiters == 0 {
+			sf.remove(key, call)
+			call.cancel()
+		}
+		sf.locker.Unlock()
+		return nil, context.Context().Err()
+	}
+}
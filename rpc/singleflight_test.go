@@ -0,0 +1,168 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/singleflight_test.go                               *
+ *                                                        *
+ * hprose client single flight test for Go.               *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	gocontext "context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type flightStub struct {
+	Sum       func(gocontext.Context, int, int) (int, error)    `name:"sum" idempotent:"true"`
+	SumString func(gocontext.Context, int, int) (string, error) `name:"sum" idempotent:"true"`
+	SumOnce   func(gocontext.Context, int, int) (int, error)    `name:"sum"`
+}
+
+func newFlightClient(t *testing.T, sf *SingleFlight, calls *int32) (*flightStub, func()) {
+	server := NewTCPServer("tcp://127.0.0.1:0")
+	server.AddFunction("sum", func(a, b int) int {
+		atomic.AddInt32(calls, 1)
+		time.Sleep(100 * time.Millisecond)
+		return a + b
+	}, Options{})
+	if err := server.Handle(); err != nil {
+		t.Fatal(err)
+	}
+	client := NewTCPClient(server.URI())
+	client.SetMaxPoolSize(4)
+	client.AddInvokeHandler(sf.Handler)
+	var stub *flightStub
+	client.UseService(&stub)
+	return stub, func() {
+		client.Close()
+		server.Close()
+	}
+}
+
+func TestSingleFlightCoalescing(t *testing.T) {
+	var calls int32
+	stub, closeAll := newFlightClient(t, NewSingleFlight(), &calls)
+	defer closeAll()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if n, err := stub.Sum(gocontext.Background(), 1, 2); n != 3 || err != nil {
+				t.Error(n, err)
+			}
+		}()
+		// the response is decoded with the settings of every invoking
+		go func() {
+			defer wg.Done()
+			if s, err := stub.SumString(gocontext.Background(), 1, 2); s != "3" || err != nil {
+				t.Error(s, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Error("the identical requests are not coalesced", n)
+	}
+	atomic.StoreInt32(&calls, 0)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if n, err := stub.Sum(gocontext.Background(), i, 0); n != i || err != nil {
+				t.Error(n, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(&calls); n != 3 {
+		t.Error("the different requests are coalesced", n)
+	}
+	atomic.StoreInt32(&calls, 0)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stub.SumOnce(gocontext.Background(), 1, 1)
+		}()
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(&calls); n != 3 {
+		t.Error("the non-idempotent requests are coalesced", n)
+	}
+}
+
+func TestSingleFlightCancel(t *testing.T) {
+	var calls int32
+	stub, closeAll := newFlightClient(t, NewSingleFlight(), &calls)
+	defer closeAll()
+	var wg sync.WaitGroup
+	// the canceled invoking does not cancel the other invokings
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		if n, err := stub.Sum(gocontext.Background(), 1, 2); n != 3 || err != nil {
+			t.Error(n, err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		ctx, cancel := gocontext.WithTimeout(gocontext.Background(), 20*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		if _, err := stub.Sum(ctx, 1, 2); err != gocontext.DeadlineExceeded {
+			t.Error(err)
+		}
+		if elapsed := time.Since(start); elapsed > 80*time.Millisecond {
+			t.Error("the canceled invoking waits for the response", elapsed)
+		}
+	}()
+	wg.Wait()
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Error(n)
+	}
+	// the request is canceled when all the invokings are canceled, and the
+	// next invoking sends a new request
+	atomic.StoreInt32(&calls, 0)
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), 20*time.Millisecond)
+	if _, err := stub.Sum(ctx, 5, 5); err != gocontext.DeadlineExceeded {
+		t.Error(err)
+	}
+	cancel()
+	if n, err := stub.Sum(gocontext.Background(), 5, 5); n != 10 || err != nil {
+		t.Error(n, err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Error(n)
+	}
+}
+
+func TestSingleFlightRemove(t *testing.T) {
+	var calls int32
+	sf := NewSingleFlight()
+	stub, closeAll := newFlightClient(t, sf, &calls)
+	defer closeAll()
+	if _, err := stub.Sum(gocontext.Background(), 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	sf.locker.Lock()
+	defer sf.locker.Unlock()
+	if len(sf.calls) != 0 {
+		t.Error("the finished calls are not removed", len(sf.calls))
+	}
+}
@@ -12,7 +12,7 @@
  *                                                        *
  * hprose socket client for Go.                           *
  *                                                        *
- * LastModified: Oct 20, 2016                             *
+ * LastModified: Oct 18, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -20,6 +20,8 @@
 package rpc
 
 import (
+	"bufio"
+	gocontext "context"
 	"crypto/tls"
 	"net"
 	"runtime"
@@ -33,18 +35,83 @@ type connEntry struct {
 	timer *time.Timer
 }
 
+type connPool struct {
+	entries chan *connEntry
+	count   int32
+}
+
+func (pool *connPool) getConn() *connEntry {
+	for {
+		select {
+		case entry := <-pool.entries:
+			if entry.timer != nil {
+				entry.timer.Stop()
+			}
+			if entry.conn != nil {
+				return entry
+			}
+			continue
+		default:
+			return nil
+		}
+	}
+}
+
+func (pool *connPool) close(conn net.Conn) {
+	conn.Close()
+	atomic.AddInt32(&pool.count, -1)
+}
+
+type duplexConn struct {
+	conn        net.Conn
+	uri         string
+	responses   map[uint32]chan socketResponse
+	timer       *time.Timer
+	closed      bool
+	locker      sync.Mutex
+	writeLocker sync.Mutex
+}
+
+func (dc *duplexConn) pending() int {
+	dc.locker.Lock()
+	defer dc.locker.Unlock()
+	return len(dc.responses)
+}
+
+func (dc *duplexConn) remove(id uint32, idleTimeout time.Duration) {
+	dc.locker.Lock()
+	delete(dc.responses, id)
+	if len(dc.responses) == 0 && !dc.closed {
+		dc.timer.Reset(idleTimeout)
+	}
+	dc.locker.Unlock()
+}
+
 // SocketClient is base struct for TCPClient and UnixClient
+//
+// When FullDuplex is true, the requests are sent with ids and multiplexed over
+// at most MaxPoolSize connections per service address, the responses are
+// matched by the ids. MaxPendingRequests is the max count of the requests
+// which are waiting for the responses, the other requests wait until the
+// count is below it or they are timeout. FullDuplex and MaxPendingRequests
+// should be set before the first request.
 type SocketClient struct {
 	baseClient
-	ReadBuffer  int
-	WriteBuffer int
-	IdleTimeout time.Duration
-	TLSConfig   *tls.Config
-	connPool    chan *connEntry
-	connCount   int32
-	nextid      uint32
-	createConn  func() net.Conn
-	cond        sync.Cond
+	ReadBuffer         int
+	WriteBuffer        int
+	IdleTimeout        time.Duration
+	TLSConfig          *tls.Config
+	FullDuplex         bool
+	MaxPendingRequests int
+	pools              map[string]*connPool
+	duplexConns        map[string][]*duplexConn
+	dialing            map[string]int
+	pending            chan struct{}
+	poolSize           int
+	closed             bool
+	nextid             uint32
+	createConn         func(uri string) net.Conn
+	cond               sync.Cond
 }
 
 func (client *SocketClient) initSocketClient() {
@@ -53,8 +120,13 @@ func (client *SocketClient) initSocketClient() {
 	client.WriteBuffer = 0
 	client.IdleTimeout = 30 * time.Second
 	client.TLSConfig = nil
-	client.connPool = make(chan *connEntry, runtime.NumCPU())
-	client.connCount = 0
+	client.FullDuplex = false
+	client.MaxPendingRequests = 1024
+	client.pools = make(map[string]*connPool)
+	client.duplexConns = make(map[string][]*duplexConn)
+	client.dialing = make(map[string]int)
+	client.poolSize = runtime.NumCPU()
+	client.closed = false
 	client.nextid = 0
 	client.cond.L = &sync.Mutex{}
 	client.SendAndReceive = client.sendAndReceive
@@ -71,81 +143,188 @@ func (client *SocketClient) SetTLSClientConfig(config *tls.Config) {
 }
 
 // MaxPoolSize returns the max conn pool size of hprose socket client
+//
+// Every service address has its own conn pool.
 func (client *SocketClient) MaxPoolSize() int {
-	return cap(client.connPool)
+	return client.poolSize
 }
 
 // SetMaxPoolSize sets the max conn pool size of hprose socket client
 func (client *SocketClient) SetMaxPoolSize(size int) {
-	pool := make(chan *connEntry, size)
-	for i := 0; i < len(client.connPool); i++ {
-		select {
-		case pool <- <-client.connPool:
-		default:
+	client.cond.L.Lock()
+	client.poolSize = size
+	for _, pool := range client.pools {
+		entries := make(chan *connEntry, size)
+		for i := len(pool.entries); i > 0; i-- {
+			select {
+			case entries <- <-pool.entries:
+			default:
+			}
 		}
+		pool.entries = entries
 	}
-	client.connPool = pool
+	client.cond.L.Unlock()
 }
 
-func (client *SocketClient) getConn() *connEntry {
-	for {
-		select {
-		case entry, closed := <-client.connPool:
-			if !closed {
-				panic(errClientIsAlreadyClosed)
-			}
-			if entry.timer != nil {
-				entry.timer.Stop()
-			}
-			if entry.conn != nil {
-				return entry
-			}
-			continue
-		default:
-			return nil
-		}
+func (client *SocketClient) getPool(uri string) *connPool {
+	pool := client.pools[uri]
+	if pool == nil {
+		pool = &connPool{entries: make(chan *connEntry, client.poolSize)}
+		client.pools[uri] = pool
 	}
+	return pool
 }
 
-func (client *SocketClient) fetchConn(fullDuplex bool) *connEntry {
+func (client *SocketClient) fetchConn(
+	uri string, fullDuplex bool) (*connPool, *connEntry, error) {
 	client.cond.L.Lock()
+	if client.closed {
+		client.cond.L.Unlock()
+		return nil, nil, errClientIsAlreadyClosed
+	}
+	pool := client.getPool(uri)
 	for {
-		entry := client.getConn()
+		entry := pool.getConn()
 		if entry != nil && entry.conn != nil {
 			client.cond.L.Unlock()
-			return entry
+			return pool, entry, nil
 		}
-		if int(atomic.AddInt32(&client.connCount, 1)) <= cap(client.connPool) {
+		if int(atomic.AddInt32(&pool.count, 1)) <= cap(pool.entries) {
 			client.cond.L.Unlock()
-			entry := &connEntry{conn: client.createConn()}
-			return entry
+			conn, err := client.dial(uri)
+			if err != nil {
+				atomic.AddInt32(&pool.count, -1)
+				client.cond.Broadcast()
+				return nil, nil, err
+			}
+			return pool, &connEntry{conn: conn}, nil
 		}
-		atomic.AddInt32(&client.connCount, -1)
+		atomic.AddInt32(&pool.count, -1)
 		client.cond.Wait()
 	}
 }
 
+func (client *SocketClient) dial(uri string) (conn net.Conn, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			if e, ok := e.(error); ok {
+				err = e
+			} else {
+				err = NewPanicError(e)
+			}
+		}
+	}()
+	return client.createConn(uri), nil
+}
+
+func (client *SocketClient) putConn(pool *connPool, entry *connEntry) {
+	client.cond.L.Lock()
+	if client.closed {
+		pool.close(entry.conn)
+	} else {
+		pool.entries <- entry
+	}
+	client.cond.L.Unlock()
+	client.cond.Broadcast()
+}
+
 func ifErrorPanic(err error) {
 	if err != nil {
 		panic(err)
 	}
 }
 
+// retainConns closes the idle connections to the service addresses which are
+// not in uriList, the other connections are closed when they become idle.
+func (client *SocketClient) retainConns(uriList []string) {
+	retained := make(map[string]bool, len(uriList))
+	for _, uri := range uriList {
+		retained[uri] = true
+	}
+	var idleConns []*duplexConn
+	client.cond.L.Lock()
+	for uri, pool := range client.pools {
+		if !retained[uri] {
+			delete(client.pools, uri)
+			for entry := pool.getConn(); entry != nil; entry = pool.getConn() {
+				pool.close(entry.conn)
+			}
+		}
+	}
+	for uri, conns := range client.duplexConns {
+		if !retained[uri] {
+			delete(client.duplexConns, uri)
+			for _, dc := range conns {
+				if dc.pending() == 0 {
+					idleConns = append(idleConns, dc)
+				}
+			}
+		}
+	}
+	client.cond.L.Unlock()
+	for _, dc := range idleConns {
+		client.closeDuplexConn(dc, nil)
+	}
+}
+
 // Close the client
 func (client *SocketClient) Close() {
-	close(client.connPool)
+	client.baseClient.Close()
+	client.cond.L.Lock()
+	client.closed = true
+	for _, pool := range client.pools {
+		for entry := pool.getConn(); entry != nil; entry = pool.getConn() {
+			pool.close(entry.conn)
+		}
+	}
+	duplexConns := client.duplexConns
+	client.duplexConns = make(map[string][]*duplexConn)
+	client.cond.L.Unlock()
+	client.cond.Broadcast()
+	for _, conns := range duplexConns {
+		for _, dc := range conns {
+			client.closeDuplexConn(dc, errClientIsAlreadyClosed)
+		}
+	}
 }
 
-func (client *SocketClient) close(conn net.Conn) {
-	conn.Close()
-	atomic.AddInt32(&client.connCount, -1)
+// watchContext closes conn when ctx is done. The returned stop function waits
+// for the watching goroutine to exit, so conn can be reused safely after it
+// returns, and it reports whether conn is closed by the watching goroutine.
+func watchContext(ctx gocontext.Context, conn net.Conn) (stop func() bool) {
+	done := ctx.Done()
+	if done == nil {
+		return func() bool { return false }
+	}
+	stopped := make(chan struct{})
+	closed := make(chan bool, 1)
+	go func() {
+		select {
+		case <-done:
+			conn.Close()
+			closed <- true
+		case <-stopped:
+			closed <- false
+		}
+	}()
+	return func() bool {
+		close(stopped)
+		return <-closed
+	}
 }
 
 func (client *SocketClient) sendAndReceive(
 	data []byte, context *ClientContext) ([]byte, error) {
-	entry := client.fetchConn(false)
+	if client.FullDuplex {
+		return client.duplexSendAndReceive(data, context)
+	}
+	pool, entry, err := client.fetchConn(context.URI(), false)
+	if err != nil {
+		return nil, err
+	}
 	conn := entry.conn
-	err := conn.SetDeadline(time.Now().Add(context.Timeout))
+	stop := watchContext(context.Context(), conn)
+	err = conn.SetDeadline(time.Now().Add(context.Timeout))
 	if err == nil {
 		err = clientSendData(conn, data)
 	}
@@ -155,21 +334,206 @@ func (client *SocketClient) sendAndReceive(
 	if err == nil {
 		err = conn.SetDeadline(time.Time{})
 	}
+	if stop() {
+		err = context.Context().Err()
+	}
 	if err != nil {
-		client.close(conn)
-		client.cond.Signal()
+		pool.close(conn)
+		client.cond.Broadcast()
 		return nil, err
 	}
 	if entry.timer == nil {
 		entry.timer = time.AfterFunc(client.IdleTimeout, func() {
-			client.close(conn)
+			pool.close(conn)
 			entry.conn = nil
 			entry.timer = nil
 		})
 	} else {
 		entry.timer.Reset(client.IdleTimeout)
 	}
-	client.connPool <- entry
-	client.cond.Signal()
+	client.putConn(pool, entry)
 	return data, nil
 }
+
+// fetchDuplexConn returns the connection with the least pending requests,
+// a new connection is dialed (without holding the lock) if all of them are
+// busy and the count of the connections is less than MaxPoolSize.
+func (client *SocketClient) fetchDuplexConn(uri string) (*duplexConn, error) {
+	client.cond.L.Lock()
+	var best *duplexConn
+	for {
+		if client.closed {
+			client.cond.L.Unlock()
+			return nil, errClientIsAlreadyClosed
+		}
+		conns := client.duplexConns[uri]
+		min := 0
+		best = nil
+		for _, dc := range conns {
+			if n := dc.pending(); best == nil || n < min {
+				best, min = dc, n
+			}
+		}
+		if best != nil && (min == 0 ||
+			len(conns)+client.dialing[uri] >= client.poolSize) {
+			client.cond.L.Unlock()
+			return best, nil
+		}
+		if best != nil || client.dialing[uri] == 0 {
+			break
+		}
+		// waits for the connection which is dialing
+		client.cond.Wait()
+	}
+	client.dialing[uri]++
+	client.cond.L.Unlock()
+	conn, err := client.dial(uri)
+	client.cond.L.Lock()
+	if client.dialing[uri]--; client.dialing[uri] == 0 {
+		delete(client.dialing, uri)
+	}
+	if err == nil && client.closed {
+		conn.Close()
+		best, err = nil, errClientIsAlreadyClosed
+	}
+	if err != nil {
+		client.cond.L.Unlock()
+		client.cond.Broadcast()
+		if best != nil {
+			return best, nil
+		}
+		return nil, err
+	}
+	dc := &duplexConn{
+		conn:      conn,
+		uri:       uri,
+		responses: make(map[uint32]chan socketResponse),
+	}
+	dc.timer = time.AfterFunc(client.IdleTimeout, func() {
+		if dc.pending() == 0 {
+			client.closeDuplexConn(dc, nil)
+		}
+	})
+	client.duplexConns[uri] = append(client.duplexConns[uri], dc)
+	client.cond.L.Unlock()
+	client.cond.Broadcast()
+	go client.duplexRecvLoop(dc)
+	return dc, nil
+}
+
+func (client *SocketClient) closeDuplexConn(dc *duplexConn, err error) {
+	client.cond.L.Lock()
+	conns := client.duplexConns[dc.uri]
+	for i, c := range conns {
+		if c == dc {
+			conns = append(conns[:i:i], conns[i+1:]...)
+			break
+		}
+	}
+	if len(conns) == 0 {
+		delete(client.duplexConns, dc.uri)
+	} else {
+		client.duplexConns[dc.uri] = conns
+	}
+	client.cond.L.Unlock()
+	dc.locker.Lock()
+	if dc.closed {
+		dc.locker.Unlock()
+		return
+	}
+	dc.closed = true
+	dc.timer.Stop()
+	dc.conn.Close()
+	responses := dc.responses
+	dc.responses = nil
+	dc.locker.Unlock()
+	if err == nil {
+		err = errConnIsClosed
+	}
+	for _, response := range responses {
+		response <- socketResponse{nil, err}
+	}
+}
+
+func (client *SocketClient) duplexRecvLoop(dc *duplexConn) {
+	reader := bufio.NewReader(dc.conn)
+	for {
+		id, data, err := clientRecvDuplexData(reader)
+		if err != nil {
+			client.closeDuplexConn(dc, err)
+			return
+		}
+		// the response is removed when it is delivered, so every response
+		// channel (buffered with 1) is sent at most once and never blocks.
+		dc.locker.Lock()
+		response := dc.responses[id]
+		delete(dc.responses, id)
+		dc.locker.Unlock()
+		if response != nil {
+			response <- socketResponse{data, nil}
+		}
+	}
+}
+
+func (client *SocketClient) acquirePending() chan struct{} {
+	client.cond.L.Lock()
+	if client.pending == nil {
+		size := client.MaxPendingRequests
+		if size <= 0 {
+			size = 1024
+		}
+		client.pending = make(chan struct{}, size)
+	}
+	pending := client.pending
+	client.cond.L.Unlock()
+	return pending
+}
+
+func (client *SocketClient) duplexSendAndReceive(
+	data []byte, context *ClientContext) ([]byte, error) {
+	timer := time.NewTimer(context.Timeout)
+	defer timer.Stop()
+	done := context.Context().Done()
+	pending := client.acquirePending()
+	select {
+	case pending <- struct{}{}:
+		defer func() { <-pending }()
+	case <-timer.C:
+		return nil, ErrTimeout
+	case <-done:
+		return nil, context.Context().Err()
+	}
+	dc, err := client.fetchDuplexConn(context.URI())
+	if err != nil {
+		return nil, err
+	}
+	id := atomic.AddUint32(&client.nextid, 1)
+	response := make(chan socketResponse, 1)
+	dc.locker.Lock()
+	if dc.closed {
+		dc.locker.Unlock()
+		return nil, errConnIsClosed
+	}
+	dc.timer.Stop()
+	dc.responses[id] = response
+	dc.locker.Unlock()
+	defer dc.remove(id, client.IdleTimeout)
+	dc.writeLocker.Lock()
+	err = dc.conn.SetWriteDeadline(time.Now().Add(context.Timeout))
+	if err == nil {
+		err = clientSendDuplexData(dc.conn, id, data)
+	}
+	dc.writeLocker.Unlock()
+	if err != nil {
+		client.closeDuplexConn(dc, err)
+		return nil, err
+	}
+	select {
+	case resp := <-response:
+		return resp.data, resp.err
+	case <-timer.C:
+		return nil, ErrTimeout
+	case <-done:
+		return nil, context.Context().Err()
+	}
+}
@@ -0,0 +1,167 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/socket_client_test.go                              *
+ *                                                        *
+ * hprose socket client test for Go.                      *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"bytes"
+	gocontext "context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+type duplexStub struct {
+	Sum   func(...int) (int, error)
+	Echo  func(string) (string, error)
+	Sleep func(gocontext.Context, int) (int, error)
+}
+
+func addDuplexFunctions(service contextService) {
+	service.AddFunction("sum", func(a ...int) (n int) {
+		for _, x := range a {
+			n += x
+		}
+		return
+	}, Options{})
+	service.AddFunction("echo", func(s string) string { return s }, Options{})
+	service.AddFunction("sleep", func(ms int) int {
+		time.Sleep(time.Duration(ms) * time.Millisecond)
+		return ms
+	}, Options{})
+}
+
+func testDuplex(t *testing.T, name string, client Client, socket *SocketClient) {
+	socket.FullDuplex = true
+	var stub *duplexStub
+	client.UseService(&stub)
+	var wg sync.WaitGroup
+	for i := 0; i < 1000; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if n, err := stub.Sum(i, 1, 2); err != nil || n != i+3 {
+				t.Error(name, i, n, err)
+			}
+			// the large request is written in more than one part
+			if i%50 == 0 {
+				s := strings.Repeat(fmt.Sprint(i), 2000)
+				if echo, err := stub.Echo(s); err != nil || echo != s {
+					t.Error(name, i, len(echo), err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	ctx, cancel := gocontext.WithTimeout(
+		gocontext.Background(), 30*time.Millisecond)
+	defer cancel()
+	if _, err := stub.Sleep(ctx, 500); err != gocontext.DeadlineExceeded {
+		t.Error(name, err)
+	}
+	client.SetTimeout(50 * time.Millisecond)
+	if _, err := stub.Sleep(gocontext.Background(), 500); err != ErrTimeout {
+		t.Error(name, err)
+	}
+	client.SetTimeout(5 * time.Second)
+	// the late responses of the timeout requests are dropped
+	if s, err := stub.Echo("after"); s != "after" || err != nil {
+		t.Error(name, s, err)
+	}
+	client.Close()
+	if _, err := stub.Echo("closed"); err == nil {
+		t.Error(name, "the client is closed")
+	}
+}
+
+func TestTCPFullDuplex(t *testing.T) {
+	server := NewTCPServer("tcp://127.0.0.1:0")
+	addDuplexFunctions(server)
+	server.Handle()
+	defer server.Close()
+	client := NewTCPClient(server.URI())
+	client.SetMaxPoolSize(4)
+	testDuplex(t, "tcp", client, &client.SocketClient)
+	client = NewTCPClient(server.URI())
+	client.MaxPendingRequests = 10
+	testDuplex(t, "tcp pending", client, &client.SocketClient)
+}
+
+func TestUnixFullDuplex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hprose")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	uri := "unix:" + filepath.Join(dir, "duplex.sock")
+	server := NewUnixServer(uri)
+	addDuplexFunctions(server)
+	server.Handle()
+	defer server.Close()
+	client := NewUnixClient(uri)
+	testDuplex(t, "unix", client, &client.SocketClient)
+}
+
+func TestFullDuplexDialError(t *testing.T) {
+	client := NewTCPClient("tcp://127.0.0.1:1")
+	defer client.Close()
+	client.FullDuplex = true
+	client.SetRetry(0)
+	var stub *duplexStub
+	client.UseService(&stub)
+	if _, err := stub.Echo("x"); err == nil {
+		t.Error("expected the dial error")
+	}
+}
+
+// TestRecvDataShortRead checks that the packets are read completely when
+// the reader returns less bytes than requested.
+func TestRecvDataShortRead(t *testing.T) {
+	body := bytes.Repeat([]byte("hprose"), 1000)
+	buf := new(bytes.Buffer)
+	clientSendData(buf, body)
+	clientSendDuplexData(buf, 7, body)
+	clientSendData(buf, body[:10])
+	reader := iotest.OneByteReader(buf)
+	var data packet
+	if err := serverRecvData(reader, &data); err != nil ||
+		data.fullDuplex || !bytes.Equal(data.body, body) {
+		t.Fatal(err, data.fullDuplex, len(data.body))
+	}
+	if err := serverRecvData(reader, &data); err != nil || !data.fullDuplex ||
+		toUint32(data.id[:]) != 7 || !bytes.Equal(data.body, body) {
+		t.Fatal(err, data.fullDuplex, len(data.body))
+	}
+	if err := serverRecvData(reader, &data); err != nil ||
+		!bytes.Equal(data.body, body[:10]) {
+		t.Fatal(err, len(data.body))
+	}
+	buf.Reset()
+	serverSendData(buf, packet{body: body})
+	result, err := clientRecvData(iotest.OneByteReader(buf), nil)
+	if err != nil || !bytes.Equal(result, body) {
+		t.Error(err, len(result))
+	}
+}
@@ -12,7 +12,7 @@
  *                                                        *
  * hprose socket common for Go.                           *
  *                                                        *
- * LastModified: Oct 20, 2016                             *
+ * LastModified: Oct 18, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -20,6 +20,7 @@
 package rpc
 
 import (
+	"errors"
 	"io"
 	"net"
 	"runtime"
@@ -99,7 +100,7 @@ func serverSendData(writer io.Writer, data packet) (err error) {
 
 func serverRecvData(reader io.Reader, data *packet) (err error) {
 	header := data.id[:]
-	if _, err = reader.Read(header); err != nil {
+	if _, err = io.ReadFull(reader, header); err != nil {
 		return
 	}
 	size := toUint32(header)
@@ -108,7 +109,7 @@ func serverRecvData(reader io.Reader, data *packet) (err error) {
 		size &= 0x7FFFFFFF
 		data.fullDuplex = true
 		data.body = nil
-		if _, err = reader.Read(data.id[:]); err != nil {
+		if _, err = io.ReadFull(reader, data.id[:]); err != nil {
 			return
 		}
 	}
@@ -117,7 +118,7 @@ func serverRecvData(reader io.Reader, data *packet) (err error) {
 	} else {
 		data.body = make([]byte, size)
 	}
-	_, err = reader.Read(data.body)
+	_, err = io.ReadFull(reader, data.body)
 	return
 }
 
@@ -145,7 +146,7 @@ func clientSendData(writer io.Writer, data []byte) (err error) {
 
 func clientRecvData(reader io.Reader, buf []byte) (data []byte, err error) {
 	var header [4]byte
-	if _, err = reader.Read(header[:]); err != nil {
+	if _, err = io.ReadFull(reader, header[:]); err != nil {
 		return
 	}
 	size := toUint32(header[:])
@@ -154,7 +155,51 @@ func clientRecvData(reader io.Reader, buf []byte) (data []byte, err error) {
 	} else {
 		data = make([]byte, size)
 	}
-	_, err = reader.Read(data)
+	_, err = io.ReadFull(reader, data)
+	return
+}
+
+func clientSendDuplexData(
+	writer io.Writer, id uint32, data []byte) (err error) {
+	n := len(data)
+	const i = 8
+	buf := acquireBuffer()
+	fromUint32(buf, uint32(n|0x80000000))
+	fromUint32(buf[4:], id)
+	p := 2048 - i
+	if n <= p {
+		copy(buf[i:], data)
+		_, err = writer.Write(buf[:n+i])
+		releaseBuffer(buf)
+	} else {
+		copy(buf[i:], data[:p])
+		_, err = writer.Write(buf)
+		releaseBuffer(buf)
+		if err != nil {
+			return err
+		}
+		_, err = writer.Write(data[p:])
+	}
+	return err
+}
+
+func clientRecvDuplexData(
+	reader io.Reader) (id uint32, data []byte, err error) {
+	var header [8]byte
+	if _, err = io.ReadFull(reader, header[:4]); err != nil {
+		return
+	}
+	size := toUint32(header[:4])
+	if size&0x80000000 == 0 {
+		return 0, nil, errors.New("Wrong Response: not a full duplex packet")
+	}
+	size &= 0x7FFFFFFF
+	if _, err = io.ReadFull(reader, header[4:]); err != nil {
+		return
+	}
+	id = toUint32(header[4:])
+	data = make([]byte, size)
+	_, err = io.ReadFull(reader, data)
 	return
 }
 
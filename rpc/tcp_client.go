@@ -12,7 +12,7 @@
  *                                                        *
  * hprose tcp client for Go.                              *
  *                                                        *
- * LastModified: Oct 11, 2016                             *
+ * LastModified: Oct 18, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -43,6 +43,7 @@ func NewTCPClient(uri ...string) (client *TCPClient) {
 	client.NoDelay = true
 	client.KeepAlive = true
 	client.createConn = client.createTCPConn
+	client.setURIList = client.SetURIList
 	client.SetURIList(uri)
 	return
 }
@@ -55,10 +56,11 @@ func newTCPClient(uri ...string) Client {
 func (client *TCPClient) SetURIList(uriList []string) {
 	checkAddresses(uriList, tcpSchemes)
 	client.baseClient.SetURIList(uriList)
+	client.retainConns(uriList)
 }
 
-func (client *TCPClient) createTCPConn() net.Conn {
-	u, err := url.Parse(client.uri)
+func (client *TCPClient) createTCPConn(uri string) net.Conn {
+	u, err := url.Parse(uri)
 	ifErrorPanic(err)
 	tcpaddr, err := net.ResolveTCPAddr(u.Scheme, u.Host)
 	ifErrorPanic(err)
@@ -12,7 +12,7 @@
  *                                                        *
  * hprose tcp server for Go.                              *
  *                                                        *
- * LastModified: Oct 2, 2016                              *
+ * LastModified: Oct 18, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -73,11 +73,15 @@ func (server *TCPServer) Handle() (err error) {
 		return err
 	}
 	go server.ServeTCP(server.listener)
-	return nil
+	if err = server.Register(server.URI()); err != nil {
+		server.Close()
+	}
+	return err
 }
 
 // Close the hprose tcp server
 func (server *TCPServer) Close() {
+	server.Deregister()
 	if server.listener != nil {
 		listener := server.listener
 		server.listener = nil
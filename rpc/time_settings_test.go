@@ -0,0 +1,85 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/time_settings_test.go                              *
+ *                                                        *
+ * hprose client time settings test for Go.               *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type errorEvents struct {
+	errors chan error
+}
+
+func (e errorEvents) OnError(name string, err error) {
+	e.errors <- err
+}
+
+type timeStub struct {
+	Echo      func(time.Time) (time.Time, error) `name:"echo" timeprecision:"1s"`
+	EchoNanos func(time.Time) (time.Time, error) `name:"echo" timeprecision:"1000"`
+	EchoBad   func(time.Time) (time.Time, error) `name:"echo" location:"Nowhere/Bad"`
+}
+
+func TestTimeSettingsTags(t *testing.T) {
+	server := NewTCPServer("tcp://127.0.0.1:0")
+	server.AddFunction("echo", func(t time.Time) time.Time { return t }, Options{})
+	server.Handle()
+	defer server.Close()
+	client := NewTCPClient(server.URI())
+	defer client.Close()
+	events := errorEvents{make(chan error, 1)}
+	client.SetEvent(events)
+	var stub *timeStub
+	// the invalid location is reported instead of panicking
+	client.UseService(&stub)
+	select {
+	case err := <-events.errors:
+		if err == nil {
+			t.Error("the invalid location is not reported")
+		}
+	default:
+		t.Error("the invalid location is not reported")
+	}
+	now := time.Date(2026, 10, 18, 12, 34, 56, 123456789, time.UTC)
+	if result, err := stub.Echo(now); err != nil || !result.Equal(now.Truncate(time.Second)) {
+		t.Error(result, err)
+	}
+	if result, err := stub.EchoNanos(now); err != nil || !result.Equal(now.Truncate(time.Microsecond)) {
+		t.Error(result, err)
+	}
+	if result, err := stub.EchoBad(now); err != nil || !result.Equal(now) {
+		t.Error(result, err)
+	}
+}
+
+func TestGetPrecisionValue(t *testing.T) {
+	for tag, want := range map[reflect.StructTag]time.Duration{
+		``:                      0,
+		`timeprecision:"1ms"`:   time.Millisecond,
+		`timeprecision:"1000"`:  time.Microsecond,
+		`timeprecision:"bogus"`: 0,
+	} {
+		if got := getPrecisionValue(tag, "timeprecision"); got != want {
+			t.Error(tag, got, want)
+		}
+	}
+}
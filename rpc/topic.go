@@ -12,7 +12,7 @@
  *                                                        *
  * hprose push topic for Go.                              *
  *                                                        *
- * LastModified: Sep 21, 2016                             *
+ * LastModified: Oct 18, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -20,47 +20,440 @@
 package rpc
 
 import (
+	"strings"
 	"sync"
 	"time"
 )
 
+// TopicName is the name of the topic which a message is pushed to. If the
+// first parameter of the Subscribe callback is a TopicName, it receives the
+// topic name of the message, which is useful for the wildcard subscriptions.
+type TopicName string
+
+// IsTopicPattern reports whether the topic name is a wildcard pattern.
+//
+// The topic names are hierarchical, the segments are separated by dots. In a
+// pattern, the segment "*" matches any one segment, and the last segment ">"
+// matches one or more segments, for example "orders.*" matches "orders.1",
+// and "orders.>" matches both "orders.1" and "orders.1.items".
+func IsTopicPattern(name string) bool {
+	for _, segment := range strings.Split(name, ".") {
+		if segment == "*" || segment == ">" {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchTopic reports whether the topic name matches the pattern.
+func MatchTopic(pattern string, name string) bool {
+	patterns := strings.Split(pattern, ".")
+	segments := strings.Split(name, ".")
+	for i, p := range patterns {
+		if p == ">" {
+			return i == len(patterns)-1 && i < len(segments)
+		}
+		if i >= len(segments) || p != "*" && p != segments[i] {
+			return false
+		}
+	}
+	return len(patterns) == len(segments)
+}
+
+// validTopicName reports whether the topic name or pattern is valid, the
+// segments are not empty, "*" and ">" are whole segments, and ">" is the last
+// segment.
+func validTopicName(name string) bool {
+	segments := strings.Split(name, ".")
+	for i, segment := range segments {
+		if segment == "" ||
+			segment == ">" && i != len(segments)-1 ||
+			len(segment) > 1 && strings.ContainsAny(segment, "*>") {
+			return false
+		}
+	}
+	return true
+}
+
+// OverflowPolicy decides what happens when a message is pushed to a client
+// whose message queue is full.
+type OverflowPolicy int
+
+const (
+	// OverflowDropOldest drops the oldest message in the queue
+	OverflowDropOldest OverflowPolicy = iota
+	// OverflowDropNewest drops the pushed message
+	OverflowDropNewest
+	// OverflowDisconnect drops the pushed message and all the queued messages,
+	// and the client is offline
+	OverflowDisconnect
+)
+
+// DeliveryMode is the delivery guarantee of the pushed messages.
+type DeliveryMode int
+
+const (
+	// DeliveryAtMostOnce removes the messages once they are sent
+	DeliveryAtMostOnce DeliveryMode = iota
+	// DeliveryAtLeastOnce keeps the messages until they are acknowledged, and
+	// sends them again if they are not acknowledged in time
+	DeliveryAtLeastOnce
+)
+
+// DefaultPushQueueSize is the default max number of the queued messages of a
+// push client.
+const DefaultPushQueueSize = 256
+
+// DefaultAckTimeout is the default time to wait for the acknowledgement of a
+// message before it is sent again.
+const DefaultAckTimeout = 30 * time.Second
+
+// PublishOptions is the options of PublishWithOptions.
+//
+// Timeout is the max time of a subscription request waiting for messages, and
+// Heartbeat is the max time between two subscription requests of a client
+// before the client is offline. The zero values mean the Timeout and the
+// Heartbeat of the service.
+//
+// QueueSize is the max number of the queued messages of every client, the
+// zero value means DefaultPushQueueSize, and Overflow decides what happens
+// when the queue is full.
+//
+// BatchSize is the max number of the messages returned by a subscription
+// request, the zero value means all the queued messages. It is only used for
+// the clients which resume from the last received message id, the other
+// clients get one message for every request.
+//
+// Delivery is the delivery mode. With DeliveryAtLeastOnce, the clients which
+// resume from the last received message id acknowledge the messages in their
+// next subscription request, and a message which is not acknowledged in
+// AckTimeout (DefaultAckTimeout if zero) is sent again, until it has been sent
+// MaxDeliveries times (0 means no limit). The OnDeliveryFailed event of the
+// service is fired for the messages which are dropped before they are
+// acknowledged, and the callback of Unicast and Multicast is called when the
+// message is acknowledged instead of sent. The other clients get the messages
+// at most once.
+type PublishOptions struct {
+	Timeout       time.Duration
+	Heartbeat     time.Duration
+	QueueSize     int
+	Overflow      OverflowPolicy
+	BatchSize     int
+	Delivery      DeliveryMode
+	AckTimeout    time.Duration
+	MaxDeliveries int
+}
+
+// the keys of the messages with id which are pushed to the clients that
+// resume from the last received message id.
+const (
+	pushMessageID       = "$id"
+	pushMessageIDs      = "$ids"
+	pushMessageTopics   = "$topics"
+	pushMessageMessages = "$messages"
+)
+
+type topicMessage struct {
+	id         int64
+	topic      string
+	result     interface{}
+	callback   func(bool)
+	sent       bool
+	sentTime   time.Time
+	deliveries int
+}
+
+type subscriber struct {
+	messages []*topicMessage
+	notify   chan struct{}
+	polling  int
+	idle     *time.Timer
+}
+
 type topic struct {
 	sync.RWMutex
-	messages  map[string]chan interface{}
-	heartbeat time.Duration
+	name          string
+	pattern       bool
+	method        *Method
+	timeout       time.Duration
+	subscribers   map[string]*subscriber
+	heartbeat     time.Duration
+	queueSize     int
+	overflow      OverflowPolicy
+	batchSize     int
+	atLeastOnce   bool
+	ackTimeout    time.Duration
+	maxDeliveries int
+	lastID        int64
+	failed        func(id string, result interface{})
+	// dynamic is true if the topic is created by TopicPolicy, it is removed
+	// when its last client is offline.
+	dynamic bool
+	removed bool
 }
 
-func newTopic(heartbeat time.Duration) *topic {
+func newTopic(name string, options PublishOptions,
+	failed func(id string, result interface{})) *topic {
 	t := new(topic)
-	t.messages = make(map[string]chan interface{})
-	t.heartbeat = heartbeat
+	t.name = name
+	t.pattern = IsTopicPattern(name)
+	t.subscribers = make(map[string]*subscriber)
+	t.timeout = options.Timeout
+	t.heartbeat = options.Heartbeat
+	t.queueSize = options.QueueSize
+	if t.queueSize <= 0 {
+		t.queueSize = DefaultPushQueueSize
+	}
+	t.overflow = options.Overflow
+	t.batchSize = options.BatchSize
+	t.atLeastOnce = options.Delivery == DeliveryAtLeastOnce
+	t.ackTimeout = options.AckTimeout
+	if t.ackTimeout <= 0 {
+		t.ackTimeout = DefaultAckTimeout
+	}
+	t.maxDeliveries = options.MaxDeliveries
+	t.failed = failed
+	// the message ids start from the current time in microseconds, so they
+	// still increase after the service restarts, and the clients which resume
+	// from the last message id don't miss the new messages.
+	t.lastID = time.Now().UnixNano() / int64(time.Microsecond)
 	return t
 }
 
-func (t *topic) get(id string) (message chan interface{}) {
-	t.RLock()
-	message = t.messages[id]
-	t.RUnlock()
+// poll starts a subscription request of id, the subscriber is created if it
+// doesn't exist. It returns nil if the topic is removed.
+func (t *topic) poll(id string) (s *subscriber, created bool) {
+	t.Lock()
+	if t.removed {
+		t.Unlock()
+		return nil, false
+	}
+	s = t.subscribers[id]
+	if s == nil {
+		s = &subscriber{notify: make(chan struct{}, 1)}
+		t.subscribers[id] = s
+		created = true
+	}
+	s.polling++
+	if s.idle != nil {
+		s.idle.Stop()
+		s.idle = nil
+	}
+	t.Unlock()
 	return
 }
 
-func (t *topic) put(id string, message chan interface{}) {
+// done ends a subscription request of id, offline is called if there is no
+// subscription request of id in the heartbeat time.
+func (t *topic) done(id string, s *subscriber, offline func()) {
+	t.Lock()
+	s.polling--
+	if s.polling == 0 && t.subscribers[id] == s {
+		s.idle = time.AfterFunc(t.heartbeat, offline)
+	}
+	t.Unlock()
+}
+
+// drop the messages of id which are removed before they are delivered.
+func (t *topic) drop(id string, messages []*topicMessage) {
+	for _, m := range messages {
+		if t.atLeastOnce {
+			if m.callback != nil {
+				m.callback(false)
+			}
+			if t.failed != nil {
+				t.failed(id, m.result)
+			}
+		} else if !m.sent && m.callback != nil {
+			m.callback(false)
+		}
+	}
+}
+
+// remove the subscriber s of id, the messages which are not delivered are
+// dropped.
+func (t *topic) remove(id string, s *subscriber) bool {
 	t.Lock()
-	t.messages[id] = message
+	if t.subscribers[id] != s {
+		t.Unlock()
+		return false
+	}
+	delete(t.subscribers, id)
+	if s.idle != nil {
+		s.idle.Stop()
+		s.idle = nil
+	}
+	messages := s.messages
+	s.messages = nil
 	t.Unlock()
+	t.drop(id, messages)
+	return true
 }
 
-func (t *topic) remove(id string) {
+// close marks the topic removed if it has no subscriber.
+func (t *topic) close() bool {
 	t.Lock()
-	delete(t.messages, id)
+	defer t.Unlock()
+	if len(t.subscribers) > 0 {
+		return false
+	}
+	t.removed = true
+	return true
+}
+
+// push the result of the topic source to the message queue of id, ok is false
+// if id is not subscribed or the result is dropped because the queue is full.
+func (t *topic) push(id string, source string, result interface{},
+	callback func(bool)) (s *subscriber, ok bool) {
+	var dropped []*topicMessage
+	t.Lock()
+	s = t.subscribers[id]
+	if s == nil {
+		t.Unlock()
+		return nil, false
+	}
+	if len(s.messages) >= t.queueSize {
+		if t.overflow != OverflowDropOldest {
+			t.Unlock()
+			if t.atLeastOnce && t.failed != nil {
+				t.failed(id, result)
+			}
+			return s, false
+		}
+		dropped = append(dropped, s.messages[0])
+		s.messages[0] = nil
+		s.messages = s.messages[1:]
+	}
+	t.lastID++
+	m := &topicMessage{
+		id:       t.lastID,
+		topic:    source,
+		result:   result,
+		callback: callback,
+	}
+	s.messages = append(s.messages, m)
 	t.Unlock()
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+	t.drop(id, dropped)
+	return s, true
+}
+
+// ack removes the sent messages of s which are acknowledged. With
+// DeliveryAtLeastOnce, the messages in acks are acknowledged, otherwise the
+// messages up to lastID are acknowledged.
+func (t *topic) ack(s *subscriber, lastID int64, acks map[int64]bool) {
+	var acked []*topicMessage
+	t.Lock()
+	messages := s.messages[:0]
+	for _, m := range s.messages {
+		if m.sent && (t.atLeastOnce && acks[m.id] ||
+			!t.atLeastOnce && m.id <= lastID) {
+			acked = append(acked, m)
+		} else {
+			messages = append(messages, m)
+		}
+	}
+	for i := len(messages); i < len(s.messages); i++ {
+		s.messages[i] = nil
+	}
+	s.messages = messages
+	t.Unlock()
+	if t.atLeastOnce {
+		for _, m := range acked {
+			if m.callback != nil {
+				m.callback(true)
+			}
+		}
+	}
+}
+
+// next returns the first batch of the messages of s, or nil if there is none.
+//
+// If resume is false, only the first message is returned and removed.
+// Otherwise the messages are kept until they are acknowledged, and with
+// DeliveryAtLeastOnce, the sent messages are not returned again until the
+// acknowledgement times out, wait is the time until the first timeout.
+func (t *topic) next(id string, s *subscriber, resume bool) (
+	batch []*topicMessage, wait time.Duration) {
+	var callbacks []func(bool)
+	var dropped []*topicMessage
+	now := time.Now()
+	t.Lock()
+	switch {
+	case !resume:
+		if len(s.messages) > 0 {
+			batch = []*topicMessage{s.messages[0]}
+			s.messages[0] = nil
+			s.messages = s.messages[1:]
+		}
+	case t.atLeastOnce:
+		messages := s.messages[:0]
+		for _, m := range s.messages {
+			if m.sent {
+				timeout := m.sentTime.Add(t.ackTimeout).Sub(now)
+				if timeout > 0 {
+					if wait == 0 || timeout < wait {
+						wait = timeout
+					}
+					messages = append(messages, m)
+					continue
+				}
+				if t.maxDeliveries > 0 && m.deliveries >= t.maxDeliveries {
+					dropped = append(dropped, m)
+					continue
+				}
+			}
+			if t.batchSize <= 0 || len(batch) < t.batchSize {
+				batch = append(batch, m)
+			}
+			messages = append(messages, m)
+		}
+		for i := len(messages); i < len(s.messages); i++ {
+			s.messages[i] = nil
+		}
+		s.messages = messages
+	default:
+		n := len(s.messages)
+		if t.batchSize > 0 && n > t.batchSize {
+			n = t.batchSize
+		}
+		batch = append(batch, s.messages[:n]...)
+	}
+	for _, m := range batch {
+		m.sentTime = now
+		m.deliveries++
+		if !m.sent {
+			m.sent = true
+			if (!t.atLeastOnce || !resume) && m.callback != nil {
+				callbacks = append(callbacks, m.callback)
+			}
+		}
+	}
+	t.Unlock()
+	for _, callback := range callbacks {
+		callback(true)
+	}
+	t.drop(id, dropped)
+	return
+}
+
+func (t *topic) depth(id string) (depth int) {
+	t.RLock()
+	if s := t.subscribers[id]; s != nil {
+		depth = len(s.messages)
+	}
+	t.RUnlock()
+	return
 }
 
 func (t *topic) idlist() (result []string) {
 	t.RLock()
-	result = make([]string, len(t.messages))
+	result = make([]string, len(t.subscribers))
 	i := 0
-	for id := range t.messages {
+	for id := range t.subscribers {
 		result[i] = id
 		i++
 	}
@@ -70,7 +463,7 @@ func (t *topic) idlist() (result []string) {
 
 func (t *topic) exist(id string) (exist bool) {
 	t.RLock()
-	_, exist = t.messages[id]
+	_, exist = t.subscribers[id]
 	t.RUnlock()
 	return
 }
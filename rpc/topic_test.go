@@ -0,0 +1,784 @@
+/**********************************************************\
+|                                                          |
+|                          hprose                          |
+|                                                          |
+| Official WebSite: http://www.hprose.com/                 |
+|                   http://www.hprose.org/                 |
+|                                                          |
+\**********************************************************/
+/**********************************************************\
+ *                                                        *
+ * rpc/topic_test.go                                      *
+ *                                                        *
+ * hprose push topic test for Go.                         *
+ *                                                        *
+ * LastModified: Oct 18, 2026                             *
+ * Author: Ma Bingyao <andot@hprose.com>                  *
+ *                                                        *
+\**********************************************************/
+
+package rpc
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// pushResults records the callbacks of Unicast.
+type pushResults struct {
+	sync.Mutex
+	sent   []int
+	failed []int
+}
+
+func (r *pushResults) callback(n int) func(bool) {
+	return func(ok bool) {
+		r.Lock()
+		if ok {
+			r.sent = append(r.sent, n)
+		} else {
+			r.failed = append(r.failed, n)
+		}
+		r.Unlock()
+	}
+}
+
+func (r *pushResults) check(t *testing.T, sent []int, failed []int) {
+	t.Helper()
+	r.Lock()
+	defer r.Unlock()
+	if len(r.sent) != len(sent) || len(sent) > 0 && !reflect.DeepEqual(r.sent, sent) {
+		t.Errorf("sent %v, want %v", r.sent, sent)
+	}
+	if len(r.failed) != len(failed) || len(failed) > 0 && !reflect.DeepEqual(r.failed, failed) {
+		t.Errorf("failed %v, want %v", r.failed, failed)
+	}
+}
+
+var pollSettings = &InvokeSettings{ResultTypes: []reflect.Type{reflect.TypeOf(0)}}
+
+// pollTopic sends a subscription request without the last message id, it
+// returns one message or 0 if there is none.
+func pollTopic(t *testing.T, client Client, topic string, id string) int {
+	t.Helper()
+	results, err := client.Invoke(topic, []reflect.Value{reflect.ValueOf(id)}, pollSettings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return int(results[0].Int())
+}
+
+func newQueueServer(topic string, overflow OverflowPolicy, event ServiceEvent) (*TCPServer, *TCPClient) {
+	server := NewTCPServer("tcp://127.0.0.1:0")
+	server.Event = event
+	server.PublishWithOptions(topic, PublishOptions{
+		Timeout:   50 * time.Millisecond,
+		Heartbeat: time.Minute,
+		QueueSize: 3,
+		Overflow:  overflow,
+	})
+	server.Handle()
+	client := NewTCPClient(server.URI())
+	return server, client
+}
+
+func TestOverflowDropOldest(t *testing.T) {
+	server, client := newQueueServer("news", OverflowDropOldest, nil)
+	defer server.Close()
+	defer client.Close()
+	if n := pollTopic(t, client, "news", "a"); n != 0 {
+		t.Error(n)
+	}
+	results := new(pushResults)
+	for i := 1; i <= 5; i++ {
+		server.Unicast("news", "a", i, results.callback(i))
+	}
+	if n := server.QueueDepth("news", "a"); n != 3 {
+		t.Error(n)
+	}
+	results.check(t, nil, []int{1, 2})
+	for _, want := range []int{3, 4, 5} {
+		if n := pollTopic(t, client, "news", "a"); n != want {
+			t.Error(n, want)
+		}
+	}
+	results.check(t, []int{3, 4, 5}, []int{1, 2})
+	if !server.Exist("news", "a") {
+		t.Error("the client is offline")
+	}
+}
+
+func TestOverflowDropNewest(t *testing.T) {
+	server, client := newQueueServer("news", OverflowDropNewest, nil)
+	defer server.Close()
+	defer client.Close()
+	pollTopic(t, client, "news", "a")
+	results := new(pushResults)
+	for i := 1; i <= 5; i++ {
+		server.Unicast("news", "a", i, results.callback(i))
+	}
+	if n := server.QueueDepth("news", "a"); n != 3 {
+		t.Error(n)
+	}
+	results.check(t, nil, []int{4, 5})
+	for _, want := range []int{1, 2, 3, 0} {
+		if n := pollTopic(t, client, "news", "a"); n != want {
+			t.Error(n, want)
+		}
+	}
+	results.check(t, []int{1, 2, 3}, []int{4, 5})
+}
+
+type unsubscribeCounter struct {
+	sync.Mutex
+	ids []string
+}
+
+func (c *unsubscribeCounter) OnUnsubscribe(topic string, id string, service Service) {
+	c.Lock()
+	c.ids = append(c.ids, topic+" "+id)
+	c.Unlock()
+}
+
+func TestOverflowDisconnect(t *testing.T) {
+	counter := new(unsubscribeCounter)
+	server, client := newQueueServer("news", OverflowDisconnect, counter)
+	defer server.Close()
+	defer client.Close()
+	pollTopic(t, client, "news", "a")
+	results := new(pushResults)
+	for i := 1; i <= 5; i++ {
+		server.Unicast("news", "a", i, results.callback(i))
+	}
+	// the client is offline at the 4th message, and the queued messages are
+	// dropped
+	results.check(t, nil, []int{1, 2, 3, 4, 5})
+	if server.Exist("news", "a") || server.QueueDepth("news", "a") != 0 {
+		t.Error("the client is not offline")
+	}
+	counter.Lock()
+	if !reflect.DeepEqual(counter.ids, []string{"news a"}) {
+		t.Error(counter.ids)
+	}
+	counter.Unlock()
+	// the client subscribes again with an empty queue
+	if n := pollTopic(t, client, "news", "a"); n != 0 {
+		t.Error(n)
+	}
+	server.Push("news", 6, "a")
+	if n := pollTopic(t, client, "news", "a"); n != 6 {
+		t.Error(n)
+	}
+}
+
+func messageResults(messages []*topicMessage) (results []interface{}) {
+	for _, m := range messages {
+		results = append(results, m.result)
+	}
+	return
+}
+
+func TestTopicBatchSize(t *testing.T) {
+	tp := newTopic("news", PublishOptions{BatchSize: 2, Heartbeat: time.Minute}, nil)
+	s, created := tp.poll("a")
+	if !created {
+		t.Fatal("the subscriber is not created")
+	}
+	for i := 1; i <= 5; i++ {
+		tp.push("a", "news", i, nil)
+	}
+	// the batch is sent again until it is acknowledged
+	var batch []*topicMessage
+	for i := 0; i < 2; i++ {
+		batch, _ = tp.next("a", s, true)
+		if results := messageResults(batch); !reflect.DeepEqual(results, []interface{}{1, 2}) {
+			t.Fatal(results)
+		}
+	}
+	for _, want := range [][]interface{}{{3, 4}, {5}, nil} {
+		tp.ack(s, batch[len(batch)-1].id, nil)
+		batch, _ = tp.next("a", s, true)
+		if results := messageResults(batch); !reflect.DeepEqual(results, want) {
+			t.Fatal(results, want)
+		}
+	}
+	// the clients which don't resume get one message for every request
+	tp = newTopic("news", PublishOptions{BatchSize: 2, Heartbeat: time.Minute}, nil)
+	s, _ = tp.poll("a")
+	tp.push("a", "news", 1, nil)
+	tp.push("a", "news", 2, nil)
+	if batch, _ := tp.next("a", s, false); !reflect.DeepEqual(messageResults(batch), []interface{}{1}) {
+		t.Error(messageResults(batch))
+	}
+	// no limit when BatchSize is 0
+	tp = newTopic("news", PublishOptions{Heartbeat: time.Minute}, nil)
+	s, _ = tp.poll("a")
+	for i := 1; i <= 300; i++ {
+		tp.push("a", "news", i, nil)
+	}
+	if batch, _ := tp.next("a", s, true); len(batch) != DefaultPushQueueSize {
+		t.Error(len(batch))
+	}
+}
+
+func TestPushBatchSize(t *testing.T) {
+	server := NewTCPServer("tcp://127.0.0.1:0")
+	server.PublishWithOptions("news", PublishOptions{BatchSize: 4})
+	server.Handle()
+	defer server.Close()
+	client := NewTCPClient(server.URI())
+	defer client.Close()
+	var batches []int
+	var lock sync.Mutex
+	client.AddInvokeHandler(func(name string, args []reflect.Value,
+		context Context, next NextInvokeHandler) ([]reflect.Value, error) {
+		results, err := next(name, args, context)
+		if err == nil && len(results) > 0 {
+			if batch, ok := parsePushBatch(results[0]); ok {
+				lock.Lock()
+				batches = append(batches, len(batch.messages))
+				lock.Unlock()
+			}
+		}
+		return results, err
+	})
+	got := make(chan int, 20)
+	client.Subscribe("news", "a", nil, func(n int) { got <- n })
+	waitUntil(time.Second, func() bool { return server.Exist("news", "a") })
+	for i := 1; i <= 10; i++ {
+		server.Push("news", i, "a")
+	}
+	for i := 1; i <= 10; i++ {
+		select {
+		case n := <-got:
+			if n != i {
+				t.Fatal(n, i)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatal("message", i, "is not received")
+		}
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	for _, n := range batches {
+		if n > 4 {
+			t.Error("the batch is larger than BatchSize", batches)
+		}
+	}
+}
+
+func TestQueueDepth(t *testing.T) {
+	server := NewTCPServer("tcp://127.0.0.1:0")
+	server.PublishWithOptions("news", PublishOptions{Heartbeat: time.Minute})
+	defer server.Close()
+	if n := server.QueueDepth("news", "a"); n != 0 {
+		t.Error(n)
+	}
+	tp := server.topics["news"]
+	s, _ := tp.poll("a")
+	for i := 1; i <= 3; i++ {
+		server.Push("news", i, "a")
+	}
+	if n := server.QueueDepth("news", "a"); n != 3 {
+		t.Error(n)
+	}
+	// the sent messages are counted until they are acknowledged
+	batch, _ := tp.next("a", s, true)
+	if n := server.QueueDepth("news", "a"); len(batch) != 3 || n != 3 {
+		t.Error(len(batch), n)
+	}
+	tp.ack(s, batch[1].id, nil)
+	if n := server.QueueDepth("news", "a"); n != 1 {
+		t.Error(n)
+	}
+	// the messages of the clients which don't resume are removed when sent
+	tp.next("a", s, false)
+	if n := server.QueueDepth("news", "a"); n != 0 {
+		t.Error(n)
+	}
+	if n := server.QueueDepth("news", "b"); n != 0 {
+		t.Error(n)
+	}
+}
+
+func TestTopicRedelivery(t *testing.T) {
+	tp := newTopic("news", PublishOptions{
+		Heartbeat:  time.Minute,
+		Delivery:   DeliveryAtLeastOnce,
+		AckTimeout: 50 * time.Millisecond,
+	}, nil)
+	s, _ := tp.poll("a")
+	results := new(pushResults)
+	tp.push("a", "news", 1, results.callback(1))
+	batch, wait := tp.next("a", s, true)
+	if len(batch) != 1 || wait != 0 {
+		t.Fatal(len(batch), wait)
+	}
+	id := batch[0].id
+	// the sent message is not sent again until the acknowledgement times out
+	batch, wait = tp.next("a", s, true)
+	if batch != nil || wait <= 0 || wait > 50*time.Millisecond {
+		t.Error(len(batch), wait)
+	}
+	// the last message id does not acknowledge the messages
+	tp.ack(s, id, nil)
+	results.check(t, nil, nil)
+	time.Sleep(wait)
+	batch, _ = tp.next("a", s, true)
+	if len(batch) != 1 || batch[0].id != id || batch[0].deliveries != 2 {
+		t.Fatal("the message is not sent again", batch)
+	}
+	tp.ack(s, id, map[int64]bool{id + 1: true})
+	results.check(t, nil, nil)
+	tp.ack(s, id, map[int64]bool{id: true})
+	results.check(t, []int{1}, nil)
+	if n := tp.depth("a"); n != 0 {
+		t.Error(n)
+	}
+}
+
+func TestTopicMaxDeliveries(t *testing.T) {
+	var failed []interface{}
+	tp := newTopic("news", PublishOptions{
+		Heartbeat:     time.Minute,
+		Delivery:      DeliveryAtLeastOnce,
+		AckTimeout:    10 * time.Millisecond,
+		MaxDeliveries: 2,
+	}, func(id string, result interface{}) {
+		failed = append(failed, id, result)
+	})
+	s, _ := tp.poll("a")
+	results := new(pushResults)
+	tp.push("a", "news", 1, results.callback(1))
+	for i := 0; i < 2; i++ {
+		if batch, _ := tp.next("a", s, true); len(batch) != 1 {
+			t.Fatal(i, len(batch))
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if batch, _ := tp.next("a", s, true); batch != nil {
+		t.Error("the message is sent more than MaxDeliveries times")
+	}
+	results.check(t, nil, []int{1})
+	if !reflect.DeepEqual(failed, []interface{}{"a", 1}) {
+		t.Error(failed)
+	}
+	if n := tp.depth("a"); n != 0 {
+		t.Error(n)
+	}
+}
+
+type deliveryEvents struct {
+	sync.Mutex
+	failed       []interface{}
+	unsubscribed chan string
+}
+
+func newDeliveryEvents() *deliveryEvents {
+	return &deliveryEvents{unsubscribed: make(chan string, 10)}
+}
+
+func (e *deliveryEvents) OnDeliveryFailed(topic string, id string, result interface{}) {
+	e.Lock()
+	e.failed = append(e.failed, result)
+	e.Unlock()
+}
+
+func (e *deliveryEvents) OnUnsubscribe(topic string, id string, service Service) {
+	e.unsubscribed <- topic + " " + id
+}
+
+func (e *deliveryEvents) check(t *testing.T, failed ...interface{}) {
+	t.Helper()
+	e.Lock()
+	defer e.Unlock()
+	if len(e.failed) != len(failed) || len(failed) > 0 && !reflect.DeepEqual(e.failed, failed) {
+		t.Errorf("failed %v, want %v", e.failed, failed)
+	}
+	e.failed = nil
+}
+
+func TestDeliveryFailedOnOverflow(t *testing.T) {
+	events := newDeliveryEvents()
+	server := NewTCPServer("tcp://127.0.0.1:0")
+	server.Event = events
+	for i, overflow := range []OverflowPolicy{
+		OverflowDropOldest, OverflowDropNewest, OverflowDisconnect} {
+		server.PublishWithOptions("news"+strconv.Itoa(i), PublishOptions{
+			Heartbeat: time.Minute,
+			QueueSize: 2,
+			Overflow:  overflow,
+			Delivery:  DeliveryAtLeastOnce,
+		})
+	}
+	for i := 0; i < 3; i++ {
+		server.topics["news"+strconv.Itoa(i)].poll("a")
+	}
+	results := new(pushResults)
+	for i := 1; i <= 3; i++ {
+		server.Unicast("news0", "a", i, results.callback(i))
+	}
+	results.check(t, nil, []int{1})
+	events.check(t, 1)
+	results = new(pushResults)
+	for i := 1; i <= 3; i++ {
+		server.Unicast("news1", "a", i, results.callback(i))
+	}
+	results.check(t, nil, []int{3})
+	events.check(t, 3)
+	results = new(pushResults)
+	for i := 1; i <= 3; i++ {
+		server.Unicast("news2", "a", i, results.callback(i))
+	}
+	results.check(t, nil, []int{1, 2, 3})
+	events.check(t, 3, 1, 2)
+	if server.Exist("news2", "a") {
+		t.Error("the client is not offline")
+	}
+	// the messages pushed to the clients which are not subscribed are not
+	// failed deliveries
+	server.Push("news0", 4, "b")
+	events.check(t)
+}
+
+func TestDeliveryFailedOnOffline(t *testing.T) {
+	events := newDeliveryEvents()
+	server := NewTCPServer("tcp://127.0.0.1:0")
+	server.Event = events
+	server.PublishWithOptions("news", PublishOptions{
+		Timeout:   20 * time.Millisecond,
+		Heartbeat: 100 * time.Millisecond,
+		Delivery:  DeliveryAtLeastOnce,
+	})
+	server.Handle()
+	defer server.Close()
+	client := NewTCPClient(server.URI())
+	defer client.Close()
+	settings := &InvokeSettings{ResultTypes: []reflect.Type{interfaceType}}
+	poll := func() interface{} {
+		results, err := client.Invoke("news", []reflect.Value{
+			reflect.ValueOf("a"), reflect.ValueOf(""), reflect.ValueOf(""),
+		}, settings)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return results[0].Interface()
+	}
+	poll()
+	results := new(pushResults)
+	server.Unicast("news", "a", 1, results.callback(1))
+	server.Unicast("news", "a", 2, results.callback(2))
+	// the messages are sent, but never acknowledged
+	if batch := poll(); batch == nil {
+		t.Fatal("the messages are not sent")
+	}
+	results.check(t, nil, nil)
+	select {
+	case id := <-events.unsubscribed:
+		if id != "news a" {
+			t.Error(id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the client is not offline")
+	}
+	results.check(t, nil, []int{1, 2})
+	events.check(t, 1, 2)
+}
+
+func TestMatchTopic(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		{"orders", "orders", true},
+		{"orders", "orders.1", false},
+		{"orders.1", "orders.2", false},
+		{"*", "orders", true},
+		{"*", "orders.1", false},
+		{">", "orders", true},
+		{">", "orders.1.items", true},
+		{"orders.*", "orders.1", true},
+		{"orders.*", "orders", false},
+		{"orders.*", "orders.1.items", false},
+		{"orders.>", "orders.1", true},
+		{"orders.>", "orders.1.items", true},
+		{"orders.>", "orders", false},
+		{"orders.>", "users.1", false},
+		{"*.1", "orders.1", true},
+		{"*.1", "orders.2", false},
+		{"orders.*.items", "orders.1.items", true},
+		{"orders.*.items", "orders.1.users", false},
+		{"*.*.>", "orders.1", false},
+		{"*.*.>", "orders.1.items", true},
+	}
+	for _, test := range tests {
+		if match := MatchTopic(test.pattern, test.name); match != test.match {
+			t.Errorf("MatchTopic(%q, %q) = %v", test.pattern, test.name, match)
+		}
+	}
+}
+
+func TestValidTopicName(t *testing.T) {
+	tests := []struct {
+		name    string
+		valid   bool
+		pattern bool
+	}{
+		{"orders", true, false},
+		{"orders.1", true, false},
+		{"*", true, true},
+		{">", true, true},
+		{"orders.*", true, true},
+		{"orders.>", true, true},
+		{"*.*.>", true, true},
+		{"", false, false},
+		{".", false, false},
+		{".orders", false, false},
+		{"orders.", false, false},
+		{"orders..1", false, false},
+		{"orders.>.1", false, true},
+		{">.orders", false, true},
+		{"orders.*x", false, false},
+		{"orders.1>", false, false},
+		{"**", false, false},
+	}
+	for _, test := range tests {
+		if valid := validTopicName(test.name); valid != test.valid {
+			t.Errorf("validTopicName(%q) = %v", test.name, valid)
+		}
+		if pattern := IsTopicPattern(test.name); pattern != test.pattern {
+			t.Errorf("IsTopicPattern(%q) = %v", test.name, pattern)
+		}
+	}
+}
+
+func TestTopicPolicy(t *testing.T) {
+	server := NewTCPServer("tcp://127.0.0.1:0")
+	var policyTopics []string
+	var lock sync.Mutex
+	server.TopicPolicy = func(topic string, context ServiceContext) (PublishOptions, bool) {
+		lock.Lock()
+		policyTopics = append(policyTopics, topic)
+		lock.Unlock()
+		if context == nil || !strings.HasPrefix(topic, "orders.") {
+			return PublishOptions{}, false
+		}
+		return PublishOptions{
+			Timeout:   20 * time.Millisecond,
+			Heartbeat: time.Minute,
+			QueueSize: 1,
+		}, true
+	}
+	server.Handle()
+	defer server.Close()
+	client := NewTCPClient(server.URI())
+	defer client.Close()
+	// the topics which are not created yet have no clients
+	if server.Exist("orders.1", "a") || server.IDList("orders.1") != nil {
+		t.Error("the topic is created before the subscription")
+	}
+	server.Push("orders.1", 1, "a")
+	if n := pollTopic(t, client, "orders.1", "a"); n != 0 {
+		t.Error(n)
+	}
+	if !server.Exist("orders.1", "a") {
+		t.Fatal("the topic is not created")
+	}
+	// the topic is created with the options of the policy
+	server.Push("orders.1", 1, "a")
+	server.Push("orders.1", 2, "a")
+	if n := server.QueueDepth("orders.1", "a"); n != 1 {
+		t.Error(n)
+	}
+	if n := pollTopic(t, client, "orders.1", "a"); n != 2 {
+		t.Error(n)
+	}
+	// the policy is only asked once for every topic
+	pollTopic(t, client, "orders.1", "b")
+	// the topics which are denied by the policy, and the invalid topic names
+	// are not created
+	for _, name := range []string{"users.1", "orders..1", "orders.*x"} {
+		if _, err := client.Invoke(name, []reflect.Value{reflect.ValueOf("a")}, pollSettings); err == nil {
+			t.Error(name, "is created")
+		}
+	}
+	// the pattern can be created by the policy
+	pollTopic(t, client, "orders.*", "a")
+	server.topicLock.RLock()
+	_, created := server.topics["users.1"]
+	pattern := server.patterns["orders.*"]
+	server.topicLock.RUnlock()
+	if created || pattern == nil {
+		t.Error(created, pattern)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if want := []string{"orders.1", "users.1", "orders.*"}; !reflect.DeepEqual(policyTopics, want) {
+		t.Error(policyTopics, want)
+	}
+}
+
+func TestTopicPolicyRemove(t *testing.T) {
+	server := NewTCPServer("tcp://127.0.0.1:0")
+	server.Publish("news", 10*time.Millisecond, 300*time.Millisecond)
+	server.TopicPolicy = func(topic string, context ServiceContext) (PublishOptions, bool) {
+		return PublishOptions{
+			Timeout:   10 * time.Millisecond,
+			Heartbeat: 300 * time.Millisecond,
+		}, true
+	}
+	server.Handle()
+	defer server.Close()
+	client := NewTCPClient(server.URI())
+	defer client.Close()
+	topicCount := func() (topics int, patterns int) {
+		server.topicLock.RLock()
+		defer server.topicLock.RUnlock()
+		return len(server.topics), len(server.patterns)
+	}
+	for i := 0; i < 5; i++ {
+		pollTopic(t, client, "orders."+strconv.Itoa(i), "a")
+	}
+	pollTopic(t, client, "orders.*", "a")
+	pollTopic(t, client, "news", "a")
+	if topics, patterns := topicCount(); topics != 7 || patterns != 1 {
+		t.Fatal(topics, patterns)
+	}
+	// the topics created by the policy are removed when their last clients
+	// are offline, the published topics are kept
+	if !waitUntil(2*time.Second, func() bool {
+		topics, patterns := topicCount()
+		return topics == 1 && patterns == 0 && !server.Exist("news", "a")
+	}) {
+		t.Fatal(topicCount())
+	}
+	if server.getTopic("orders.1") != nil || server.IDList("orders.1") != nil {
+		t.Error("the topic is not removed")
+	}
+	// the topic is created again by the next subscription, and the request
+	// which gets the topic before it is removed restores it
+	pollTopic(t, client, "orders.1", "a")
+	tp := server.getTopic("orders.1")
+	if tp == nil {
+		t.Fatal("the topic is not created again")
+	}
+	tp.RLock()
+	s := tp.subscribers["a"]
+	tp.RUnlock()
+	tp.remove("a", s)
+	server.removeTopic(tp)
+	if server.getTopic("orders.1") != nil {
+		t.Fatal("the topic is not removed")
+	}
+	server.subscribe(tp, "b", nil)
+	if server.getTopic("orders.1") != tp || !server.Exist("orders.1", "b") {
+		t.Error("the topic is not restored")
+	}
+	// the topic is not removed while it has clients
+	server.removeTopic(tp)
+	if server.getTopic("orders.1") != tp {
+		t.Error("the topic is removed")
+	}
+}
+
+func TestPushWithoutTopic(t *testing.T) {
+	server := NewTCPServer("tcp://127.0.0.1:0")
+	server.Publish("news.*", 0, 0)
+	// the topic matches the pattern
+	server.Push("news.1", 1)
+	defer func() {
+		if e := recover(); e == nil {
+			t.Error("the topic which is not published is pushed")
+		}
+	}()
+	server.Push("users.1", 1)
+}
+
+type topicMessageResult struct {
+	topic TopicName
+	value string
+}
+
+func TestPushPatternFanOut(t *testing.T) {
+	server := NewTCPServer("tcp://127.0.0.1:0")
+	server.Publish("orders.*", 0, 0)
+	server.Publish("orders.42", 0, 0)
+	server.Publish("orders.>", 0, 0)
+	server.Handle()
+	defer server.Close()
+	client := NewTCPClient(server.URI())
+	defer client.Close()
+	client.SetMaxPoolSize(8)
+	if err := client.Subscribe("orders.*x", "a", nil, func(string) {}); err == nil {
+		t.Error("the invalid pattern is subscribed")
+	}
+	received := map[string]chan topicMessageResult{
+		"orders.*":  make(chan topicMessageResult, 10),
+		"orders.42": make(chan topicMessageResult, 10),
+		"orders.>":  make(chan topicMessageResult, 10),
+	}
+	for name, ch := range received {
+		ch := ch
+		if err := client.Subscribe(name, "a", nil, func(topic TopicName, s string) {
+			ch <- topicMessageResult{topic, s}
+		}); err != nil {
+			t.Fatal(err)
+		}
+		name := name
+		waitUntil(time.Second, func() bool { return server.Exist(name, "a") })
+	}
+	expect := func(name string, want ...topicMessageResult) {
+		t.Helper()
+		for _, m := range want {
+			select {
+			case got := <-received[name]:
+				if got != m {
+					t.Error(name, got, m)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal(name, m, "is not received")
+			}
+		}
+		select {
+		case got := <-received[name]:
+			t.Error(name, "unexpected", got)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	server.Push("orders.42", "a")
+	server.Push("orders.7", "b")
+	server.Push("orders.7.items", "c")
+	expect("orders.42", topicMessageResult{"orders.42", "a"})
+	expect("orders.*",
+		topicMessageResult{"orders.42", "a"},
+		topicMessageResult{"orders.7", "b"})
+	expect("orders.>",
+		topicMessageResult{"orders.42", "a"},
+		topicMessageResult{"orders.7", "b"},
+		topicMessageResult{"orders.7.items", "c"})
+	// the message pushed to the pattern itself is not pushed twice
+	server.Push("orders.*", "d")
+	expect("orders.*", topicMessageResult{"orders.*", "d"})
+	expect("orders.>", topicMessageResult{"orders.*", "d"})
+	expect("orders.42")
+	// the callback of Unicast is called once for all the matched topics
+	sent := make(chan bool, 2)
+	server.Unicast("orders.7", "a", "e", func(ok bool) { sent <- ok })
+	if !<-sent {
+		t.Error("the message is not sent")
+	}
+	expect("orders.*", topicMessageResult{"orders.7", "e"})
+	expect("orders.>", topicMessageResult{"orders.7", "e"})
+	if len(sent) != 0 {
+		t.Error("the callback is called twice")
+	}
+	server.Unicast("orders.7", "b", "f", func(ok bool) { sent <- ok })
+	if <-sent {
+		t.Error("the message is sent to the client which is not subscribed")
+	}
+}
@@ -12,7 +12,7 @@
  *                                                        *
  * reflect types for Go.                                  *
  *                                                        *
- * LastModified: Sep 16, 2016                             *
+ * LastModified: Oct 18, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -20,9 +20,11 @@
 package rpc
 
 import (
+	gocontext "context"
 	"net"
 	"net/http"
 	"reflect"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/valyala/fasthttp"
@@ -31,7 +33,11 @@ import (
 var stringType = reflect.TypeOf("")
 var errorType = reflect.TypeOf((*error)(nil)).Elem()
 var interfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+var timeType = reflect.TypeOf(time.Time{})
 var contextType = reflect.TypeOf((*Context)(nil)).Elem()
+var goContextType = reflect.TypeOf((*gocontext.Context)(nil)).Elem()
+var futureType = reflect.TypeOf((*Future)(nil))
+var topicNameType = reflect.TypeOf(TopicName(""))
 var serviceContextType = reflect.TypeOf((*ServiceContext)(nil)).Elem()
 var httpContextType = reflect.TypeOf((*HTTPContext)(nil))
 var httpRequestType = reflect.TypeOf((*http.Request)(nil))
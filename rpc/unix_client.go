@@ -12,7 +12,7 @@
  *                                                        *
  * hprose unx client for Go.                              *
  *                                                        *
- * LastModified: Oct 11, 2016                             *
+ * LastModified: Oct 18, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -35,6 +35,7 @@ func NewUnixClient(uri ...string) (client *UnixClient) {
 	client = new(UnixClient)
 	client.initSocketClient()
 	client.createConn = client.createUnixConn
+	client.setURIList = client.SetURIList
 	client.SetURIList(uri)
 	return
 }
@@ -47,10 +48,11 @@ func newUnixClient(uri ...string) Client {
 func (client *UnixClient) SetURIList(uriList []string) {
 	checkAddresses(uriList, unixSchemes)
 	client.baseClient.SetURIList(uriList)
+	client.retainConns(uriList)
 }
 
-func (client *UnixClient) createUnixConn() net.Conn {
-	u, err := url.Parse(client.uri)
+func (client *UnixClient) createUnixConn(uri string) net.Conn {
+	u, err := url.Parse(uri)
 	ifErrorPanic(err)
 	unixaddr, err := net.ResolveUnixAddr(u.Scheme, u.Path)
 	ifErrorPanic(err)
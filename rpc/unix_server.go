@@ -12,7 +12,7 @@
  *                                                        *
  * hprose unix server for Go.                             *
  *                                                        *
- * LastModified: Oct 5, 2016                              *
+ * LastModified: Oct 18, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -69,11 +69,15 @@ func (server *UnixServer) Handle() (err error) {
 		return err
 	}
 	go server.ServeUnix(server.listener)
-	return nil
+	if err = server.Register(server.URI()); err != nil {
+		server.Close()
+	}
+	return err
 }
 
 // Close the hprose unix server
 func (server *UnixServer) Close() {
+	server.Deregister()
 	if server.listener != nil {
 		listener := server.listener
 		server.listener = nil
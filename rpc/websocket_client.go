@@ -12,7 +12,7 @@
  *                                                        *
  * hprose websocket client for Go.                        *
  *                                                        *
- * LastModified: Oct 11, 2016                             *
+ * LastModified: Oct 18, 2026                             *
  * Author: Ma Bingyao <andot@hprose.com>                  *
  *                                                        *
 \**********************************************************/
@@ -33,17 +33,22 @@ type reqeust struct {
 	data []byte
 }
 
+type websocketConn struct {
+	uri       string
+	conn      *websocket.Conn
+	requests  chan reqeust
+	responses map[uint32]chan socketResponse
+}
+
 // WebSocketClient is hprose websocket client
 type WebSocketClient struct {
 	baseClient
 	limiter
 	http.Header
-	dialer    websocket.Dialer
-	conn      *websocket.Conn
-	nextid    uint32
-	requests  chan reqeust
-	responses map[uint32]chan socketResponse
-	closed    bool
+	dialer websocket.Dialer
+	conns  map[string]*websocketConn
+	nextid uint32
+	closed bool
 }
 
 // NewWebSocketClient is the constructor of WebSocketClient
@@ -51,7 +56,9 @@ func NewWebSocketClient(uri ...string) (client *WebSocketClient) {
 	client = new(WebSocketClient)
 	client.initBaseClient()
 	client.initLimiter()
+	client.conns = make(map[string]*websocketConn)
 	client.closed = false
+	client.setURIList = client.SetURIList
 	client.SetURIList(uri)
 	client.SendAndReceive = client.sendAndReceive
 	return
@@ -67,28 +74,55 @@ func (client *WebSocketClient) SetURIList(uriList []string) {
 		client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
 	}
 	client.baseClient.SetURIList(uriList)
-}
-
-func (client *WebSocketClient) close(err error) {
+	retained := make(map[string]bool, len(uriList))
+	for _, uri := range uriList {
+		retained[uri] = true
+	}
 	client.cond.L.Lock()
-	if err != nil && client.responses != nil {
-		for _, response := range client.responses {
-			response <- socketResponse{nil, err}
+	for uri, wc := range client.conns {
+		if !retained[uri] {
+			delete(client.conns, uri)
+			client.closeIfRemoved(wc)
 		}
 	}
-	client.responses = nil
-	if client.conn != nil {
-		client.conn.Close()
-		client.conn = nil
+	client.cond.L.Unlock()
+}
+
+// closeIfRemoved closes the connection to the removed service address when
+// there is no request in flight.
+func (client *WebSocketClient) closeIfRemoved(wc *websocketConn) {
+	if len(wc.responses) == 0 && client.conns[wc.uri] != wc {
+		wc.conn.Close()
+	}
+}
+
+func (client *WebSocketClient) closeConn(wc *websocketConn, err error) {
+	if client.conns[wc.uri] == wc {
+		delete(client.conns, wc.uri)
+	}
+	for _, response := range wc.responses {
+		response <- socketResponse{nil, err}
+		client.unlimit()
 	}
-	client.reset()
+	wc.responses = nil
+	wc.conn.Close()
+}
+
+func (client *WebSocketClient) close(wc *websocketConn, err error) {
+	client.cond.L.Lock()
+	client.closeConn(wc, err)
 	client.cond.L.Unlock()
 }
 
 // Close the client
 func (client *WebSocketClient) Close() {
+	client.baseClient.Close()
+	client.cond.L.Lock()
 	client.closed = true
-	client.close(errClientIsAlreadyClosed)
+	for _, wc := range client.conns {
+		client.closeConn(wc, errClientIsAlreadyClosed)
+	}
+	client.cond.L.Unlock()
 }
 
 // TLSClientConfig returns the tls.Config in hprose client
@@ -101,54 +135,70 @@ func (client *WebSocketClient) SetTLSClientConfig(config *tls.Config) {
 	client.dialer.TLSClientConfig = config
 }
 
-func (client *WebSocketClient) sendLoop() {
-	conn := client.conn
-	for request := range client.requests {
-		err := conn.WriteMessage(websocket.BinaryMessage, request.data)
+func (client *WebSocketClient) sendLoop(wc *websocketConn) {
+	for request := range wc.requests {
+		err := wc.conn.WriteMessage(websocket.BinaryMessage, request.data)
 		if err != nil {
-			client.close(err)
+			client.close(wc, err)
 			break
 		}
 	}
-	client.requests = nil
 }
 
-func (client *WebSocketClient) recvLoop() {
-	conn := client.conn
+func (client *WebSocketClient) recvLoop(wc *websocketConn) {
 	for {
-		msgType, data, err := conn.ReadMessage()
+		msgType, data, err := wc.conn.ReadMessage()
 		if err != nil {
-			client.close(err)
+			client.close(wc, err)
 			break
 		}
 		if msgType == websocket.BinaryMessage {
 			id := toUint32(data)
 			client.cond.L.Lock()
-			response := client.responses[id]
+			response := wc.responses[id]
 			if response != nil {
 				response <- socketResponse{data[4:], nil}
-				delete(client.responses, id)
+				delete(wc.responses, id)
+				client.unlimit()
+				client.closeIfRemoved(wc)
 			}
-			client.unlimit()
 			client.cond.L.Unlock()
 		}
 	}
-	close(client.requests)
+	client.cond.L.Lock()
+	close(wc.requests)
+	client.cond.L.Unlock()
 }
 
-func (client *WebSocketClient) getConn(uri string) (err error) {
-	if client.conn == nil {
-		client.conn, _, err = client.dialer.Dial(uri, client.Header)
+func (client *WebSocketClient) getConn(uri string) (*websocketConn, error) {
+	wc := client.conns[uri]
+	if wc == nil {
+		conn, _, err := client.dialer.Dial(uri, client.Header)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		count := client.MaxConcurrentRequests
-		client.requests = make(chan reqeust, count)
-		client.responses = make(map[uint32]chan socketResponse, count)
-		go client.sendLoop()
-		go client.recvLoop()
+		wc = &websocketConn{
+			uri:       uri,
+			conn:      conn,
+			requests:  make(chan reqeust, count),
+			responses: make(map[uint32]chan socketResponse, count),
+		}
+		client.conns[uri] = wc
+		go client.sendLoop(wc)
+		go client.recvLoop(wc)
 	}
-	return nil
+	return wc, nil
+}
+
+func (client *WebSocketClient) cancel(wc *websocketConn, id uint32) {
+	client.cond.L.Lock()
+	if _, ok := wc.responses[id]; ok {
+		delete(wc.responses, id)
+		client.unlimit()
+		client.closeIfRemoved(wc)
+	}
+	client.cond.L.Unlock()
 }
 
 func (client *WebSocketClient) sendAndReceive(
@@ -157,28 +207,33 @@ func (client *WebSocketClient) sendAndReceive(
 	buf := make([]byte, len(data)+4)
 	fromUint32(buf, id)
 	copy(buf[4:], data)
-	response := make(chan socketResponse)
+	response := make(chan socketResponse, 1)
 	client.cond.L.Lock()
 	client.limit()
 	if client.closed {
+		client.unlimit()
 		client.cond.L.Unlock()
 		return nil, errClientIsAlreadyClosed
 	}
-	if err := client.getConn(client.uri); err != nil {
+	wc, err := client.getConn(context.URI())
+	if err != nil {
+		client.unlimit()
 		client.cond.L.Unlock()
 		return nil, err
 	}
-	client.responses[id] = response
+	wc.responses[id] = response
+	wc.requests <- reqeust{id, buf}
 	client.cond.L.Unlock()
-	client.requests <- reqeust{id, buf}
+	timer := time.NewTimer(context.Timeout)
+	defer timer.Stop()
 	select {
 	case resp := <-response:
 		return resp.data, resp.err
-	case <-time.After(context.Timeout):
-		client.cond.L.Lock()
-		delete(client.responses, id)
-		client.unlimit()
-		client.cond.L.Unlock()
+	case <-timer.C:
+		client.cancel(wc, id)
 		return nil, ErrTimeout
+	case <-context.Context().Done():
+		client.cancel(wc, id)
+		return nil, context.Context().Err()
 	}
 }